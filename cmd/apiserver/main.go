@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"log"
+	"os"
 
 	"github.com/BurntSushi/toml"
 	"github.com/DalerBakhriev/social_network/internal/app/apiserver"
@@ -22,6 +23,24 @@ func main() {
 		log.Fatalf("Failed to parse config file %s: %v", configPath, err)
 	}
 
+	switch flag.Arg(0) {
+	case "migrate":
+		if err := apiserver.Migrate(os.Stdout, config, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "reshard":
+		if err := apiserver.Reshard(os.Stdout, config); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "role":
+		if err := apiserver.SetRole(os.Stdout, config, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if err := apiserver.Start(config); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,118 @@
+package activity
+
+import (
+	"sync"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+)
+
+// Cache keeps materialized activity feeds of users ordered from newer to older.
+// Implementations must be safe for concurrent use
+type Cache interface {
+	// Get returns feed of user and whether it is cached
+	Get(userID int) ([]*model.Activity, bool)
+	// Set replaces feed of user
+	Set(userID int, activities []*model.Activity)
+	// Push prepends activity to feed of user if the feed is cached
+	Push(userID int, activity *model.Activity)
+	// RemoveActor removes activities of actor from feed of user
+	RemoveActor(userID, actorID int)
+	// Delete drops feed of user, so it is rebuilt on next Get
+	Delete(userID int)
+}
+
+// MemoryCache is a Cache keeping feeds in process memory
+type MemoryCache struct {
+	mu    sync.RWMutex
+	size  int
+	feeds map[int][]*model.Activity
+}
+
+// NewMemoryCache returns cache holding at most size latest activities per user
+func NewMemoryCache(size int) *MemoryCache {
+	return &MemoryCache{
+		size:  size,
+		feeds: make(map[int][]*model.Activity),
+	}
+}
+
+// Get ...
+func (c *MemoryCache) Get(userID int) ([]*model.Activity, bool) {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	feed, ok := c.feeds[userID]
+	if !ok {
+		return nil, false
+	}
+
+	activities := make([]*model.Activity, len(feed))
+	copy(activities, feed)
+
+	return activities, true
+}
+
+// Set ...
+func (c *MemoryCache) Set(userID int, activities []*model.Activity) {
+
+	if len(activities) > c.size {
+		activities = activities[:c.size]
+	}
+
+	feed := make([]*model.Activity, len(activities))
+	copy(feed, activities)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.feeds[userID] = feed
+}
+
+// Push ...
+func (c *MemoryCache) Push(userID int, activity *model.Activity) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	feed, ok := c.feeds[userID]
+	if !ok {
+		return
+	}
+
+	if len(feed) >= c.size {
+		feed = feed[:c.size-1]
+	}
+
+	c.feeds[userID] = append([]*model.Activity{activity}, feed...)
+}
+
+// RemoveActor ...
+func (c *MemoryCache) RemoveActor(userID, actorID int) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	feed, ok := c.feeds[userID]
+	if !ok {
+		return
+	}
+
+	pruned := make([]*model.Activity, 0, len(feed))
+	for _, activity := range feed {
+		if activity.ActorID != actorID {
+			pruned = append(pruned, activity)
+		}
+	}
+
+	c.feeds[userID] = pruned
+}
+
+// Delete ...
+func (c *MemoryCache) Delete(userID int) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.feeds, userID)
+}
@@ -0,0 +1,130 @@
+package activity
+
+import (
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// Feed records activities of users and fans them out on write
+// to materialized feeds of their accepted friends
+type Feed struct {
+	store store.Store
+	cache Cache
+	size  int
+}
+
+// NewFeed returns feed keeping size latest activities of friends per user
+func NewFeed(store store.Store, cache Cache, size int) *Feed {
+	return &Feed{
+		store: store,
+		cache: cache,
+		size:  size,
+	}
+}
+
+// Get returns the latest activities of friends of user,
+// feed missing in cache is rebuilt from database
+func (f *Feed) Get(userID int) ([]*model.Activity, error) {
+
+	if activities, ok := f.cache.Get(userID); ok {
+		return activities, nil
+	}
+
+	friendIDs, err := f.friendIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	activities, err := f.store.Activity().GetByActors(friendIDs, f.size)
+	if err != nil {
+		return nil, err
+	}
+
+	f.cache.Set(userID, activities)
+
+	return activities, nil
+}
+
+// FriendsAdded records that two users became friends.
+// Feeds of both users are dropped since they now include activities of each other
+func (f *Feed) FriendsAdded(userID, friendID int) error {
+
+	f.cache.Delete(userID)
+	f.cache.Delete(friendID)
+
+	if err := f.record(&model.Activity{
+		Type:      model.ActivityFriendAdded,
+		ActorID:   userID,
+		SubjectID: friendID,
+	}); err != nil {
+		return err
+	}
+
+	return f.record(&model.Activity{
+		Type:      model.ActivityFriendAdded,
+		ActorID:   friendID,
+		SubjectID: userID,
+	})
+}
+
+// ProfileUpdated records that user changed profile
+func (f *Feed) ProfileUpdated(userID int) error {
+	return f.record(&model.Activity{
+		Type:    model.ActivityProfileUpdated,
+		ActorID: userID,
+	})
+}
+
+// FriendRemoved prunes activities of former friends from feeds of each other
+func (f *Feed) FriendRemoved(userID, friendID int) {
+	f.cache.RemoveActor(userID, friendID)
+	f.cache.RemoveActor(friendID, userID)
+}
+
+// record saves activity and pushes it to cached feeds of friends of actor
+func (f *Feed) record(a *model.Activity) error {
+
+	actor, err := f.store.User().Find(a.ActorID)
+	if err != nil {
+		return err
+	}
+	a.ActorName, a.ActorSurname = actor.Name, actor.Surname
+
+	if a.SubjectID != 0 {
+		subject, err := f.store.User().Find(a.SubjectID)
+		if err != nil {
+			return err
+		}
+		a.SubjectName, a.SubjectSurname = subject.Name, subject.Surname
+	}
+
+	if err := f.store.Activity().Create(a); err != nil {
+		return err
+	}
+
+	friendIDs, err := f.friendIDs(a.ActorID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range friendIDs {
+		f.cache.Push(id, a)
+	}
+
+	return nil
+}
+
+func (f *Feed) friendIDs(userID int) ([]int, error) {
+
+	friends, err := f.store.User().GetFriendsList(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(friends))
+	for _, friend := range friends {
+		ids = append(ids, friend.ID)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,81 @@
+package apiserver
+
+import (
+	"net/http"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+)
+
+// activityFeedSize is the number of the latest activities of friends kept per user
+const activityFeedSize = 100
+
+func (s *server) handleActivity() http.HandlerFunc {
+
+	tmpl := parseTemplate("activity.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		activities, err := s.activityFeed.Get(userID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.render(w, r, tmpl, model.Activities{
+			Activities: activities,
+			CurrUserID: userID,
+		})
+	}
+}
+
+func (s *server) handleAPIActivity() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		activities, err := s.activityFeed.Get(user.ID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, &model.Activities{Activities: activities})
+	}
+}
+
+// friendsAdded records activity of users who became friends,
+// failure is only logged since friendship is already saved
+func (s *server) friendsAdded(userID, friendID int) {
+	if err := s.activityFeed.FriendsAdded(userID, friendID); err != nil {
+		s.logger.Errorf("Failed to record activity of new friends %d and %d: %v", userID, friendID, err)
+	}
+}
+
+// profileUpdated records activity of user who changed profile,
+// failure is only logged since profile is already saved
+func (s *server) profileUpdated(userID int) {
+	if err := s.activityFeed.ProfileUpdated(userID); err != nil {
+		s.logger.Errorf("Failed to record profile update of user %d: %v", userID, err)
+	}
+}
+
+// removeFriend removes friendship and prunes activity feeds of former friends
+func (s *server) removeFriend(userID, friendID int) error {
+
+	if err := s.store.User().RemoveFriend(userID, friendID); err != nil {
+		return err
+	}
+
+	s.activityFeed.FriendRemoved(userID, friendID)
+
+	return nil
+}
@@ -0,0 +1,353 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/gorilla/mux"
+)
+
+// numAccountsOnOnePage is size of page of accounts in admin console
+const numAccountsOnOnePage = 50
+
+type roleRequest struct {
+	Role string `json:"role"`
+}
+
+type noticeRequest struct {
+	Text string `json:"text"`
+}
+
+// requireRole forbids requests of users without role or a higher one,
+// current user is put into request context by authenticateUser
+func (s *server) requireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		if !user.HasRole(role) {
+			s.error(w, r, http.StatusForbidden, errRoleRequired)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (s *server) handleAdminUsers() http.HandlerFunc {
+
+	tmpl := parseTemplate("admin_users.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		beforeID, err := getBeforeID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		query := strings.TrimSpace(r.URL.Query().Get("q"))
+		accounts, err := s.store.User().GetAccounts(query, beforeID, numAccountsOnOnePage)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+		s.render(w, r, tmpl, model.AdminPage{
+			AccountsPage: accounts,
+			Query:        query,
+			Roles:        []string{model.RoleUser, model.RoleModerator, model.RoleAdmin},
+			IsAdmin:      user.HasRole(model.RoleAdmin),
+			CurrUserID:   user.ID,
+		})
+	}
+}
+
+// handleAdminAction runs action on user of request and returns to the console
+func (s *server) handleAdminAction(action func(r *http.Request, target *model.User) error) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		target, err := s.adminTarget(r)
+		if err != nil {
+			s.adminError(w, r, err)
+			return
+		}
+
+		if err := action(r, target); err != nil {
+			s.adminError(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, "/admin/users", http.StatusFound)
+	}
+}
+
+func (s *server) handleAPIAdminUsers() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		beforeID, err := getBeforeID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		accounts, err := s.store.User().GetAccounts(strings.TrimSpace(r.URL.Query().Get("q")), beforeID, numAccountsOnOnePage)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, accounts)
+	}
+}
+
+// handleAPIAdminAction runs action on user of request and responds with account
+func (s *server) handleAPIAdminAction(action func(r *http.Request, target *model.User) error) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		target, err := s.adminTarget(r)
+		if err != nil {
+			s.adminError(w, r, err)
+			return
+		}
+
+		if err := action(r, target); err != nil {
+			s.adminError(w, r, err)
+			return
+		}
+
+		updated, err := s.store.User().Find(target.ID)
+		if err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, model.NewAccount(updated))
+	}
+}
+
+func (s *server) handleAPIAdminSetRole() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		req := &roleRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		s.handleAPIAdminAction(func(r *http.Request, target *model.User) error {
+			return s.setRole(r, target, req.Role)
+		})(w, r)
+	}
+}
+
+func (s *server) handleAPIAdminSendNotice() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		req := &noticeRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		s.handleAPIAdminAction(func(r *http.Request, target *model.User) error {
+			return s.sendNotice(r, target, req.Text)
+		})(w, r)
+	}
+}
+
+func (s *server) handleAPIAdminDeleteUser() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		target, err := s.adminTarget(r)
+		if err != nil {
+			s.adminError(w, r, err)
+			return
+		}
+
+		if err := s.deleteUser(r, target); err != nil {
+			s.adminError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// adminTarget returns user from url of request whom current user may manage
+func (s *server) adminTarget(r *http.Request) (*model.User, error) {
+
+	id, err := strconv.Atoi(mux.Vars(r)["user_id"])
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := s.store.User().Find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, ok := currentUser(r)
+	if !ok {
+		return nil, errNotAuthenticated
+	}
+
+	if !user.CanManage(target) {
+		return nil, errCannotManageUser
+	}
+
+	return target, nil
+}
+
+// suspendUser forbids user to log in and logs the user out everywhere
+func (s *server) suspendUser(r *http.Request, target *model.User) error {
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if err := s.store.User().SetSuspended(target.ID, &now); err != nil {
+		return err
+	}
+
+	if err := s.store.Session().DeleteByUser(target.ID); err != nil {
+		return err
+	}
+
+	s.auditAdminAction(r, model.AuditUserSuspended, target, "")
+
+	return nil
+}
+
+func (s *server) unsuspendUser(r *http.Request, target *model.User) error {
+
+	if err := s.store.User().SetSuspended(target.ID, nil); err != nil {
+		return err
+	}
+
+	s.auditAdminAction(r, model.AuditUserUnsuspended, target, "")
+
+	return nil
+}
+
+func (s *server) setRole(r *http.Request, target *model.User, role string) error {
+
+	if err := s.store.User().SetRole(target.ID, role); err != nil {
+		return err
+	}
+
+	s.auditAdminAction(r, model.AuditRoleChanged, target, fmt.Sprintf("from %s to %s", target.Role, role))
+
+	return nil
+}
+
+// sendNotice adds notice with text to notifications of user
+func (s *server) sendNotice(r *http.Request, target *model.User, text string) error {
+
+	notice, err := model.NewAdminNotice(target.ID, text)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.Notification().Create(notice); err != nil {
+		return err
+	}
+
+	s.auditAdminAction(r, model.AuditNoticeSent, target, "")
+
+	return nil
+}
+
+// resetUserPassword replaces password of user with random one, logs
+// the user out everywhere and emails link to set new password
+func (s *server) resetUserPassword(r *http.Request, target *model.User) error {
+
+	password, err := newResetToken()
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.User().UpdatePassword(&model.User{ID: target.ID, Password: password}); err != nil {
+		return err
+	}
+
+	if err := s.store.Session().DeleteByUser(target.ID); err != nil {
+		return err
+	}
+
+	// user can not log in until the link is used, so cooldown does not apply
+	if err := s.sendPasswordResetLink(target); err != nil {
+		return err
+	}
+
+	s.auditAdminAction(r, model.AuditPasswordReset, target, "")
+
+	return nil
+}
+
+// deleteUser deletes account with everything user created,
+// activities of the user are pruned from feeds of friends
+func (s *server) deleteUser(r *http.Request, target *model.User) error {
+
+	friends, err := s.store.User().GetFriendsList(target.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.User().Delete(target.ID); err != nil {
+		return err
+	}
+
+	for _, friend := range friends {
+		s.activityFeed.FriendRemoved(target.ID, friend.ID)
+	}
+
+	s.auditAdminAction(r, model.AuditUserDeleted, target, target.Email)
+
+	return nil
+}
+
+// auditAdminAction records action of current user on target
+func (s *server) auditAdminAction(r *http.Request, eventType string, target *model.User, details string) {
+
+	by := "by unknown"
+	if actor, ok := currentUser(r); ok {
+		by = fmt.Sprintf("by %s %d", actor.Role, actor.ID)
+	}
+
+	s.audit(&model.AuditEvent{
+		Type:    eventType,
+		UserID:  target.ID,
+		IP:      remoteIP(r),
+		Details: strings.TrimSpace(by + " " + details),
+	})
+}
+
+func (s *server) adminError(w http.ResponseWriter, r *http.Request, err error) {
+
+	switch err {
+	case errNotAuthenticated:
+		s.error(w, r, http.StatusUnauthorized, err)
+	case errCannotManageUser:
+		s.error(w, r, http.StatusForbidden, err)
+	case model.ErrUnknownRole, model.ErrEmptyNotice, model.ErrNoticeTooLong:
+		s.error(w, r, http.StatusUnprocessableEntity, err)
+	default:
+		s.storeError(w, r, err)
+	}
+}
@@ -0,0 +1,122 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store/teststore"
+)
+
+func TestServer_HandleAdminSendNotice(t *testing.T) {
+
+	testCases := []struct {
+		name         string
+		role         string
+		text         string
+		expectedCode int
+	}{
+		{
+			name:         "valid",
+			role:         model.RoleModerator,
+			text:         "  Please fill in your profile  ",
+			expectedCode: http.StatusFound,
+		},
+		{
+			name:         "empty",
+			role:         model.RoleModerator,
+			text:         " ",
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "too long",
+			role:         model.RoleModerator,
+			text:         strings.Repeat("a", model.MaxNoticeLength+1),
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "not moderator",
+			role:         model.RoleUser,
+			text:         "Hello",
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st := teststore.New()
+			moderator := createTestUser(t, st, "moderator@example.org", "password")
+			if err := st.User().SetRole(moderator.ID, tc.role); err != nil {
+				t.Fatal(err)
+			}
+			user := createTestUser(t, st, "user@example.org", "password")
+			srv := httptest.NewServer(newTestServer(t, st, testConfig()))
+			defer srv.Close()
+
+			c := newTestClient(t, srv)
+			c.logIn(moderator.Email, "password")
+
+			resp := c.postForm(fmt.Sprintf("/admin/users/%d/notice", user.ID), url.Values{"text": {tc.text}})
+			if resp.StatusCode != tc.expectedCode {
+				t.Fatalf("expected status %d, got %d", tc.expectedCode, resp.StatusCode)
+			}
+
+			page, err := st.Notification().GetByUser(user.ID, 0, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.expectedCode != http.StatusFound {
+				if len(page.Notifications) != 0 {
+					t.Fatalf("notice is stored after failed request: %+v", page.Notifications[0])
+				}
+				return
+			}
+			if len(page.Notifications) != 1 {
+				t.Fatalf("expected 1 notification, got %d", len(page.Notifications))
+			}
+			if n := page.Notifications[0]; n.Type != model.NotificationAdminNotice || n.Text != strings.TrimSpace(tc.text) {
+				t.Fatalf("unexpected notification %+v", n)
+			}
+		})
+	}
+}
+
+func TestServer_HandleAPIAdminSendNotice(t *testing.T) {
+
+	st := teststore.New()
+	moderator := createTestUser(t, st, "moderator@example.org", "password")
+	if err := st.User().SetRole(moderator.ID, model.RoleModerator); err != nil {
+		t.Fatal(err)
+	}
+	user := createTestUser(t, st, "user@example.org", "password")
+	srv := httptest.NewServer(newTestServer(t, st, testConfig()))
+	defer srv.Close()
+
+	c := newTestClient(t, srv)
+	c.logIn(moderator.Email, "password")
+
+	resp, err := c.client.Post(
+		fmt.Sprintf("%s/api/v1/admin/users/%d/notice", srv.URL, user.ID),
+		"application/json",
+		strings.NewReader(`{"text": "Welcome"}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readBody(t, resp)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	page, err := st.Notification().GetByUser(user.ID, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Notifications) != 1 || page.Notifications[0].Text != "Welcome" {
+		t.Fatalf("unexpected notifications %+v", page.Notifications)
+	}
+}
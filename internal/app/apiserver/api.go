@@ -0,0 +1,474 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"github.com/gorilla/mux"
+)
+
+// userRequest is json body of signup and profile update requests
+type userRequest struct {
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	Name      string `json:"name"`
+	Surname   string `json:"surname"`
+	Age       int    `json:"age"`
+	Sex       string `json:"sex"`
+	Interests string `json:"interests"`
+	City      string `json:"city"`
+}
+
+// logInRequest is json body of login request
+type logInRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// usersResponse is json body of responses with list of users
+type usersResponse struct {
+	Users []*model.User `json:"users"`
+}
+
+func (s *server) configureAPIRouter(api *mux.Router) {
+
+	api.HandleFunc("/signup", s.handleAPISignUp()).Methods("POST")
+	api.HandleFunc("/login", s.handleAPILogIn()).Methods("POST")
+	api.HandleFunc("/login/2fa", s.handleAPITwoFactorLogIn()).Methods("POST")
+	api.HandleFunc("/password/forgot", s.handleAPIPasswordForgot()).Methods("POST")
+	api.HandleFunc("/password/reset", s.handleAPIPasswordReset()).Methods("POST")
+	api.HandleFunc("/verify", s.handleAPIVerifyEmail()).Methods("POST")
+	api.HandleFunc("/users", s.handleAPIGetUsers()).Methods("GET")
+	api.HandleFunc("/users/search", s.handleAPISearchUsers()).Methods("GET")
+	api.HandleFunc("/users/{user_id:[0-9]+}", s.handleAPIGetUser()).Methods("GET")
+	api.HandleFunc("/users/{user_id:[0-9]+}/friends", s.handleAPIGetFriendsList()).Methods("GET")
+	api.HandleFunc("/users/{user_id:[0-9]+}/posts", s.handleAPIGetUserPosts()).Methods("GET")
+
+	authenticated := api.NewRoute().Subrouter()
+	authenticated.Use(s.authenticateUser)
+	authenticated.HandleFunc("/logout", s.handleAPILogOut()).Methods("POST")
+	authenticated.HandleFunc("/me", s.handleAPIGetMe()).Methods("GET")
+	authenticated.HandleFunc("/verify/resend", s.handleAPIResendVerification()).Methods("POST")
+	authenticated.HandleFunc("/me", s.handleAPIUpdateMe()).Methods("PUT")
+	authenticated.HandleFunc("/friend_requests", s.handleAPIGetFriendsRequests()).Methods("GET")
+	authenticated.HandleFunc("/friend_requests/{friend_id:[0-9]+}", s.requireVerifiedEmail(actionFriendRequests, s.handleAPISendFriendRequest())).Methods("POST")
+	authenticated.HandleFunc("/friend_requests/{friend_id:[0-9]+}/accept", s.requireVerifiedEmail(actionFriendRequests, s.handleAPIAcceptFriendRequest())).Methods("POST")
+	authenticated.HandleFunc("/friend_requests/{friend_id:[0-9]+}/decline", s.handleAPIDeclineFriendRequest()).Methods("POST")
+	authenticated.HandleFunc("/friend_requests/{friend_id:[0-9]+}/cancel", s.handleAPICancelFriendRequest()).Methods("POST")
+	authenticated.HandleFunc("/friends/{friend_id:[0-9]+}", s.handleAPIRemoveFriend()).Methods("DELETE")
+	authenticated.HandleFunc("/posts", s.requireVerifiedEmail(actionPosts, s.handleAPICreatePost())).Methods("POST")
+	authenticated.HandleFunc("/posts/{post_id:[0-9]+}", s.handleAPIUpdatePost()).Methods("PUT")
+	authenticated.HandleFunc("/posts/{post_id:[0-9]+}", s.handleAPIDeletePost()).Methods("DELETE")
+	authenticated.HandleFunc("/feed", s.handleAPIFeed()).Methods("GET")
+	authenticated.HandleFunc("/activity", s.handleAPIActivity()).Methods("GET")
+	authenticated.HandleFunc("/notifications", s.handleAPINotifications()).Methods("GET")
+	authenticated.HandleFunc("/notifications/{notification_id:[0-9]+}/read", s.handleAPIReadNotification()).Methods("POST")
+	authenticated.HandleFunc("/notifications/read_all", s.handleAPIReadAllNotifications()).Methods("POST")
+	authenticated.HandleFunc("/sessions", s.handleAPISessions()).Methods("GET")
+	authenticated.HandleFunc("/sessions", s.handleAPIRevokeAllSessions()).Methods("DELETE")
+	authenticated.HandleFunc("/sessions/{session_id:[0-9a-f]+}", s.handleAPIRevokeSession()).Methods("DELETE")
+	authenticated.HandleFunc("/two_factor", s.handleAPITwoFactor()).Methods("GET")
+	authenticated.HandleFunc("/two_factor/enroll", s.handleAPITwoFactorEnroll()).Methods("POST")
+	authenticated.HandleFunc("/two_factor/confirm", s.handleAPITwoFactorConfirm()).Methods("POST")
+	authenticated.HandleFunc("/two_factor/disable", s.handleAPITwoFactorDisable()).Methods("POST")
+	authenticated.HandleFunc("/api_tokens", s.handleAPIGetAPITokens()).Methods("GET")
+	authenticated.HandleFunc("/api_tokens", s.handleAPICreateAPIToken()).Methods("POST")
+	authenticated.HandleFunc("/api_tokens/{token_id:[0-9]+}", s.handleAPIRevokeAPIToken()).Methods("DELETE")
+	authenticated.HandleFunc("/dialogs", s.handleAPIDialogs()).Methods("GET")
+	authenticated.HandleFunc("/dialogs/{user_id:[0-9]+}", s.handleAPIGetMessages()).Methods("GET")
+	authenticated.HandleFunc("/dialogs/{user_id:[0-9]+}", s.requireVerifiedEmail(actionMessages, s.handleAPISendMessage())).Methods("POST")
+	authenticated.HandleFunc("/admin/users", s.requireRole(model.RoleModerator, s.handleAPIAdminUsers())).Methods("GET")
+	authenticated.HandleFunc("/admin/users/{user_id:[0-9]+}/suspend", s.requireRole(model.RoleModerator, s.handleAPIAdminAction(s.suspendUser))).Methods("POST")
+	authenticated.HandleFunc("/admin/users/{user_id:[0-9]+}/unsuspend", s.requireRole(model.RoleModerator, s.handleAPIAdminAction(s.unsuspendUser))).Methods("POST")
+	authenticated.HandleFunc("/admin/users/{user_id:[0-9]+}/notice", s.requireRole(model.RoleModerator, s.handleAPIAdminSendNotice())).Methods("POST")
+	authenticated.HandleFunc("/admin/users/{user_id:[0-9]+}/role", s.requireRole(model.RoleAdmin, s.handleAPIAdminSetRole())).Methods("PUT")
+	authenticated.HandleFunc("/admin/users/{user_id:[0-9]+}/reset_password", s.requireRole(model.RoleAdmin, s.handleAPIAdminAction(s.resetUserPassword))).Methods("POST")
+	authenticated.HandleFunc("/admin/users/{user_id:[0-9]+}", s.requireRole(model.RoleAdmin, s.handleAPIAdminDeleteUser())).Methods("DELETE")
+}
+
+func (s *server) handleAPISignUp() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		req := &userRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user := &model.User{
+			Email:     strings.TrimSpace(req.Email),
+			Password:  req.Password,
+			Name:      req.Name,
+			Surname:   req.Surname,
+			Age:       req.Age,
+			Sex:       req.Sex,
+			Interests: req.Interests,
+			City:      req.City,
+		}
+
+		if err := user.Validate(); err != nil {
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		if err := s.store.User().Create(user); err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		if err := s.sendVerification(user); err != nil {
+			s.logger.Errorf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+
+		user.Sanitize()
+		s.respond(w, r, http.StatusCreated, user)
+	}
+}
+
+func (s *server) handleAPILogIn() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		req := &logInRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, wait, err := s.authenticate(r, req.Email, req.Password)
+		if err != nil {
+			s.loginError(w, r, wait, err)
+			return
+		}
+
+		twoFactorRequired, err := s.logIn(w, r, user)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		if twoFactorRequired {
+			s.respond(w, r, http.StatusAccepted, &twoFactorRequiredResponse{TwoFactorRequired: true})
+			return
+		}
+
+		user.Sanitize()
+		s.respond(w, r, http.StatusOK, user)
+	}
+}
+
+func (s *server) handleAPILogOut() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if err := s.endSession(w, r); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+func (s *server) handleAPIGetUsers() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		filter, cursor, err := parseUsersPageQuery(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		page, err := s.store.User().GetUsersPage(filter, cursor, numUsersOnOnePage)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, page)
+	}
+}
+
+func (s *server) handleAPISearchUsers() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		query := r.URL.Query()
+		firstName := strings.TrimSpace(query.Get("first_name"))
+		lastName := strings.TrimSpace(query.Get("last_name"))
+		if firstName == "" && lastName == "" {
+			s.error(w, r, http.StatusBadRequest, errors.New("first_name or last_name must be set"))
+			return
+		}
+
+		var cursor *model.Cursor
+		if value := query.Get("cursor"); value != "" {
+			c, err := model.DecodeCursor(value)
+			if err != nil {
+				s.error(w, r, http.StatusBadRequest, err)
+				return
+			}
+			cursor = c
+		}
+
+		page, err := s.store.User().Search(firstName, lastName, numUsersOnOnePage, cursor)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, page)
+	}
+}
+
+func (s *server) handleAPIGetUser() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := strconv.Atoi(mux.Vars(r)["user_id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		user, err := s.store.User().Find(id)
+		if err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		user.Sanitize()
+		user.Email = ""
+		s.respond(w, r, http.StatusOK, user)
+	}
+}
+
+func (s *server) handleAPIGetFriendsList() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		id, err := strconv.Atoi(mux.Vars(r)["user_id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		users, err := s.store.User().GetFriendsList(id)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, &usersResponse{Users: users})
+	}
+}
+
+func (s *server) handleAPIGetMe() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+		user.Sanitize()
+		s.respond(w, r, http.StatusOK, user)
+	}
+}
+
+func (s *server) handleAPIUpdateMe() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		req := &userRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if req.Age <= 0 {
+			s.error(w, r, http.StatusUnprocessableEntity, model.ErrInvalidAge)
+			return
+		}
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+		user.Name = req.Name
+		user.Surname = req.Surname
+		user.Age = req.Age
+		user.Sex = req.Sex
+		user.Interests = req.Interests
+		user.City = req.City
+
+		if err := s.store.User().Update(user); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		s.profileUpdated(user.ID)
+		s.notifyProfileUpdated(user.ID)
+
+		user.Sanitize()
+		s.respond(w, r, http.StatusOK, user)
+	}
+}
+
+func (s *server) handleAPIGetFriendsRequests() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		requests, err := s.friendRequests(user.ID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, requests)
+	}
+}
+
+func (s *server) handleAPISendFriendRequest() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		userID := user.ID
+		friendID, err := s.getFriendID(w, r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if userID == friendID {
+			s.error(w, r, http.StatusBadRequest, errFriendRequestToYourself)
+			return
+		}
+
+		if _, err := s.store.User().Find(friendID); err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		if err := s.store.User().SendFriendRequest(userID, friendID); err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+		s.notifyFriendRequest(userID, friendID)
+
+		s.respond(w, r, http.StatusCreated, nil)
+	}
+}
+
+func (s *server) handleAPIAcceptFriendRequest() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		userID := user.ID
+		friendID, err := s.getFriendID(w, r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.store.User().AcceptFriendRequest(userID, friendID); err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+		s.friendsAdded(userID, friendID)
+		s.notifyFriendRequestAccepted(userID, friendID)
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+func (s *server) handleAPIDeclineFriendRequest() http.HandlerFunc {
+	return s.handleAPIChangeFriendship(s.store.User().DeclineFriendRequest)
+}
+
+func (s *server) handleAPICancelFriendRequest() http.HandlerFunc {
+	return s.handleAPIChangeFriendship(s.store.User().CancelFriendRequest)
+}
+
+func (s *server) handleAPIRemoveFriend() http.HandlerFunc {
+	return s.handleAPIChangeFriendship(s.removeFriend)
+}
+
+// handleAPIChangeFriendship returns handler applying change
+// to friendship between current user and friend from url
+func (s *server) handleAPIChangeFriendship(change func(int, int) error) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		friendID, err := s.getFriendID(w, r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := change(user.ID, friendID); err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// storeError responds with status code matching error returned by store
+func (s *server) storeError(w http.ResponseWriter, r *http.Request, err error) {
+
+	switch err {
+	case store.ErrRecordNotFound:
+		s.error(w, r, http.StatusNotFound, err)
+	case store.ErrFriendRequestWasAlreadySent, store.ErrEmailAlreadyExists, store.ErrTwoFactorAlreadyEnabled:
+		s.error(w, r, http.StatusConflict, err)
+	default:
+		s.error(w, r, http.StatusInternalServerError, err)
+	}
+}
+
+// currentUser returns user put into request context by authenticateUser
+func currentUser(r *http.Request) (*model.User, bool) {
+
+	user, ok := r.Context().Value(ctxKeyUser).(*model.User)
+
+	return user, ok
+}
+
+// requireCurrentUser returns current user, it responds with
+// 401 when handler is reached without authenticateUser
+func (s *server) requireCurrentUser(w http.ResponseWriter, r *http.Request) (*model.User, bool) {
+
+	user, ok := currentUser(r)
+	if !ok {
+		s.error(w, r, http.StatusUnauthorized, errNotAuthenticated)
+	}
+
+	return user, ok
+}
@@ -0,0 +1,40 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DalerBakhriev/social_network/internal/app/store/teststore"
+)
+
+func TestServer_HandleAPIWithoutCurrentUser(t *testing.T) {
+
+	s := newTestServer(t, teststore.New(), testConfig())
+
+	// handler is called bypassing authenticateUser
+	rec := httptest.NewRecorder()
+	s.handleAPIGetMe().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/me", nil))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+}
+
+func TestServer_HandleAPIGetMe(t *testing.T) {
+
+	st := teststore.New()
+	createTestUser(t, st, "user@example.org", "password")
+	srv := httptest.NewServer(newTestServer(t, st, testConfig()))
+	defer srv.Close()
+
+	c := newTestClient(t, srv)
+	if resp, _ := c.get("/api/v1/me"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("anonymous user: status %d", resp.StatusCode)
+	}
+
+	c.logIn("user@example.org", "password")
+	if resp, _ := c.get("/api/v1/me"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("logged in user: status %d", resp.StatusCode)
+	}
+}
@@ -2,12 +2,16 @@ package apiserver
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 
+	"github.com/DalerBakhriev/social_network/internal/app/mailer"
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/sessionstore"
 	"github.com/DalerBakhriev/social_network/internal/app/store/sqlstore"
-	"github.com/gorilla/sessions"
 )
 
 func newDB(databaseURL string) (*sql.DB, error) {
@@ -41,25 +45,180 @@ func getDataBaseURL() string {
 	dbPassword := getEnvOrDefaultValue("MYSQL_PASSWORD", "password")
 	dbName := getEnvOrDefaultValue("MYSQL_DATABASE", "social_network_db")
 
-	dataBaseURL := fmt.Sprintf("%s:%s@tcp(%s)/%s", dbUser, dbPassword, dbHost, dbName)
+	dataBaseURL := fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true&clientFoundRows=true", dbUser, dbPassword, dbHost, dbName)
 
 	return dataBaseURL
 }
 
+// shardDataBaseURL returns url of shard database, shard
+// without database url is kept in the main database
+func shardDataBaseURL(shard ShardConfig) string {
+
+	if shard.DatabaseURL == "" {
+		return getDataBaseURL()
+	}
+
+	return shard.DatabaseURL
+}
+
+// newStore opens the main database and databases of shards
+// and returns store over them with func closing the databases
+func newStore(config *Config) (*sqlstore.Store, func(), error) {
+
+	dbs := make(map[string]*sql.DB)
+	closeAll := func() {
+		for _, db := range dbs {
+			db.Close()
+		}
+	}
+
+	openDB := func(url string) (*sql.DB, error) {
+		if db, ok := dbs[url]; ok {
+			return db, nil
+		}
+		db, err := newDB(url)
+		if err != nil {
+			return nil, err
+		}
+		dbs[url] = db
+		return db, nil
+	}
+
+	db, err := openDB(getDataBaseURL())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(config.Sharding.Shards) == 0 {
+		return sqlstore.New(db), closeAll, nil
+	}
+
+	shards := make([]*sqlstore.Shard, 0, len(config.Sharding.Shards))
+	for _, shard := range config.Sharding.Shards {
+		shardDB, err := openDB(shardDataBaseURL(shard))
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("shard %s: %w", shard.Name, err)
+		}
+		shards = append(shards, &sqlstore.Shard{
+			Name:  shard.Name,
+			DB:    shardDB,
+			State: shard.State,
+		})
+	}
+
+	store, err := sqlstore.NewSharded(db, shards, config.Sharding.VirtualNodes)
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+
+	return store, closeAll, nil
+}
+
+// newMailer returns mailer described by config with func
+// closing file emails are written to
+func newMailer(config MailerConfig) (mailer.Mailer, func(), error) {
+
+	switch config.Type {
+	case "smtp":
+		return mailer.NewSMTPMailer(config.Host, config.Port, config.Username, config.Password, config.From), func() {}, nil
+
+	case "log":
+		if config.File == "" {
+			return mailer.NewLogMailer(os.Stdout, config.From), func() {}, nil
+		}
+		f, err := os.OpenFile(config.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, nil, err
+		}
+		return mailer.NewLogMailer(f, config.From), func() { f.Close() }, nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown mailer type %q", config.Type)
+}
+
 // Start ...
 func Start(config *Config) error {
 
-	dataBaseURL := getDataBaseURL()
-	db, err := newDB(dataBaseURL)
+	if err := model.SetPasswordCost(config.BcryptCost); err != nil {
+		return err
+	}
+
+	for _, limit := range config.RateLimits {
+		if err := limit.validate(); err != nil {
+			return err
+		}
+	}
+
+	store, closeStore, err := newStore(config)
 	if err != nil {
 		return err
 	}
 
-	defer db.Close()
+	defer closeStore()
 
-	store := sqlstore.New(db)
-	sessionStore := sessions.NewCookieStore([]byte(config.SessionKey))
-	srv := newServer(store, sessionStore)
+	mailer, closeMailer, err := newMailer(config.Mailer)
+	if err != nil {
+		return err
+	}
+
+	defer closeMailer()
+
+	sessionStore := sessionstore.NewStore(store.Session(), config.SessionMaxAge, []byte(config.SessionKey))
+	sessionStore.Options.Secure = config.SecureCookies
+	srv := newServer(store, sessionStore, mailer, config)
+
+	done := make(chan struct{})
+	defer close(done)
+	go srv.cleanup(sessionStore, done)
 
 	return http.ListenAndServe(config.BindAddr, srv)
 }
+
+// Reshard moves users to shards they belong to according
+// to config and writes its report to out
+func Reshard(out io.Writer, config *Config) error {
+
+	store, closeStore, err := newStore(config)
+	if err != nil {
+		return err
+	}
+
+	defer closeStore()
+
+	moved, err := store.Reshard(func(userID int, from, to string) {
+		fmt.Fprintf(out, "Moved user %d from %s to %s\n", userID, from, to)
+	})
+	fmt.Fprintf(out, "Moved %d users\n", moved)
+
+	return err
+}
+
+// SetRole gives role to user with email, it is how the first admin
+// is appointed. Args are email and role
+func SetRole(out io.Writer, config *Config, args []string) error {
+
+	if len(args) != 2 {
+		return errors.New("Usage: role <email> user | moderator | admin")
+	}
+
+	store, closeStore, err := newStore(config)
+	if err != nil {
+		return err
+	}
+
+	defer closeStore()
+
+	user, err := store.User().FindByEmail(args[0])
+	if err != nil {
+		return fmt.Errorf("user %s: %w", args[0], err)
+	}
+
+	if err := store.User().SetRole(user.ID, args[1]); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "User %d %s is %s now\n", user.ID, user.Email, args[1])
+
+	return nil
+}
@@ -0,0 +1,294 @@
+package apiserver
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// apiTokenPrefix makes tokens easy to recognize in scripts and leaked logs
+	apiTokenPrefix = "snt_"
+	// apiTokenTouchPeriod is how often last-used time of token is updated
+	apiTokenTouchPeriod = time.Minute
+)
+
+// sessionOnlyPaths are paths of administration and account security,
+// token leaked from script must not let anyone manage accounts or
+// keep access after the owner revokes it
+var sessionOnlyPaths = []string{
+	"/admin",
+	"/api/v1/admin",
+	"/api/v1/sessions",
+	"/api/v1/api_tokens",
+	"/api/v1/two_factor",
+}
+
+type apiTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// apiTokensResponse is json body of response with tokens of user
+type apiTokensResponse struct {
+	APITokens []*model.APIToken `json:"api_tokens"`
+}
+
+// apiTokenCreatedResponse holds token itself, it is not shown again
+type apiTokenCreatedResponse struct {
+	Token    string          `json:"token"`
+	APIToken *model.APIToken `json:"api_token"`
+}
+
+func (s *server) handleAPITokens() http.HandlerFunc {
+
+	tmpl := parseTemplate("api_tokens.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		page := model.APITokensPage{CurrUserID: userID}
+		if r.Method == http.MethodPost {
+			if err := r.ParseForm(); err != nil {
+				s.error(w, r, http.StatusBadRequest, err)
+				return
+			}
+
+			token, _, err := s.createAPIToken(userID, r.PostForm.Get("name"), r.PostForm["scopes"])
+			if err != nil {
+				s.apiTokenError(w, r, err)
+				return
+			}
+			page.NewToken = token
+		}
+
+		if page.Tokens, err = s.store.APIToken().GetByUser(userID); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.render(w, r, tmpl, page)
+	}
+}
+
+func (s *server) handleRevokeAPIToken() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		tokenID, err := strconv.Atoi(mux.Vars(r)["token_id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.store.APIToken().Delete(userID, tokenID); err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, "/api_tokens", http.StatusFound)
+	}
+}
+
+func (s *server) handleAPIGetAPITokens() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		tokens, err := s.store.APIToken().GetByUser(user.ID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, apiTokensResponse{APITokens: tokens})
+	}
+}
+
+func (s *server) handleAPICreateAPIToken() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		req := &apiTokenRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		token, apiToken, err := s.createAPIToken(user.ID, req.Name, req.Scopes)
+		if err != nil {
+			s.apiTokenError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, &apiTokenCreatedResponse{
+			Token:    token,
+			APIToken: apiToken,
+		})
+	}
+}
+
+func (s *server) handleAPIRevokeAPIToken() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		tokenID, err := strconv.Atoi(mux.Vars(r)["token_id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.store.APIToken().Delete(user.ID, tokenID); err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// createAPIToken generates token for user, only its hash is stored
+func (s *server) createAPIToken(userID int, name string, scopes []string) (string, *model.APIToken, error) {
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	token := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	apiToken := &model.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		TokenHash: hashAPIToken(token),
+	}
+
+	if err := apiToken.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	if err := s.store.APIToken().Create(apiToken); err != nil {
+		return "", nil, err
+	}
+
+	return token, apiToken, nil
+}
+
+// authenticateAPIToken returns owner of bearer token,
+// last-used time of token is recorded once in a minute
+func (s *server) authenticateAPIToken(token string) (*model.User, *model.APIToken, error) {
+
+	apiToken, err := s.store.APIToken().FindByHash(hashAPIToken(token))
+	if err != nil {
+		if err == store.ErrRecordNotFound {
+			return nil, nil, errInvalidAPIToken
+		}
+		return nil, nil, err
+	}
+
+	user, err := s.store.User().Find(apiToken.UserID)
+	if err != nil {
+		if err == store.ErrRecordNotFound {
+			return nil, nil, errInvalidAPIToken
+		}
+		return nil, nil, err
+	}
+	if user.Suspended() {
+		return nil, nil, errAccountSuspended
+	}
+
+	now := time.Now()
+	if apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) > apiTokenTouchPeriod {
+		if err := s.store.APIToken().Touch(apiToken.ID, now); err != nil {
+			s.logger.Errorf("Failed to record use of api token %d: %v", apiToken.ID, err)
+		}
+	}
+
+	return user, apiToken, nil
+}
+
+// bearerToken returns token from Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+
+	header := r.Header.Get("Authorization")
+	if len(header) < len("Bearer ") || !strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return "", false
+	}
+
+	return strings.TrimSpace(header[len("Bearer "):]), true
+}
+
+// requiredScope returns scope api token needs for request, empty scope
+// means the route is available only with session, e.g. managing tokens
+func requiredScope(r *http.Request) string {
+
+	for _, path := range sessionOnlyPaths {
+		if r.URL.Path == path || strings.HasPrefix(r.URL.Path, path+"/") {
+			return ""
+		}
+	}
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return model.ScopeRead
+	}
+
+	switch path := r.URL.Path; {
+	case strings.HasPrefix(path, "/api/v1/friend_requests/"), strings.HasPrefix(path, "/api/v1/friends/"):
+		return model.ScopeFriends
+	case strings.HasPrefix(path, "/api/v1/dialogs/"):
+		return model.ScopeMessages
+	}
+
+	return ""
+}
+
+func hashAPIToken(token string) string {
+
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *server) apiTokenError(w http.ResponseWriter, r *http.Request, err error) {
+
+	switch err {
+	case model.ErrEmptyAPITokenName, model.ErrAPITokenNameTooLong, model.ErrNoAPITokenScopes, model.ErrUnknownAPITokenScope:
+		s.error(w, r, http.StatusUnprocessableEntity, err)
+	default:
+		s.storeError(w, r, err)
+	}
+}
@@ -0,0 +1,56 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store/teststore"
+)
+
+func TestServer_APITokenScope(t *testing.T) {
+
+	st := teststore.New()
+	user := createTestUser(t, st, "moderator@example.org", "password")
+	if err := st.User().SetRole(user.ID, model.RoleModerator); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.APIToken().Create(&model.APIToken{
+		UserID:    user.ID,
+		Name:      "script",
+		Scopes:    []string{model.ScopeRead, model.ScopeFriends, model.ScopeMessages},
+		TokenHash: hashAPIToken("secret"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer(t, st, testConfig())
+
+	testCases := []struct {
+		method       string
+		path         string
+		expectedCode int
+	}{
+		{method: "GET", path: "/api/v1/me", expectedCode: http.StatusOK},
+		{method: "GET", path: "/admin/users", expectedCode: http.StatusForbidden},
+		{method: "GET", path: "/api/v1/admin/users", expectedCode: http.StatusForbidden},
+		{method: "GET", path: "/api/v1/sessions", expectedCode: http.StatusForbidden},
+		{method: "DELETE", path: "/api/v1/sessions", expectedCode: http.StatusForbidden},
+		{method: "GET", path: "/api/v1/api_tokens", expectedCode: http.StatusForbidden},
+		{method: "POST", path: "/api/v1/api_tokens", expectedCode: http.StatusForbidden},
+		{method: "GET", path: "/api/v1/two_factor", expectedCode: http.StatusForbidden},
+		{method: "POST", path: "/api/v1/two_factor/disable", expectedCode: http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			s.ServeHTTP(rec, req)
+			if rec.Code != tc.expectedCode {
+				t.Fatalf("expected status %d, got %d", tc.expectedCode, rec.Code)
+			}
+		})
+	}
+}
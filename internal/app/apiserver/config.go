@@ -1,10 +1,141 @@
 package apiserver
 
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/ratelimit"
+)
+
 // Config contains apiserver configuration setting
 type Config struct {
-	BindAddr   string `toml:"bind_addr"`
-	LogLevel   string `toml:"log_level"`
-	SessionKey string `toml:"session_key"`
+	BindAddr              string                   `toml:"bind_addr"`
+	LogLevel              string                   `toml:"log_level"`
+	SessionKey            string                   `toml:"session_key"`
+	SessionMaxAge         int                      `toml:"session_max_age"`
+	BcryptCost            int                      `toml:"bcrypt_cost"`
+	LoginThrottle         LoginThrottleConfig      `toml:"login_throttle"`
+	RateLimits            []RateLimitConfig        `toml:"rate_limits"`
+	SecureCookies         bool                     `toml:"secure_cookies"`
+	CORSAllowedOrigins    []string                 `toml:"cors_allowed_origins"`
+	BaseURL               string                   `toml:"base_url"`
+	Mailer                MailerConfig             `toml:"mailer"`
+	Verification          VerificationConfig       `toml:"verification"`
+	PasswordResetCooldown int                      `toml:"password_reset_cooldown"`
+	TwoFactorKey          string                   `toml:"two_factor_key"`
+	IdentityProviders     []IdentityProviderConfig `toml:"identity_providers"`
+	MessagesFriendsOnly   bool                     `toml:"messages_friends_only"`
+	Sharding              ShardingConfig           `toml:"sharding"`
+}
+
+// LoginThrottleConfig slows down password guessing, durations are in seconds.
+// After FreeAttempts failures every next log in has to wait twice as long
+// as the previous one starting from a second up to MaxBackoff, after
+// LockoutAttempts failures log in is locked for LockoutDuration. Failures
+// are counted per email and per ip address, many users may share address,
+// so it has its own IPFreeAttempts and IPLockoutAttempts
+type LoginThrottleConfig struct {
+	FreeAttempts      int `toml:"free_attempts"`
+	MaxBackoff        int `toml:"max_backoff"`
+	LockoutAttempts   int `toml:"lockout_attempts"`
+	IPFreeAttempts    int `toml:"ip_free_attempts"`
+	IPLockoutAttempts int `toml:"ip_lockout_attempts"`
+	LockoutDuration   int `toml:"lockout_duration"`
+}
+
+// RateLimitConfig limits requests to Routes with Methods, all methods when
+// Methods is empty. Routes are path templates as in OpenAPI specification.
+// Every user or ip address may make Requests per Period seconds on average
+// with bursts up to Burst requests, Burst equal to zero means Requests
+type RateLimitConfig struct {
+	Name     string   `toml:"name"`
+	Routes   []string `toml:"routes"`
+	Methods  []string `toml:"methods"`
+	Requests int      `toml:"requests"`
+	Period   int      `toml:"period"`
+	Burst    int      `toml:"burst"`
+}
+
+func (c RateLimitConfig) validate() error {
+
+	if c.Name == "" {
+		return errors.New("rate limit must have name")
+	}
+	if c.Requests <= 0 || c.Period <= 0 || c.Burst < 0 {
+		return fmt.Errorf("rate limit %s: requests and period must be positive", c.Name)
+	}
+
+	return nil
+}
+
+func (c RateLimitConfig) matches(method, route string) bool {
+
+	if len(c.Methods) > 0 && !containsString(c.Methods, method) {
+		return false
+	}
+
+	return containsString(c.Routes, route)
+}
+
+func (c RateLimitConfig) limit() ratelimit.Limit {
+	return ratelimit.Limit{
+		Requests: c.Requests,
+		Period:   time.Duration(c.Period) * time.Second,
+		Burst:    c.Burst,
+	}
+}
+
+// MailerConfig describes how emails are sent, Type is smtp or log.
+// Log mailer writes emails to File or to stdout when File is empty
+type MailerConfig struct {
+	Type     string `toml:"type"`
+	From     string `toml:"from"`
+	File     string `toml:"file"`
+	Host     string `toml:"host"`
+	Port     int    `toml:"port"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+}
+
+// VerificationConfig sets up email verification, durations are in seconds.
+// Restrict lists actions forbidden to users with unverified email,
+// they are friend_requests, messages and posts
+type VerificationConfig struct {
+	LinkTTL        int      `toml:"link_ttl"`
+	ResendCooldown int      `toml:"resend_cooldown"`
+	Restrict       []string `toml:"restrict"`
+}
+
+func (c VerificationConfig) restricts(action string) bool {
+	return containsString(c.Restrict, action)
+}
+
+// IdentityProviderConfig describes OpenID Connect provider users may sign
+// in with. Name is used in urls, redirect url registered at provider
+// is BaseURL + /auth/{name}/callback
+type IdentityProviderConfig struct {
+	Name         string   `toml:"name"`
+	DisplayName  string   `toml:"display_name"`
+	Issuer       string   `toml:"issuer"`
+	ClientID     string   `toml:"client_id"`
+	ClientSecret string   `toml:"client_secret"`
+	Scopes       []string `toml:"scopes"`
+}
+
+// ShardingConfig lists databases users and friendships are partitioned across
+type ShardingConfig struct {
+	VirtualNodes int           `toml:"virtual_nodes"`
+	Shards       []ShardConfig `toml:"shards"`
+}
+
+// ShardConfig describes single shard, empty database url means
+// the main database. State is one of active, joining or leaving
+type ShardConfig struct {
+	Name        string `toml:"name"`
+	DatabaseURL string `toml:"database_url"`
+	State       string `toml:"state"`
 }
 
 // NewConfig ...
@@ -12,5 +143,42 @@ func NewConfig() *Config {
 	return &Config{
 		BindAddr: ":8080",
 		LogLevel: "debug",
+		// sessions expire after 30 days of inactivity
+		SessionMaxAge: 30 * 24 * 60 * 60,
+		BcryptCost:    model.DefaultPasswordCost,
+		LoginThrottle: LoginThrottleConfig{
+			FreeAttempts:      3,
+			MaxBackoff:        60,
+			LockoutAttempts:   10,
+			IPFreeAttempts:    20,
+			IPLockoutAttempts: 100,
+			LockoutDuration:   15 * 60,
+		},
+		BaseURL: "http://localhost:8080",
+		Mailer: MailerConfig{
+			Type: "log",
+			From: "noreply@localhost",
+			Port: 25,
+		},
+		Verification: VerificationConfig{
+			LinkTTL:        3 * 24 * 60 * 60,
+			ResendCooldown: 60,
+			Restrict:       []string{"friend_requests"},
+		},
+		PasswordResetCooldown: 60,
+		Sharding: ShardingConfig{
+			VirtualNodes: 100,
+		},
 	}
 }
+
+func containsString(list []string, s string) bool {
+
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
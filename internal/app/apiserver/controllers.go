@@ -4,12 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
-	"io/ioutil"
 	"net/http"
+	"net/url"
 	"path"
 	"strconv"
+	"strings"
 
 	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"github.com/gorilla/csrf"
 	"github.com/gorilla/mux"
 )
 
@@ -18,17 +21,65 @@ const (
 	templatesPath     = "./internal/app/apiserver/templates"
 )
 
+// parseTemplate parses page template, page may show number
+// of unread notifications of current user with unreadNotifications,
+// every form posted to server must contain csrfField
+func parseTemplate(name string) *template.Template {
+	return template.Must(template.New(name).Funcs(template.FuncMap{
+		"unreadNotifications": func() int { return 0 },
+		"csrfField":           func() template.HTML { return "" },
+	}).ParseFiles(path.Join(templatesPath, name)))
+}
+
+// render executes page template for current user
+func (s *server) render(w http.ResponseWriter, r *http.Request, tmpl *template.Template, data interface{}) {
+
+	page, err := tmpl.Clone()
+	if err != nil {
+		s.error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	page.Funcs(template.FuncMap{
+		"unreadNotifications": func() int { return s.unreadNotifications(r) },
+		"csrfField":           func() template.HTML { return csrf.TemplateField(r) },
+	})
+
+	if err := page.Execute(w, data); err != nil {
+		s.logger.Errorf("Failed to render %s: %v", tmpl.Name(), err)
+	}
+}
+
+// unreadNotifications returns number of unread notifications
+// of current user, it is zero for anonymous user
+func (s *server) unreadNotifications(r *http.Request) int {
+
+	session, err := s.sessionStore.Get(r, sessionName)
+	if err != nil {
+		return 0
+	}
+
+	userID, ok := session.Values["user_id"].(int)
+	if !ok {
+		return 0
+	}
+
+	unread, err := s.store.Notification().CountUnread(userID)
+	if err != nil {
+		s.logger.Errorf("Failed to count unread notifications of user %d: %v", userID, err)
+		return 0
+	}
+
+	return unread
+}
+
 func (s *server) handleSignUp() http.HandlerFunc {
 
+	tmpl := parseTemplate("signup.html")
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		if r.Method != http.MethodPost {
-			loginForm, err := ioutil.ReadFile(path.Join(templatesPath, "signup.html"))
-			if err != nil {
-				s.error(w, r, http.StatusInternalServerError, err)
-				return
-			}
-			w.Write(loginForm)
+			s.render(w, r, tmpl, model.LogInPage{Providers: s.identityProviderLinks()})
 			return
 		}
 
@@ -58,11 +109,20 @@ func (s *server) handleSignUp() http.HandlerFunc {
 			Interests: inputInterests,
 		}
 
+		if err := user.Validate(); err != nil {
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
 		if err := s.store.User().Create(user); err != nil {
-			s.error(w, r, http.StatusInternalServerError, err)
+			s.storeError(w, r, err)
 			return
 		}
 
+		if err := s.sendVerification(user); err != nil {
+			s.logger.Errorf("Failed to send verification email to user %d: %v", user.ID, err)
+		}
+
 		user.Sanitize()
 		http.Redirect(w, r, "/login", http.StatusFound)
 	}
@@ -72,57 +132,101 @@ func (s *server) handleMainPage() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		users, err := s.store.User().GetTopUsers(numUsersOnOnePage)
+		filter, cursor, err := parseUsersPageQuery(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		page, err := s.store.User().GetUsersPage(filter, cursor, numUsersOnOnePage)
 		if err != nil {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
 
-		usersForTemplate := model.Users{Users: users}
-		tmpl := template.Must(template.ParseFiles(path.Join(templatesPath, "users.html")))
-		tmpl.Execute(w, usersForTemplate)
+		usersForTemplate := model.Users{
+			Users:    page.Users,
+			Filter:   filter,
+			NextPage: usersPageQuery(filter, page.NextCursor),
+			PrevPage: usersPageQuery(filter, page.PrevCursor),
+		}
+		tmpl := parseTemplate("users.html")
+		s.render(w, r, tmpl, usersForTemplate)
 	}
 
 }
 
-func (s *server) handleLogIn() http.HandlerFunc {
+func (s *server) handleSearchUsers() http.HandlerFunc {
 
+	tmpl := parseTemplate("users_search.html")
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		if r.Method != http.MethodPost {
-			loginForm, err := ioutil.ReadFile(path.Join(templatesPath, "login.html"))
+		query := r.URL.Query()
+		firstName := strings.TrimSpace(query.Get("first_name"))
+		lastName := strings.TrimSpace(query.Get("last_name"))
+
+		var cursor *model.Cursor
+		if value := query.Get("cursor"); value != "" {
+			c, err := model.DecodeCursor(value)
+			if err != nil {
+				s.error(w, r, http.StatusBadRequest, err)
+				return
+			}
+			cursor = c
+		}
+
+		page := &model.UsersPage{Users: make([]*model.User, 0)}
+		if firstName != "" || lastName != "" {
+			var err error
+			page, err = s.store.User().Search(firstName, lastName, numUsersOnOnePage, cursor)
 			if err != nil {
 				s.error(w, r, http.StatusInternalServerError, err)
 				return
 			}
-			w.Write(loginForm)
+		}
+
+		if wantsJSON(r) {
+			s.respond(w, r, http.StatusOK, page)
 			return
 		}
 
-		inputEmail := r.FormValue("email")
-		inputPassword := r.FormValue("password")
+		s.render(w, r, tmpl, model.UsersSearch{
+			Users:     page.Users,
+			FirstName: firstName,
+			LastName:  lastName,
+			NextPage:  searchPageQuery(firstName, lastName, page.NextCursor),
+			PrevPage:  searchPageQuery(firstName, lastName, page.PrevCursor),
+		})
+	}
+}
 
-		user, err := s.store.User().FindByEmail(inputEmail)
-		if err != nil {
-			s.error(w, r, http.StatusUnauthorized, err)
+func (s *server) handleLogIn() http.HandlerFunc {
+
+	tmpl := parseTemplate("login.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodPost {
+			s.render(w, r, tmpl, model.LogInPage{Providers: s.identityProviderLinks()})
 			return
 		}
 
-		if passwordIsCorrect := user.ComparePassword(inputPassword); !passwordIsCorrect {
-			s.error(w, r, http.StatusUnauthorized, errInncorrectEmailOrPassword)
+		inputEmail := r.FormValue("email")
+		inputPassword := r.FormValue("password")
+
+		user, wait, err := s.authenticate(r, inputEmail, inputPassword)
+		if err != nil {
+			s.loginError(w, r, wait, err)
 			return
 		}
 
-		session, err := s.sessionStore.Get(r, sessionName)
+		twoFactorRequired, err := s.logIn(w, r, user)
 		if err != nil {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
 
-		session.Values["user_id"] = user.ID
-		err = session.Save(r, w)
-		if err != nil {
-			s.error(w, r, http.StatusInternalServerError, err)
+		if twoFactorRequired {
+			http.Redirect(w, r, "/login/2fa", http.StatusFound)
 			return
 		}
 
@@ -134,43 +238,28 @@ func (s *server) handleLogOut() http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		session, err := s.sessionStore.Get(r, sessionName)
-		if err != nil {
-			s.error(w, r, http.StatusInternalServerError, err)
-			return
-		}
-
-		session.Values["user_id"] = -1
-		session.Options.MaxAge = -1
-
-		err = session.Save(r, w)
-		if err != nil {
+		if err := s.endSession(w, r); err != nil {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
 
-		http.Redirect(w, r, "/", http.StatusOK)
+		http.Redirect(w, r, "/", http.StatusFound)
 	}
 }
 
 func (s *server) handleUserEdit() http.HandlerFunc {
 
+	tmpl := parseTemplate("user_edit.html")
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		if r.Method != http.MethodPost {
-
-			userEditForm, err := ioutil.ReadFile(path.Join(templatesPath, "user_edit.html"))
-			if err != nil {
-				s.respond(w, r, http.StatusInternalServerError, err)
-				return
-			}
-			w.Write(userEditForm)
+			s.render(w, r, tmpl, nil)
 			return
 		}
 
 		userID, err := s.getUserID(w, r)
 		if err != nil {
-			s.error(w, r, http.StatusUnauthorized, err)
+			s.userIDError(w, r, err)
 			return
 		}
 
@@ -201,6 +290,8 @@ func (s *server) handleUserEdit() http.HandlerFunc {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
+		s.profileUpdated(user.ID)
+		s.notifyProfileUpdated(user.ID)
 
 		http.Redirect(w, r, fmt.Sprintf("/users/%d", user.ID), http.StatusFound)
 	}
@@ -208,7 +299,7 @@ func (s *server) handleUserEdit() http.HandlerFunc {
 
 func (s *server) handleGetSingleUser() http.HandlerFunc {
 
-	tmpl := template.Must(template.ParseFiles(path.Join(templatesPath, "user.html")))
+	tmpl := parseTemplate("user.html")
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		vars := mux.Vars(r)
@@ -219,14 +310,41 @@ func (s *server) handleGetSingleUser() http.HandlerFunc {
 			return
 		}
 		user, err := s.store.User().Find(id)
-		tmpl.Execute(w, user)
+		if err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		beforeID, err := getBeforeID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		posts, err := s.store.Post().GetByAuthor(id, beforeID, numPostsOnOnePage)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		currUserID, err := s.getUserID(w, r)
+		if err != nil {
+			currUserID = -1
+		}
+
+		s.render(w, r, tmpl, model.Wall{
+			User:       *user,
+			Posts:      posts.Posts,
+			CurrUserID: currUserID,
+			NextPage:   postsPageURL(fmt.Sprintf("/users/%d", id), posts.NextBefore),
+		})
 	}
 
 }
 
 func (s *server) handleGetFriendsRequests() http.HandlerFunc {
 
-	tmpl := template.Must(template.ParseFiles(path.Join(templatesPath, "requests.html")))
+	tmpl := parseTemplate("requests.html")
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		vars := mux.Vars(r)
@@ -237,19 +355,40 @@ func (s *server) handleGetFriendsRequests() http.HandlerFunc {
 			s.error(w, r, http.StatusBadRequest, err)
 			return
 		}
-		users, err := s.store.User().GetFriendsRequests(id)
 
-		usersForTemplate := model.FriendsAndRequests{
-			Users:      users,
-			CurrUserID: id,
+		requests, err := s.friendRequests(id)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
 		}
-		tmpl.Execute(w, usersForTemplate)
+
+		s.render(w, r, tmpl, requests)
+	}
+}
+
+// friendRequests returns incoming and outgoing friend requests of user
+func (s *server) friendRequests(userID int) (*model.FriendRequests, error) {
+
+	incoming, err := s.store.User().GetIncomingFriendsRequests(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	outgoing, err := s.store.User().GetOutgoingFriendsRequests(userID)
+	if err != nil {
+		return nil, err
 	}
+
+	return &model.FriendRequests{
+		Incoming:   incoming,
+		Outgoing:   outgoing,
+		CurrUserID: userID,
+	}, nil
 }
 
 func (s *server) handleGetFriendsList() http.HandlerFunc {
 
-	tmpl := template.Must(template.ParseFiles(path.Join(templatesPath, "friends.html")))
+	tmpl := parseTemplate("friends.html")
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		vars := mux.Vars(r)
@@ -266,8 +405,97 @@ func (s *server) handleGetFriendsList() http.HandlerFunc {
 			Users:      users,
 			CurrUserID: id,
 		}
-		tmpl.Execute(w, usersForTemplate)
+		s.render(w, r, tmpl, usersForTemplate)
+	}
+}
+
+// parseUsersPageQuery reads users directory filter and page cursor
+// from query parameters
+func parseUsersPageQuery(r *http.Request) (*model.UserFilter, *model.Cursor, error) {
+
+	query := r.URL.Query()
+	filter := &model.UserFilter{
+		City:      query.Get("city"),
+		Sex:       query.Get("sex"),
+		Interests: query.Get("interests"),
+	}
+
+	for param, dest := range map[string]*int{
+		"min_age": &filter.MinAge,
+		"max_age": &filter.MaxAge,
+	} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		age, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("wrong %s format, must be number", param)
+		}
+		*dest = age
+	}
+
+	var cursor *model.Cursor
+	if value := query.Get("cursor"); value != "" {
+		c, err := model.DecodeCursor(value)
+		if err != nil {
+			return nil, nil, err
+		}
+		cursor = c
+	}
+
+	return filter, cursor, nil
+}
+
+// usersPageQuery returns url of users directory page with given
+// filter and cursor, empty cursor means there is no such page
+func usersPageQuery(filter *model.UserFilter, cursor string) string {
+
+	if cursor == "" {
+		return ""
+	}
+
+	query := url.Values{}
+	if filter.City != "" {
+		query.Set("city", filter.City)
+	}
+	if filter.Sex != "" {
+		query.Set("sex", filter.Sex)
+	}
+	if filter.MinAge > 0 {
+		query.Set("min_age", strconv.Itoa(filter.MinAge))
+	}
+	if filter.MaxAge > 0 {
+		query.Set("max_age", strconv.Itoa(filter.MaxAge))
+	}
+	if filter.Interests != "" {
+		query.Set("interests", filter.Interests)
 	}
+	query.Set("cursor", cursor)
+
+	return "/?" + query.Encode()
+}
+
+// searchPageQuery returns url of users search page with given
+// name prefixes and cursor, empty cursor means there is no such page
+func searchPageQuery(firstName, lastName, cursor string) string {
+
+	if cursor == "" {
+		return ""
+	}
+
+	query := url.Values{}
+	query.Set("first_name", firstName)
+	query.Set("last_name", lastName)
+	query.Set("cursor", cursor)
+
+	return "/users/search?" + query.Encode()
+}
+
+// wantsJSON tells whether client asked for json instead of html page
+func wantsJSON(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "json" ||
+		strings.Contains(r.Header.Get("Accept"), "application/json")
 }
 
 func (s *server) getUserID(w http.ResponseWriter, r *http.Request) (int, error) {
@@ -278,12 +506,35 @@ func (s *server) getUserID(w http.ResponseWriter, r *http.Request) (int, error)
 		return -1, err
 	}
 
-	userID, ok := session.Values["user_id"]
+	userID, ok := session.Values["user_id"].(int)
 	if !ok {
 		return -1, errNotAuthenticated
 	}
 
-	return userID.(int), nil
+	user, err := s.store.User().Find(userID)
+	if err != nil {
+		if err == store.ErrRecordNotFound {
+			return -1, errNotAuthenticated
+		}
+		return -1, err
+	}
+
+	if user.Suspended() {
+		return -1, errAccountSuspended
+	}
+
+	return userID, nil
+}
+
+// userIDError responds to request getUserID did not authenticate
+func (s *server) userIDError(w http.ResponseWriter, r *http.Request, err error) {
+
+	if err == errAccountSuspended {
+		s.error(w, r, http.StatusForbidden, err)
+		return
+	}
+
+	s.error(w, r, http.StatusUnauthorized, err)
 }
 
 func (s *server) getFriendID(w http.ResponseWriter, r *http.Request) (int, error) {
@@ -305,7 +556,7 @@ func (s *server) handleSendFriendsRequest() http.HandlerFunc {
 
 		userID, err := s.getUserID(w, r)
 		if err != nil {
-			s.error(w, r, http.StatusUnauthorized, err)
+			s.userIDError(w, r, err)
 			return
 		}
 
@@ -316,15 +567,20 @@ func (s *server) handleSendFriendsRequest() http.HandlerFunc {
 		}
 
 		if userID == friendID {
-			s.error(w, r, http.StatusBadRequest, errors.New("You cant send friends request to yourself"))
+			s.error(w, r, http.StatusBadRequest, errFriendRequestToYourself)
+			return
+		}
+
+		if _, err := s.store.User().Find(friendID); err != nil {
+			s.storeError(w, r, err)
 			return
 		}
 
-		errFriendRequest := s.store.User().SendFriendRequest(userID, friendID)
-		if errFriendRequest != nil {
-			s.error(w, r, http.StatusInternalServerError, errFriendRequest)
+		if err := s.store.User().SendFriendRequest(userID, friendID); err != nil {
+			s.storeError(w, r, err)
 			return
 		}
+		s.notifyFriendRequest(userID, friendID)
 
 		http.Redirect(w, r, fmt.Sprintf("/users/%d", friendID), http.StatusFound)
 	}
@@ -336,7 +592,7 @@ func (s *server) handleAcceptFriendsRequest() http.HandlerFunc {
 
 		userID, err := s.getUserID(w, r)
 		if err != nil {
-			s.error(w, r, http.StatusUnauthorized, err)
+			s.userIDError(w, r, err)
 			return
 		}
 
@@ -359,10 +615,63 @@ func (s *server) handleAcceptFriendsRequest() http.HandlerFunc {
 		}
 
 		if err := s.store.User().AcceptFriendRequest(userID, friendID); err != nil {
-			s.error(w, r, http.StatusInternalServerError, err)
+			s.storeError(w, r, err)
 			return
 		}
+		s.friendsAdded(userID, friendID)
+		s.notifyFriendRequestAccepted(userID, friendID)
 
 		http.Redirect(w, r, fmt.Sprintf("/users/%d", userID), http.StatusFound)
 	}
 }
+
+func (s *server) handleDeclineFriendsRequest() http.HandlerFunc {
+	return s.handleChangeFriendship(s.store.User().DeclineFriendRequest, "/users/%d/friends_requests")
+}
+
+func (s *server) handleCancelFriendsRequest() http.HandlerFunc {
+	return s.handleChangeFriendship(s.store.User().CancelFriendRequest, "/users/%d/friends_requests")
+}
+
+func (s *server) handleRemoveFriend() http.HandlerFunc {
+	return s.handleChangeFriendship(s.removeFriend, "/users/%d/friends")
+}
+
+// handleChangeFriendship returns handler applying change to friendship
+// between user from url, who must be logged in, and friend from url.
+// After that it redirects to page of the user built from redirectFormat
+func (s *server) handleChangeFriendship(change func(int, int) error, redirectFormat string) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		userIDFromRequest, err := strconv.Atoi(mux.Vars(r)["user_id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if userID != userIDFromRequest {
+			s.error(w, r, http.StatusUnauthorized, errors.New("To change friendship login as current user"))
+			return
+		}
+
+		friendID, err := s.getFriendID(w, r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := change(userID, friendID); err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf(redirectFormat, userID), http.StatusFound)
+	}
+}
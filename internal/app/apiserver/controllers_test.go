@@ -0,0 +1,223 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/store/teststore"
+)
+
+func TestServer_HandleSignUp(t *testing.T) {
+
+	st := teststore.New()
+	createTestUser(t, st, "taken@example.org", "password")
+	srv := httptest.NewServer(newTestServer(t, st, testConfig()))
+	defer srv.Close()
+
+	testCases := []struct {
+		name         string
+		form         url.Values
+		expectedCode int
+	}{
+		{
+			name: "valid",
+			form: url.Values{
+				"email":    {"user@example.org"},
+				"password": {"password"},
+				"name":     {"Ann"},
+				"surname":  {"Smith"},
+				"city":     {"Moscow"},
+				"age":      {"25"},
+				"sex":      {"female"},
+			},
+			expectedCode: http.StatusFound,
+		},
+		{
+			name:         "invalid age",
+			form:         url.Values{"email": {"age@example.org"}, "password": {"password"}, "age": {"young"}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid email",
+			form:         url.Values{"email": {"invalid"}, "password": {"password"}, "age": {"25"}},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "email is taken",
+			form:         url.Values{"email": {"taken@example.org"}, "password": {"password"}, "age": {"25"}},
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := newTestClient(t, srv).postForm("/signup", tc.form)
+			if resp.StatusCode != tc.expectedCode {
+				t.Fatalf("expected status %d, got %d", tc.expectedCode, resp.StatusCode)
+			}
+		})
+	}
+
+	user, err := st.User().FindByEmail("user@example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "Ann" || user.Age != 25 || !user.ComparePassword("password") {
+		t.Fatalf("unexpected user %+v", user)
+	}
+}
+
+func TestServer_HandleLogIn(t *testing.T) {
+
+	st := teststore.New()
+	user := createTestUser(t, st, "user@example.org", "password")
+	srv := httptest.NewServer(newTestServer(t, st, testConfig()))
+	defer srv.Close()
+
+	testCases := []struct {
+		name         string
+		email        string
+		password     string
+		expectedCode int
+	}{
+		{
+			name:         "valid",
+			email:        "user@example.org",
+			password:     "password",
+			expectedCode: http.StatusFound,
+		},
+		{
+			name:         "wrong password",
+			email:        "user@example.org",
+			password:     "wrong",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "unknown email",
+			email:        "unknown@example.org",
+			password:     "password",
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := newTestClient(t, srv).postForm("/login", url.Values{"email": {tc.email}, "password": {tc.password}})
+			if resp.StatusCode != tc.expectedCode {
+				t.Fatalf("expected status %d, got %d", tc.expectedCode, resp.StatusCode)
+			}
+		})
+	}
+
+	c := newTestClient(t, srv)
+	c.logIn("user@example.org", "password")
+	if resp, _ := c.get(fmt.Sprintf("/users/%d/friends_requests", user.ID)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("page of logged in user: status %d", resp.StatusCode)
+	}
+}
+
+func TestServer_FriendRequestFlow(t *testing.T) {
+
+	st := teststore.New()
+	alice := createTestUser(t, st, "alice@example.org", "password")
+	bob := createTestUser(t, st, "bob@example.org", "password")
+	srv := httptest.NewServer(newTestServer(t, st, testConfig()))
+	defer srv.Close()
+
+	sendPath := fmt.Sprintf("/users/send_friend_request/%d", bob.ID)
+	acceptPath := fmt.Sprintf("/users/%d/accept_friend_request/%d", bob.ID, alice.ID)
+
+	if resp := newTestClient(t, srv).postForm(sendPath, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("request of anonymous user: status %d", resp.StatusCode)
+	}
+
+	aliceClient := newTestClient(t, srv)
+	aliceClient.logIn("alice@example.org", "password")
+
+	if resp := aliceClient.postForm(fmt.Sprintf("/users/send_friend_request/%d", alice.ID), nil); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("request to yourself: status %d", resp.StatusCode)
+	}
+	if resp := aliceClient.postForm(sendPath, nil); resp.StatusCode != http.StatusFound {
+		t.Fatalf("request: status %d", resp.StatusCode)
+	}
+	if resp := aliceClient.postForm(sendPath, nil); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("repeated request: status %d", resp.StatusCode)
+	}
+	if resp := aliceClient.postForm(acceptPath, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("accept by requester: status %d", resp.StatusCode)
+	}
+
+	requests, err := st.User().GetIncomingFriendsRequests(bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || requests[0].ID != alice.ID {
+		t.Fatalf("unexpected incoming requests %+v", requests)
+	}
+
+	bobClient := newTestClient(t, srv)
+	bobClient.logIn("bob@example.org", "password")
+	if resp := bobClient.postForm(acceptPath, nil); resp.StatusCode != http.StatusFound {
+		t.Fatalf("accept: status %d", resp.StatusCode)
+	}
+
+	for _, pair := range [][2]int{{alice.ID, bob.ID}, {bob.ID, alice.ID}} {
+		areFriends, err := st.User().AreFriends(pair[0], pair[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !areFriends {
+			t.Fatalf("users %d and %d are not friends", pair[0], pair[1])
+		}
+	}
+}
+
+func TestServer_GetUserIDOfSuspendedUser(t *testing.T) {
+
+	st := teststore.New()
+	user := createTestUser(t, st, "user@example.org", "password")
+	srv := httptest.NewServer(newTestServer(t, st, testConfig()))
+	defer srv.Close()
+
+	c := newTestClient(t, srv)
+	c.logIn("user@example.org", "password")
+	if resp, _ := c.get("/sessions"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("active user: status %d", resp.StatusCode)
+	}
+
+	now := time.Now()
+	if err := st.User().SetSuspended(user.ID, &now); err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := c.get("/sessions"); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("suspended user: status %d", resp.StatusCode)
+	}
+}
+
+func TestServer_HandleSendFriendsRequestToUnknownUser(t *testing.T) {
+
+	st := teststore.New()
+	user := createTestUser(t, st, "user@example.org", "password")
+	srv := httptest.NewServer(newTestServer(t, st, testConfig()))
+	defer srv.Close()
+
+	c := newTestClient(t, srv)
+	c.logIn("user@example.org", "password")
+
+	resp := c.postForm(fmt.Sprintf("/users/send_friend_request/%d", user.ID+100), nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	outgoing, err := st.User().GetOutgoingFriendsRequests(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outgoing) != 0 {
+		t.Fatalf("request to unknown user is stored: %v", outgoing)
+	}
+}
@@ -0,0 +1,101 @@
+package apiserver
+
+import (
+	"crypto/sha256"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/csrf"
+	"github.com/gorilla/handlers"
+)
+
+// csrfCookieName is the name of cookie holding csrf token of browser,
+// it is dropped on log in and log out so that every session gets new token
+const csrfCookieName = "csrf"
+
+// protectFromCSRF checks csrf token of requests with unsafe methods,
+// token is sent in form field added by csrfField template func
+// or in X-CSRF-Token header
+func (s *server) protectFromCSRF(next http.Handler) http.Handler {
+
+	key := sha256.Sum256([]byte("csrf:" + s.config.SessionKey))
+	protect := csrf.Protect(
+		key[:],
+		csrf.CookieName(csrfCookieName),
+		csrf.Path("/"),
+		csrf.HttpOnly(true),
+		csrf.Secure(s.config.SecureCookies),
+		csrf.SameSite(csrf.SameSiteLaxMode),
+		csrf.TrustedOrigins(originHosts(s.config.CORSAllowedOrigins)),
+		csrf.ErrorHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.error(w, r, http.StatusForbidden, csrf.FailureReason(r))
+		})),
+	)(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isJSONAPIRequest(r) || isBearerAPIRequest(r) {
+			r = csrf.UnsafeSkipCheck(r)
+		}
+		protect.ServeHTTP(w, r)
+	})
+}
+
+// isJSONAPIRequest tells whether request is json api call, such requests
+// can not be sent by html form of another site and cross-origin scripts
+// need CORS preflight for them, so they are not checked for csrf token
+func isJSONAPIRequest(r *http.Request) bool {
+
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	return err == nil && mediaType == "application/json"
+}
+
+// isBearerAPIRequest tells whether api request is authenticated with
+// token, browsers never add Authorization header on their own
+func isBearerAPIRequest(r *http.Request) bool {
+
+	_, ok := bearerToken(r)
+
+	return ok && strings.HasPrefix(r.URL.Path, "/api/")
+}
+
+// dropCSRFToken removes csrf cookie, new token is issued with the next page
+func dropCSRFToken(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: csrfCookieName, Path: "/", MaxAge: -1})
+}
+
+// allowCORS lets scripts of allowed origins call server with cookies of user,
+// nothing is allowed to other sites when allowlist is empty
+func (s *server) allowCORS(next http.Handler) http.Handler {
+
+	if len(s.config.CORSAllowedOrigins) == 0 {
+		return next
+	}
+
+	return handlers.CORS(
+		handlers.AllowedOrigins(s.config.CORSAllowedOrigins),
+		handlers.AllowedMethods([]string{"GET", "HEAD", "POST", "PUT", "DELETE"}),
+		handlers.AllowedHeaders([]string{"Content-Type", "X-CSRF-Token"}),
+		handlers.ExposedHeaders([]string{"Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"}),
+		handlers.AllowCredentials(),
+	)(next)
+}
+
+// originHosts returns hosts of origins for referer check of csrf protection
+func originHosts(origins []string) []string {
+
+	hosts := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		if u, err := url.Parse(origin); err == nil && u.Host != "" {
+			hosts = append(hosts, u.Host)
+		}
+	}
+
+	return hosts
+}
@@ -0,0 +1,245 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/gorilla/mux"
+)
+
+const numMessagesOnOnePage = 50
+
+// messageRequest is json body of request sending message
+type messageRequest struct {
+	Text string `json:"text"`
+}
+
+func (s *server) handleDialogs() http.HandlerFunc {
+
+	tmpl := parseTemplate("dialogs.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		dialogs, err := s.store.Dialog().GetDialogs(userID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.render(w, r, tmpl, model.Dialogs{
+			Dialogs:    dialogs,
+			CurrUserID: userID,
+		})
+	}
+}
+
+func (s *server) handleDialog() http.HandlerFunc {
+
+	tmpl := parseTemplate("dialog.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		peerID, err := strconv.Atoi(mux.Vars(r)["user_id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			message := &model.Message{
+				FromID: userID,
+				ToID:   peerID,
+				Text:   r.FormValue("text"),
+			}
+			if err := s.sendMessage(message); err != nil {
+				s.messageError(w, r, err)
+				return
+			}
+			http.Redirect(w, r, fmt.Sprintf("/dialogs/%d", peerID), http.StatusFound)
+			return
+		}
+
+		peer, err := s.store.User().Find(peerID)
+		if err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		beforeID, err := getBeforeID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		messages, err := s.readMessages(userID, peerID, beforeID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.render(w, r, tmpl, model.Conversation{
+			Peer:       peer,
+			Messages:   messages.Messages,
+			CurrUserID: userID,
+			NextPage:   postsPageURL(fmt.Sprintf("/dialogs/%d", peerID), messages.NextBefore),
+		})
+	}
+}
+
+func (s *server) handleAPIDialogs() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		dialogs, err := s.store.Dialog().GetDialogs(user.ID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, &model.Dialogs{Dialogs: dialogs})
+	}
+}
+
+func (s *server) handleAPIGetMessages() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		peerID, err := strconv.Atoi(mux.Vars(r)["user_id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		beforeID, err := getBeforeID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		messages, err := s.readMessages(user.ID, peerID, beforeID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, messages)
+	}
+}
+
+func (s *server) handleAPISendMessage() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		peerID, err := strconv.Atoi(mux.Vars(r)["user_id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &messageRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		message := &model.Message{
+			FromID: user.ID,
+			ToID:   peerID,
+			Text:   req.Text,
+		}
+
+		if err := s.sendMessage(message); err != nil {
+			s.messageError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, message)
+	}
+}
+
+// sendMessage checks that sender may write to recipient and saves message
+func (s *server) sendMessage(m *model.Message) error {
+
+	if m.FromID == m.ToID {
+		return errMessageToYourself
+	}
+
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := s.store.User().Find(m.ToID); err != nil {
+		return err
+	}
+
+	if s.config.MessagesFriendsOnly {
+		areFriends, err := s.store.User().AreFriends(m.FromID, m.ToID)
+		if err != nil {
+			return err
+		}
+		if !areFriends {
+			return errMessagesFriendsOnly
+		}
+	}
+
+	return s.store.Dialog().Send(m)
+}
+
+// readMessages returns page of dialog history and marks
+// messages received by user in the dialog as read
+func (s *server) readMessages(userID, peerID, beforeID int) (*model.MessagesPage, error) {
+
+	messages, err := s.store.Dialog().GetMessages(userID, peerID, beforeID, numMessagesOnOnePage)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.Dialog().MarkRead(userID, peerID); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// messageError responds with status code matching error of sending message
+func (s *server) messageError(w http.ResponseWriter, r *http.Request, err error) {
+
+	switch err {
+	case model.ErrEmptyMessage, model.ErrMessageTooLong:
+		s.error(w, r, http.StatusUnprocessableEntity, err)
+	case errMessageToYourself:
+		s.error(w, r, http.StatusBadRequest, err)
+	case errMessagesFriendsOnly:
+		s.error(w, r, http.StatusForbidden, err)
+	default:
+		s.storeError(w, r, err)
+	}
+}
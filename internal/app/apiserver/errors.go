@@ -3,6 +3,37 @@ package apiserver
 import "errors"
 
 var (
-	errInncorrectEmailOrPassword = errors.New("Incorrect email or password")
-	errNotAuthenticated          = errors.New("Not authenticated")
+	errInncorrectEmailOrPassword   = errors.New("Incorrect email or password")
+	errTooManyLoginAttempts        = errors.New("Too many failed log ins, try again later")
+	errTooManyRequests             = errors.New("Too many requests, try again later")
+	errNotAuthenticated            = errors.New("Not authenticated")
+	errFriendRequestToYourself     = errors.New("You cant send friends request to yourself")
+	errPostNotFound                = errors.New("Post not found")
+	errWrongBeforeFormat           = errors.New("wrong before format, must be number")
+	errMessageToYourself           = errors.New("You cant send message to yourself")
+	errMessagesFriendsOnly         = errors.New("Messages can be sent only to friends")
+	errStreamingUnsupported        = errors.New("Streaming is not supported")
+	errWrongLastEventIDFormat      = errors.New("wrong Last-Event-ID format, must be number")
+	errInvalidPasswordResetToken   = errors.New("Password reset link is invalid or expired")
+	errInvalidVerificationLink     = errors.New("Verification link is invalid or expired")
+	errEmailAlreadyVerified        = errors.New("Email is already verified")
+	errVerificationCooldown        = errors.New("Verification email was sent recently, try again later")
+	errEmailNotVerified            = errors.New("Verify your email to do it")
+	errTwoFactorUnavailable        = errors.New("Two-factor authentication is not configured")
+	errTwoFactorNotEnrolled        = errors.New("Start two-factor enrollment first")
+	errTwoFactorNotEnabled         = errors.New("Two-factor authentication is not enabled")
+	errInvalidTwoFactorCode        = errors.New("Invalid two-factor code")
+	errTwoFactorLoginExpired       = errors.New("Log in with email and password first")
+	errTooManyTwoFactorAttempts    = errors.New("Too many invalid codes, log in again")
+	errInvalidAPIToken             = errors.New("Invalid API token")
+	errAPITokenScope               = errors.New("API token has no scope for it")
+	errUnknownIdentityProvider     = errors.New("Unknown identity provider")
+	errIdentityProviderUnavailable = errors.New("Identity provider is not available, try again later")
+	errInvalidExternalLogIn        = errors.New("Sign in was interrupted or expired, try again")
+	errExternalLogInFailed         = errors.New("Sign in with identity provider failed")
+	errExternalEmailNotVerified    = errors.New("Email of external account is not verified")
+	errExternalEmailTaken          = errors.New("Account with this email exists, log in with password and verify email first")
+	errAccountSuspended            = errors.New("Account is suspended")
+	errRoleRequired                = errors.New("You are not allowed to do it")
+	errCannotManageUser            = errors.New("You can not manage this account")
 )
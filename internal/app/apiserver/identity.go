@@ -0,0 +1,273 @@
+package apiserver
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/identity"
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"github.com/gorilla/mux"
+)
+
+// externalLogInTTL is how long user may stay at identity provider
+const externalLogInTTL = 10 * time.Minute
+
+// session values kept while user signs in at identity provider
+const (
+	externalProviderKey = "external_provider"
+	externalStateKey    = "external_state"
+	externalNonceKey    = "external_nonce"
+	externalVerifierKey = "external_verifier"
+	externalAtKey       = "external_at"
+)
+
+// newIdentityProviders returns providers listed in config
+func newIdentityProviders(config *Config) []identity.Provider {
+
+	providers := make([]identity.Provider, 0, len(config.IdentityProviders))
+	for _, p := range config.IdentityProviders {
+		providers = append(providers, identity.NewOIDCProvider(identity.OIDCConfig{
+			Name:         p.Name,
+			DisplayName:  p.DisplayName,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			Scopes:       p.Scopes,
+			RedirectURL:  strings.TrimRight(config.BaseURL, "/") + "/auth/" + p.Name + "/callback",
+		}))
+	}
+
+	return providers
+}
+
+// handleExternalLogIn redirects user to identity provider
+func (s *server) handleExternalLogIn() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		provider, err := s.identityProvider(mux.Vars(r)["provider"])
+		if err != nil {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		}
+
+		session, err := s.sessionStore.Get(r, sessionName)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		values := make(map[string]string, 3)
+		for _, key := range []string{externalStateKey, externalNonceKey, externalVerifierKey} {
+			if values[key], err = identity.RandomString(); err != nil {
+				s.error(w, r, http.StatusInternalServerError, err)
+				return
+			}
+			session.Values[key] = values[key]
+		}
+		session.Values[externalProviderKey] = provider.Name()
+		session.Values[externalAtKey] = time.Now().Unix()
+
+		authURL, err := provider.AuthCodeURL(r.Context(), values[externalStateKey], values[externalNonceKey], values[externalVerifierKey])
+		if err != nil {
+			s.logger.Errorf("Failed to discover identity provider %s: %v", provider.Name(), err)
+			s.error(w, r, http.StatusBadGateway, errIdentityProviderUnavailable)
+			return
+		}
+
+		if err := session.Save(r, w); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		http.Redirect(w, r, authURL, http.StatusFound)
+	}
+}
+
+// handleExternalLogInCallback signs user in when identity provider
+// redirects him back with authorization code
+func (s *server) handleExternalLogInCallback() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		provider, err := s.identityProvider(mux.Vars(r)["provider"])
+		if err != nil {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		}
+
+		session, err := s.sessionStore.Get(r, sessionName)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		providerName, _ := session.Values[externalProviderKey].(string)
+		state, _ := session.Values[externalStateKey].(string)
+		nonce, _ := session.Values[externalNonceKey].(string)
+		verifier, _ := session.Values[externalVerifierKey].(string)
+		startedAt, _ := session.Values[externalAtKey].(int64)
+
+		// state is used once whatever the result is
+		for _, key := range []string{externalProviderKey, externalStateKey, externalNonceKey, externalVerifierKey, externalAtKey} {
+			delete(session.Values, key)
+		}
+		if err := session.Save(r, w); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		query := r.URL.Query()
+		if providerName != provider.Name() ||
+			state == "" ||
+			subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 ||
+			time.Since(time.Unix(startedAt, 0)) > externalLogInTTL {
+			s.error(w, r, http.StatusBadRequest, errInvalidExternalLogIn)
+			return
+		}
+
+		if reason := query.Get("error"); reason != "" {
+			s.logger.Infof("Identity provider %s refused sign in: %s %s", provider.Name(), reason, query.Get("error_description"))
+			s.error(w, r, http.StatusUnauthorized, errExternalLogInFailed)
+			return
+		}
+
+		id, err := provider.Exchange(r.Context(), query.Get("code"), nonce, verifier)
+		if err != nil {
+			s.logger.Infof("Sign in with identity provider %s failed: %v", provider.Name(), err)
+			s.error(w, r, http.StatusUnauthorized, errExternalLogInFailed)
+			return
+		}
+
+		user, err := s.userForIdentity(id)
+		if err != nil {
+			switch err {
+			case errExternalEmailNotVerified:
+				s.error(w, r, http.StatusForbidden, err)
+				return
+			case errExternalEmailTaken:
+				s.error(w, r, http.StatusConflict, err)
+				return
+			}
+			s.storeError(w, r, err)
+			return
+		}
+
+		if user.Suspended() {
+			s.error(w, r, http.StatusForbidden, errAccountSuspended)
+			return
+		}
+
+		twoFactorRequired, err := s.logIn(w, r, user)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		if twoFactorRequired {
+			http.Redirect(w, r, "/login/2fa", http.StatusFound)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/users/%d", user.ID), http.StatusFound)
+	}
+}
+
+// userForIdentity returns user linked to identity, identity unknown yet is
+// linked to user with the same email or to new account when email is
+// verified by identity provider
+func (s *server) userForIdentity(id *identity.Identity) (*model.User, error) {
+
+	linked, err := s.store.ExternalIdentity().Find(id.Provider, id.Subject)
+	if err == nil {
+		return s.store.User().Find(linked.UserID)
+	}
+	if err != store.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if !id.EmailVerified || !strings.Contains(id.Email, "@") {
+		return nil, errExternalEmailNotVerified
+	}
+
+	user, err := s.store.User().FindByEmail(id.Email)
+	if err == store.ErrRecordNotFound {
+		user, err = s.createExternalUser(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// whoever signed up with email nobody confirmed could
+	// keep access to account linked to it
+	if !user.EmailVerified() {
+		return nil, errExternalEmailTaken
+	}
+
+	if err := s.store.ExternalIdentity().Create(&model.ExternalIdentity{
+		UserID:   user.ID,
+		Provider: id.Provider,
+		Subject:  id.Subject,
+		Email:    id.Email,
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// createExternalUser creates account for identity, it gets random
+// password which user may change through password reset
+func (s *server) createExternalUser(id *identity.Identity) (*model.User, error) {
+
+	password, err := identity.RandomString()
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		Email:    id.Email,
+		Password: password,
+		Name:     id.Name,
+		Surname:  id.Surname,
+	}
+
+	if err := s.store.User().Create(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.User().VerifyEmail(user.ID, user.Email); err != nil {
+		return nil, err
+	}
+
+	return s.store.User().Find(user.ID)
+}
+
+func (s *server) identityProvider(name string) (identity.Provider, error) {
+
+	for _, p := range s.identityProviders {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+
+	return nil, errUnknownIdentityProvider
+}
+
+// identityProviderLinks returns sign in buttons of login page
+func (s *server) identityProviderLinks() []model.IdentityProviderLink {
+
+	links := make([]model.IdentityProviderLink, 0, len(s.identityProviders))
+	for _, p := range s.identityProviders {
+		links = append(links, model.IdentityProviderLink{
+			Name:        p.Name(),
+			DisplayName: p.DisplayName(),
+		})
+	}
+
+	return links
+}
@@ -0,0 +1,319 @@
+package apiserver
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"github.com/DalerBakhriev/social_network/internal/app/store/teststore"
+)
+
+const (
+	testClientID = "test_client"
+	testKeyID    = "test_key"
+)
+
+// fakeIssuer is OpenID Connect provider signing id tokens with RSA key,
+// codes are issued by tests instead of sign in page
+type fakeIssuer struct {
+	t   *testing.T
+	srv *httptest.Server
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]*fakeGrant
+}
+
+// fakeGrant is what authorization code is exchanged for
+type fakeGrant struct {
+	challenge string
+	claims    map[string]interface{}
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &fakeIssuer{t: t, key: key, codes: make(map[string]*fakeGrant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.handleDiscovery)
+	mux.HandleFunc("/jwks", issuer.handleJWKS)
+	mux.HandleFunc("/token", issuer.handleToken)
+	issuer.srv = httptest.NewServer(mux)
+	t.Cleanup(issuer.srv.Close)
+
+	return issuer
+}
+
+// authorize issues code as if user signed in, challenge is
+// PKCE code challenge token request must match
+func (i *fakeIssuer) authorize(challenge string, claims map[string]interface{}) string {
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	code := "code" + strconv.Itoa(len(i.codes))
+	i.codes[code] = &fakeGrant{challenge: challenge, claims: claims}
+
+	return code
+}
+
+func (i *fakeIssuer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                i.srv.URL,
+		"authorization_endpoint":                i.srv.URL + "/authorize",
+		"token_endpoint":                        i.srv.URL + "/token",
+		"jwks_uri":                              i.srv.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (i *fakeIssuer) handleJWKS(w http.ResponseWriter, r *http.Request) {
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": testKeyID,
+			"n":   base64.RawURLEncoding.EncodeToString(i.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(i.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (i *fakeIssuer) handleToken(w http.ResponseWriter, r *http.Request) {
+
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	i.mu.Lock()
+	grant, ok := i.codes[r.PostForm.Get("code")]
+	delete(i.codes, r.PostForm.Get("code"))
+	i.mu.Unlock()
+
+	if !ok || pkceChallenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access_token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     i.sign(grant.claims),
+	})
+}
+
+// sign returns RS256 id token with claims added to standard ones
+func (i *fakeIssuer) sign(claims map[string]interface{}) string {
+
+	payload := map[string]interface{}{
+		"iss": i.srv.URL,
+		"aud": testClientID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		payload[k] = v
+	}
+
+	encode := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			i.t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	signed := encode(map[string]string{"alg": "RS256", "kid": testKeyID, "typ": "JWT"}) + "." + encode(payload)
+	sum := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, sum[:])
+	if err != nil {
+		i.t.Fatal(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func pkceChallenge(verifier string) string {
+
+	sum := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// startExternalLogIn follows sign in button and returns
+// query of authorization request sent to issuer
+func (c *testClient) startExternalLogIn(provider string) url.Values {
+
+	c.t.Helper()
+
+	resp, _ := c.get("/auth/" + provider)
+	if resp.StatusCode != http.StatusFound {
+		c.t.Fatalf("sign in with %s: status %d", provider, resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	return location.Query()
+}
+
+func TestServer_ExternalLogIn(t *testing.T) {
+
+	issuer := newFakeIssuer(t)
+
+	testCases := []struct {
+		name string
+		// grant returns claims of id token, challenge
+		// and state of callback for authorization request
+		grant        func(query url.Values) (claims map[string]interface{}, challenge, state string)
+		email        string
+		expectedCode int
+	}{
+		{
+			name:         "links user with verified email",
+			email:        "user@example.org",
+			expectedCode: http.StatusFound,
+		},
+		{
+			name:         "creates account",
+			email:        "new@example.org",
+			expectedCode: http.StatusFound,
+		},
+		{
+			name:  "bad state",
+			email: "user@example.org",
+			grant: func(query url.Values) (map[string]interface{}, string, string) {
+				return nil, query.Get("code_challenge"), "forged"
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:  "nonce mismatch",
+			email: "user@example.org",
+			grant: func(query url.Values) (map[string]interface{}, string, string) {
+				return map[string]interface{}{"nonce": "another"}, query.Get("code_challenge"), query.Get("state")
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:  "unverified email",
+			email: "user@example.org",
+			grant: func(query url.Values) (map[string]interface{}, string, string) {
+				return map[string]interface{}{"email_verified": false}, query.Get("code_challenge"), query.Get("state")
+			},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:  "wrong pkce verifier",
+			email: "user@example.org",
+			grant: func(query url.Values) (map[string]interface{}, string, string) {
+				return nil, pkceChallenge("another"), query.Get("state")
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st := teststore.New()
+			existing := createTestUser(t, st, "user@example.org", "password")
+			config := testConfig()
+			config.IdentityProviders = []IdentityProviderConfig{{
+				Name:         "fake",
+				Issuer:       issuer.srv.URL,
+				ClientID:     testClientID,
+				ClientSecret: "test_secret",
+			}}
+			srv := httptest.NewServer(newTestServer(t, st, config))
+			defer srv.Close()
+
+			c := newTestClient(t, srv)
+			query := c.startExternalLogIn("fake")
+			if query.Get("code_challenge_method") != "S256" {
+				t.Fatalf("authorization request without PKCE: %v", query)
+			}
+
+			claims := map[string]interface{}{
+				"sub":            "subject",
+				"nonce":          query.Get("nonce"),
+				"email":          tc.email,
+				"email_verified": true,
+				"given_name":     "Ann",
+				"family_name":    "Smith",
+			}
+			challenge, state := query.Get("code_challenge"), query.Get("state")
+			if tc.grant != nil {
+				var changed map[string]interface{}
+				changed, challenge, state = tc.grant(query)
+				for k, v := range changed {
+					claims[k] = v
+				}
+			}
+			code := issuer.authorize(challenge, claims)
+
+			resp, _ := c.get("/auth/fake/callback?" + url.Values{"code": {code}, "state": {state}}.Encode())
+			if resp.StatusCode != tc.expectedCode {
+				t.Fatalf("expected status %d, got %d", tc.expectedCode, resp.StatusCode)
+			}
+
+			linked, err := st.ExternalIdentity().Find("fake", "subject")
+			if tc.expectedCode != http.StatusFound {
+				if err != store.ErrRecordNotFound {
+					t.Fatalf("identity is linked after failed sign in: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			user, err := st.User().FindByEmail(tc.email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if linked.UserID != user.ID || (tc.email == existing.Email) != (user.ID == existing.ID) {
+				t.Fatalf("identity is linked to user %d, user %d has its email", linked.UserID, user.ID)
+			}
+			if location := resp.Header.Get("Location"); location != fmt.Sprintf("/users/%d", user.ID) {
+				t.Fatalf("redirected to %s", location)
+			}
+			if resp, _ := c.get("/api/v1/me"); resp.StatusCode != http.StatusOK {
+				t.Fatalf("session after sign in: status %d", resp.StatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+func (c LoginThrottleConfig) lockoutDuration() time.Duration {
+	return time.Duration(c.LockoutDuration) * time.Second
+}
+
+// limit returns limit of log ins, thresholds
+// of failures differ for emails and ip addresses
+func (c LoginThrottleConfig) limit(freeAttempts, lockoutAttempts int) model.LoginLimit {
+	return model.LoginLimit{
+		FreeAttempts:    freeAttempts,
+		LockoutAttempts: lockoutAttempts,
+		MaxBackoff:      time.Duration(c.MaxBackoff) * time.Second,
+		LockoutDuration: c.lockoutDuration(),
+	}
+}
+
+// loginAttemptKeys are keys failures of log in are counted with
+type loginAttemptKeys struct {
+	email string
+	ip    string
+}
+
+func newLoginAttemptKeys(r *http.Request, email string) loginAttemptKeys {
+	return loginAttemptKeys{
+		email: "email:" + strings.ToLower(strings.TrimSpace(email)),
+		ip:    "ip:" + remoteIP(r),
+	}
+}
+
+// loginLimit is limit of log ins with key
+type loginLimit struct {
+	key   string
+	limit model.LoginLimit
+}
+
+// userLoginLimit limits two-factor codes of user, they are counted
+// across sessions so that new log ins do not give new attempts
+func (s *server) userLoginLimit(userID int) loginLimit {
+
+	c := s.config.LoginThrottle
+
+	return loginLimit{fmt.Sprintf("user:%d", userID), c.limit(c.FreeAttempts, c.LockoutAttempts)}
+}
+
+// authenticate checks email and password of log in, when too many
+// log ins failed it returns time to wait before the next one
+func (s *server) authenticate(r *http.Request, email, password string) (*model.User, time.Duration, error) {
+
+	keys := newLoginAttemptKeys(r, email)
+	c := s.config.LoginThrottle
+
+	attempts, wait, err := s.reserveLogIn(
+		loginLimit{keys.email, c.limit(c.FreeAttempts, c.LockoutAttempts)},
+		loginLimit{keys.ip, c.limit(c.IPFreeAttempts, c.IPLockoutAttempts)},
+	)
+	if err != nil {
+		return nil, wait, err
+	}
+
+	user, err := s.store.User().FindByEmail(email)
+	if err != nil && err != store.ErrRecordNotFound {
+		s.releaseLogIn(attempts...)
+		return nil, 0, err
+	}
+
+	// unknown emails take as long as wrong passwords
+	// so that timing does not tell which emails exist
+	if user == nil {
+		model.CompareDummyPassword(password)
+	}
+
+	if user == nil || !user.ComparePassword(password) {
+		s.auditLoginFailure(r, keys, attempts[0], attempts[1], user)
+		return nil, 0, errInncorrectEmailOrPassword
+	}
+
+	s.releaseLogIn(attempts[1])
+
+	if user.Suspended() {
+		s.releaseLogIn(attempts[0])
+		return nil, 0, errAccountSuspended
+	}
+
+	// password does not help when too many two-factor codes were wrong
+	wait, err = s.loginWait(s.userLoginLimit(user.ID))
+	if err != nil || wait > 0 {
+		s.releaseLogIn(attempts[0])
+		if err != nil {
+			return nil, 0, err
+		}
+		return nil, wait, errTooManyLoginAttempts
+	}
+
+	if err := s.store.LoginAttempt().Delete(keys.email); err != nil {
+		s.logger.Errorf("Failed to reset failed log ins of user %d: %v", user.ID, err)
+	}
+
+	if user.PasswordNeedsRehash() {
+		user.Password = password
+		if err := s.store.User().UpdatePassword(user); err != nil {
+			s.logger.Errorf("Failed to rehash password of user %d: %v", user.ID, err)
+		}
+		user.Sanitize()
+	}
+
+	return user, 0, nil
+}
+
+// reserveLogIn counts log in as failed with every key of limits before
+// it is checked, so that concurrent log ins can not pass limits together.
+// When any limit is reached reserved keys are released and
+// time log in has to wait is returned
+func (s *server) reserveLogIn(limits ...loginLimit) ([]*model.LoginAttempts, time.Duration, error) {
+
+	attempts := make([]*model.LoginAttempts, 0, len(limits))
+	for _, l := range limits {
+		a, err := s.store.LoginAttempt().Reserve(l.key, l.limit)
+		if err == nil {
+			attempts = append(attempts, a)
+			continue
+		}
+
+		s.releaseLogIn(attempts...)
+		if err == store.ErrLoginAttemptsExceeded {
+			return nil, time.Until(l.limit.BlockedUntil(a)), errTooManyLoginAttempts
+		}
+		return nil, 0, err
+	}
+
+	return attempts, 0, nil
+}
+
+// loginWait returns how long log in limited by l has to wait
+func (s *server) loginWait(l loginLimit) (time.Duration, error) {
+
+	a, err := s.store.LoginAttempt().Find(l.key)
+	if err == store.ErrRecordNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Until(l.limit.BlockedUntil(a)), nil
+}
+
+// releaseLogIn takes back reserved log in that did not fail
+func (s *server) releaseLogIn(attempts ...*model.LoginAttempts) {
+
+	for _, a := range attempts {
+		if err := s.store.LoginAttempt().Release(a); err != nil {
+			s.logger.Errorf("Failed to release log in with %s: %v", a.Key, err)
+		}
+	}
+}
+
+// auditLoginFailure records lockouts to audit log when they start
+func (s *server) auditLoginFailure(r *http.Request, keys loginAttemptKeys, email, ip *model.LoginAttempts, user *model.User) {
+
+	if email.Failures == s.config.LoginThrottle.LockoutAttempts {
+		event := &model.AuditEvent{
+			Type:    model.AuditLoginLockout,
+			IP:      remoteIP(r),
+			Details: fmt.Sprintf("%d failed log ins to %s", email.Failures, strings.TrimPrefix(keys.email, "email:")),
+		}
+		if user != nil {
+			event.UserID = user.ID
+		}
+		s.audit(event)
+	}
+
+	if ip.Failures == s.config.LoginThrottle.IPLockoutAttempts {
+		s.audit(&model.AuditEvent{
+			Type:    model.AuditLoginIPLockout,
+			IP:      remoteIP(r),
+			Details: fmt.Sprintf("%d failed log ins from %s", ip.Failures, remoteIP(r)),
+		})
+	}
+}
+
+// audit records event to audit log and to server log
+func (s *server) audit(event *model.AuditEvent) {
+
+	s.logger.Warnw("Audit event", "type", event.Type, "user_id", event.UserID, "ip", event.IP, "details", event.Details)
+
+	if err := s.store.Audit().Create(event); err != nil {
+		s.logger.Errorf("Failed to record audit event %s: %v", event.Type, err)
+	}
+}
+
+func (s *server) loginError(w http.ResponseWriter, r *http.Request, wait time.Duration, err error) {
+
+	switch err {
+	case errInncorrectEmailOrPassword:
+		s.error(w, r, http.StatusUnauthorized, err)
+	case errAccountSuspended:
+		s.error(w, r, http.StatusForbidden, err)
+	case errTooManyLoginAttempts:
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(wait)))
+		s.error(w, r, http.StatusTooManyRequests, err)
+	default:
+		s.error(w, r, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,124 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"github.com/DalerBakhriev/social_network/internal/app/store/teststore"
+)
+
+func TestServer_AuthenticateConcurrentLogIns(t *testing.T) {
+
+	st := teststore.New()
+	createTestUser(t, st, "user@example.org", "password")
+	config := testConfig()
+	config.LoginThrottle.FreeAttempts = 2
+	s := newTestServer(t, st, config)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results = make(map[error]int)
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := httptest.NewRequest(http.MethodPost, "/login", nil)
+			_, _, err := s.authenticate(r, "user@example.org", "wrong")
+			mu.Lock()
+			results[err]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// free attempts and the first one waiting for backoff are checked
+	if n := results[errInncorrectEmailOrPassword]; n != config.LoginThrottle.FreeAttempts+1 {
+		t.Fatalf("expected %d checked passwords, got %d: %v", config.LoginThrottle.FreeAttempts+1, n, results)
+	}
+	if n := results[errTooManyLoginAttempts]; n != 20-config.LoginThrottle.FreeAttempts-1 {
+		t.Fatalf("expected the rest log ins to wait: %v", results)
+	}
+}
+
+func TestServer_AuthenticateUnknownEmail(t *testing.T) {
+
+	config := testConfig()
+	config.LoginThrottle.FreeAttempts = 0
+	s := newTestServer(t, teststore.New(), config)
+	r := httptest.NewRequest(http.MethodPost, "/login", nil)
+
+	if _, _, err := s.authenticate(r, "nobody@example.org", "password"); err != errInncorrectEmailOrPassword {
+		t.Fatalf("expected error %v, got %v", errInncorrectEmailOrPassword, err)
+	}
+
+	_, wait, err := s.authenticate(r, "nobody@example.org", "password")
+	if err != errTooManyLoginAttempts || wait <= 0 {
+		t.Fatalf("expected log in to wait, got %v after %v", err, wait)
+	}
+}
+
+func TestServer_AuthenticateReleasesLogIn(t *testing.T) {
+
+	st := teststore.New()
+	createTestUser(t, st, "user@example.org", "password")
+	s := newTestServer(t, st, testConfig())
+	r := httptest.NewRequest(http.MethodPost, "/login", nil)
+	keys := newLoginAttemptKeys(r, "user@example.org")
+
+	if _, _, err := s.authenticate(r, "user@example.org", "wrong"); err != errInncorrectEmailOrPassword {
+		t.Fatalf("expected error %v, got %v", errInncorrectEmailOrPassword, err)
+	}
+	if _, _, err := s.authenticate(r, "user@example.org", "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := st.LoginAttempt().Find(keys.email); err != store.ErrRecordNotFound {
+		t.Fatalf("failures of email are kept after log in: %v", err)
+	}
+	a, err := st.LoginAttempt().Find(keys.ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Failures != 1 {
+		t.Fatalf("expected 1 failure from ip address, got %d", a.Failures)
+	}
+}
+
+func TestServer_AuthenticateKeepsFailureWindow(t *testing.T) {
+
+	st := teststore.New()
+	createTestUser(t, st, "user@example.org", "password")
+	createTestUser(t, st, "another@example.org", "password")
+	s := newTestServer(t, st, testConfig())
+	r := httptest.NewRequest(http.MethodPost, "/login", nil)
+	keys := newLoginAttemptKeys(r, "user@example.org")
+
+	if _, _, err := s.authenticate(r, "user@example.org", "wrong"); err != errInncorrectEmailOrPassword {
+		t.Fatalf("expected error %v, got %v", errInncorrectEmailOrPassword, err)
+	}
+	failed, err := st.LoginAttempt().Find(keys.ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// users behind the same address keep logging in
+	for i := 0; i < 3; i++ {
+		if _, _, err := s.authenticate(r, "another@example.org", "password"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, err := st.LoginAttempt().Find(keys.ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Failures != 1 || !a.LastFailureAt.Equal(failed.LastFailureAt) {
+		t.Fatalf("successful log ins changed failures from ip address: %d at %v, was %d at %v",
+			a.Failures, a.LastFailureAt, failed.Failures, failed.LastFailureAt)
+	}
+}
@@ -2,6 +2,7 @@ package apiserver
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"time"
 
@@ -41,25 +42,65 @@ func (s *server) logRequest(next http.Handler) http.Handler {
 func (s *server) authenticateUser(next http.Handler) http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r); ok {
+			u, apiToken, err := s.authenticateAPIToken(token)
+			if err != nil {
+				switch err {
+				case errInvalidAPIToken:
+					s.error(w, r, http.StatusUnauthorized, err)
+				case errAccountSuspended:
+					s.error(w, r, http.StatusForbidden, err)
+				default:
+					s.error(w, r, http.StatusInternalServerError, err)
+				}
+				return
+			}
+
+			if !apiToken.HasScope(requiredScope(r)) {
+				s.error(w, r, http.StatusForbidden, errAPITokenScope)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ctxKeyUser, u)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, ctxKeyAPIToken, apiToken)))
+			return
+		}
+
 		session, err := s.sessionStore.Get(r, sessionName)
 		if err != nil {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
 
-		id, ok := session.Values["user_id"]
+		id, ok := session.Values["user_id"].(int)
 		if !ok {
 			s.error(w, r, http.StatusUnauthorized, errNotAuthenticated)
 			return
 		}
 
-		u, err := s.store.User().Find(id.(int))
+		u, err := s.store.User().Find(id)
 		if err != nil {
 			s.error(w, r, http.StatusUnauthorized, errNotAuthenticated)
 			return
 		}
 
+		if u.Suspended() {
+			s.error(w, r, http.StatusForbidden, errAccountSuspended)
+			return
+		}
+
 		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKeyUser, u)))
 	},
 	)
 }
+
+// remoteIP returns ip address request came from
+func remoteIP(r *http.Request) string {
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,117 @@
+package apiserver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/DalerBakhriev/social_network/internal/app/store/migrations"
+)
+
+const migrationsDir = "./internal/app/store/migrations"
+
+var errUnknownMigrateCommand = errors.New("Usage: migrate up | down [steps] | status | create <name>")
+
+// Migrate runs migrate subcommand with given arguments in the main
+// database and databases of shards and writes its report to out
+func Migrate(out io.Writer, config *Config, args []string) error {
+
+	if len(args) == 0 {
+		return errUnknownMigrateCommand
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			return errUnknownMigrateCommand
+		}
+		filePath, err := migrations.Create(migrationsDir, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Created %s\n", filePath)
+		return nil
+	}
+
+	urls := []string{getDataBaseURL()}
+	names := map[string]string{urls[0]: "main"}
+	for _, shard := range config.Sharding.Shards {
+		url := shardDataBaseURL(shard)
+		if _, ok := names[url]; !ok {
+			urls = append(urls, url)
+			names[url] = shard.Name
+		}
+	}
+
+	for i, url := range urls {
+		if len(urls) > 1 {
+			fmt.Fprintf(out, "Database %s:\n", names[url])
+		}
+		// shards kept in the main database are migrated with it
+		if err := migrate(out, url, i > 0, args); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// migrate runs migrate subcommand in database with given url,
+// shard databases get only migrations of tables partitioned across shards
+func migrate(out io.Writer, url string, shard bool, args []string) error {
+
+	db, err := newDB(url)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator := migrations.NewMigrator(db)
+	if shard {
+		migrator = migrations.NewShardMigrator(db)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Fprintf(out, "Applied %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errUnknownMigrateCommand
+			}
+		}
+		rolledBack, err := migrator.Down(steps)
+		for _, m := range rolledBack {
+			fmt.Fprintf(out, "Rolled back %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			state, appliedAt := "pending", ""
+			if s.Applied {
+				state, appliedAt = "applied", s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				state = "modified"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		return w.Flush()
+	}
+
+	return errUnknownMigrateCommand
+}
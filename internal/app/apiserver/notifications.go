@@ -0,0 +1,173 @@
+package apiserver
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/gorilla/mux"
+)
+
+const numNotificationsOnOnePage = 20
+
+func (s *server) handleNotifications() http.HandlerFunc {
+
+	tmpl := parseTemplate("notifications.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		beforeID, err := getBeforeID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		page, err := s.store.Notification().GetByUser(userID, beforeID, numNotificationsOnOnePage)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.render(w, r, tmpl, model.Notifications{
+			Notifications: page.Notifications,
+			CurrUserID:    userID,
+			NextPage:      postsPageURL("/notifications", page.NextBefore),
+		})
+	}
+}
+
+func (s *server) handleReadNotification() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		notificationID, err := getNotificationID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.store.Notification().MarkRead(notificationID, userID); err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, "/notifications", http.StatusFound)
+	}
+}
+
+func (s *server) handleReadAllNotifications() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		if err := s.store.Notification().MarkAllRead(userID); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		http.Redirect(w, r, "/notifications", http.StatusFound)
+	}
+}
+
+func (s *server) handleAPINotifications() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		beforeID, err := getBeforeID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		page, err := s.store.Notification().GetByUser(user.ID, beforeID, numNotificationsOnOnePage)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, page)
+	}
+}
+
+func (s *server) handleAPIReadNotification() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		notificationID, err := getNotificationID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.store.Notification().MarkRead(notificationID, user.ID); err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+func (s *server) handleAPIReadAllNotifications() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		if err := s.store.Notification().MarkAllRead(user.ID); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// notifyUsers stores notification about action of actor
+// for every recipient, failure is only logged since
+// the action is already saved
+func (s *server) notifyUsers(notificationType string, actorID int, recipients ...int) {
+
+	for _, id := range recipients {
+		if err := s.store.Notification().Create(&model.Notification{
+			UserID:  id,
+			Type:    notificationType,
+			ActorID: actorID,
+		}); err != nil {
+			s.logger.Errorf("Failed to store %s notification of user %d: %v", notificationType, id, err)
+		}
+	}
+}
+
+func getNotificationID(r *http.Request) (int, error) {
+	return strconv.Atoi(mux.Vars(r)["notification_id"])
+}
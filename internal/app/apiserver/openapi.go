@@ -0,0 +1,498 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/gorilla/mux"
+)
+
+var pathVarPatternRe = regexp.MustCompile(`\{([^:}]+):[^}]+\}`)
+
+type openAPISpec struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       *openAPIInfo                            `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components *openAPIComponents                      `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*openAPISchema         `json:"schemas"`
+	SecuritySchemes map[string]*openAPISecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type openAPISecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type openAPIOperation struct {
+	Summary     string                      `json:"summary"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIBody                `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required,omitempty"`
+	Schema   *openAPISchema `json:"schema"`
+}
+
+type openAPIBody struct {
+	Required bool                         `json:"required,omitempty"`
+	Content  map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref        string                    `json:"$ref,omitempty"`
+	Type       string                    `json:"type,omitempty"`
+	Format     string                    `json:"format,omitempty"`
+	Items      *openAPISchema            `json:"items,omitempty"`
+	Properties map[string]*openAPISchema `json:"properties,omitempty"`
+}
+
+// errorResponse is json body of every error response
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+const (
+	tagPages = "pages"
+	tagAPI   = "api"
+)
+
+// newOpenAPISpec describes every route registered in configureRouter,
+// schemas of request and response bodies are built from go types
+func newOpenAPISpec() *openAPISpec {
+
+	spec := &openAPISpec{
+		OpenAPI: "3.0.3",
+		Info: &openAPIInfo{
+			Title:   "Social network",
+			Version: "1.0.0",
+		},
+		Paths: make(map[string]map[string]*openAPIOperation),
+		Components: &openAPIComponents{
+			Schemas: make(map[string]*openAPISchema),
+			SecuritySchemes: map[string]*openAPISecurityScheme{
+				"apiToken": {
+					Type:   "http",
+					Scheme: "bearer",
+					Description: "Personal API token, it authenticates api routes instead of session cookie. " +
+						"Scope read allows GET requests, friends allows changing friend requests and friends, " +
+						"messages allows sending messages, other changes need session",
+				},
+			},
+		},
+	}
+
+	spec.addSchema("User", model.User{})
+	spec.addSchema("UsersPage", model.UsersPage{})
+	spec.addSchema("UsersList", usersResponse{})
+	spec.addSchema("FriendRequests", model.FriendRequests{})
+	spec.addSchema("UserRequest", userRequest{})
+	spec.addSchema("LogInRequest", logInRequest{})
+	spec.addSchema("Post", model.Post{})
+	spec.addSchema("PostsPage", model.PostsPage{})
+	spec.addSchema("PostRequest", postRequest{})
+	spec.addSchema("Activities", model.Activities{})
+	spec.addSchema("Message", model.Message{})
+	spec.addSchema("MessagesPage", model.MessagesPage{})
+	spec.addSchema("MessageRequest", messageRequest{})
+	spec.addSchema("Dialogs", model.Dialogs{})
+	spec.addSchema("Event", model.Event{})
+	spec.addSchema("NotificationsPage", model.NotificationsPage{})
+	spec.addSchema("PasswordForgotRequest", passwordForgotRequest{})
+	spec.addSchema("PasswordResetRequest", passwordResetRequest{})
+	spec.addSchema("VerifyEmailRequest", verifyEmailRequest{})
+	spec.addSchema("Sessions", sessionsResponse{})
+	spec.addSchema("TwoFactorCodeRequest", twoFactorCodeRequest{})
+	spec.addSchema("TwoFactorStatus", twoFactorStatusResponse{})
+	spec.addSchema("TwoFactorEnrollment", twoFactorEnrollResponse{})
+	spec.addSchema("RecoveryCodes", twoFactorRecoveryCodesResponse{})
+	spec.addSchema("TwoFactorRequired", twoFactorRequiredResponse{})
+	spec.addSchema("APITokenRequest", apiTokenRequest{})
+	spec.addSchema("APITokens", apiTokensResponse{})
+	spec.addSchema("APITokenCreated", apiTokenCreatedResponse{})
+	spec.addSchema("Account", model.Account{})
+	spec.addSchema("AccountsPage", model.AccountsPage{})
+	spec.addSchema("RoleRequest", roleRequest{})
+	spec.addSchema("NoticeRequest", noticeRequest{})
+	spec.addSchema("Error", errorResponse{})
+
+	userID := pathParam("user_id", "integer")
+	friendID := pathParam("friend_id", "integer")
+	postID := pathParam("post_id", "integer")
+	before := queryParam("before", "integer")
+	emailPrefix := queryParam("q", "string")
+	notificationID := pathParam("notification_id", "integer")
+	tokenID := pathParam("token_id", "integer")
+	sessionID := pathParam("session_id", "string")
+	provider := pathParam("provider", "string")
+	token := pathParam("token", "string")
+	directoryParams := []*openAPIParameter{
+		queryParam("city", "string"),
+		queryParam("sex", "string"),
+		queryParam("min_age", "integer"),
+		queryParam("max_age", "integer"),
+		queryParam("interests", "string"),
+		queryParam("cursor", "string"),
+	}
+	searchParams := []*openAPIParameter{
+		queryParam("first_name", "string"),
+		queryParam("last_name", "string"),
+		queryParam("cursor", "string"),
+	}
+
+	spec.page("GET", "/signup", "Sign up form")
+	spec.redirect("POST", "/signup", "Create user from sign up form", formBody())
+	spec.page("GET", "/login", "Log in form")
+	spec.redirect("POST", "/login", "Log in with email and password", formBody())
+	spec.redirect("GET", "/auth/{provider}", "Sign in with identity provider, redirects to it", nil, provider)
+	spec.redirect("GET", "/auth/{provider}/callback", "Finish sign in when identity provider redirects back", nil, provider, queryParam("state", "string"), queryParam("code", "string"))
+	spec.page("GET", "/login/2fa", "Form asking for two-factor code after password")
+	spec.redirect("POST", "/login/2fa", "Finish log in with two-factor or recovery code", formBody())
+	spec.redirect("POST", "/logout", "Log out", nil)
+	spec.page("GET", "/password/forgot", "Form asking for email to send password reset link to")
+	spec.page("POST", "/password/forgot", "Send password reset link to email from form")
+	spec.Paths["/password/forgot"]["post"].RequestBody = formBody()
+	spec.page("GET", "/password/reset/{token}", "New password form", token)
+	spec.redirect("POST", "/password/reset/{token}", "Set new password and log out everywhere", formBody(), token)
+	spec.page("GET", "/verify/{token}", "Verify email with link sent to it", token)
+	spec.redirect("POST", "/verify/resend", "Send email verification link again", nil)
+	spec.page("GET", "/user_edit", "Profile edit form")
+	spec.redirect("POST", "/user_edit", "Update profile of current user", formBody())
+	spec.page("GET", "/", "Users directory", directoryParams...)
+	spec.page("GET", "/users/search", "Search users by name and surname prefixes", searchParams...)
+	spec.page("GET", "/users/{user_id}", "User profile with wall", userID, before)
+	spec.page("GET", "/users/{user_id}/friends", "Friends of user", userID)
+	spec.page("GET", "/users/{user_id}/friends_requests", "Friend requests of user", userID)
+	spec.redirect("POST", "/users/send_friend_request/{friend_id}", "Send friend request", nil, friendID)
+	spec.redirect("POST", "/users/{user_id}/accept_friend_request/{friend_id}", "Accept friend request", nil, userID, friendID)
+	spec.redirect("POST", "/users/{user_id}/decline_friend_request/{friend_id}", "Decline friend request", nil, userID, friendID)
+	spec.redirect("POST", "/users/{user_id}/cancel_friend_request/{friend_id}", "Cancel sent friend request", nil, userID, friendID)
+	spec.redirect("POST", "/users/{user_id}/remove_friend/{friend_id}", "Remove user from friends", nil, userID, friendID)
+	spec.page("GET", "/feed", "Posts of friends of current user", before)
+	spec.page("GET", "/activity", "Recent activity of friends of current user")
+	spec.add("GET", "/ws", &openAPIOperation{
+		Summary: "WebSocket streaming events of current user as json messages of Event schema",
+		Tags:    []string{tagAPI},
+		Responses: map[string]*openAPIResponse{
+			"101":     {Description: "switching to websocket protocol"},
+			"default": errorResponseSpec(),
+		},
+	})
+	spec.add("GET", "/events", &openAPIOperation{
+		Summary: "Server-sent events of current user, data of every event is json of Event schema",
+		Tags:    []string{tagAPI},
+		Parameters: []*openAPIParameter{
+			{Name: "Last-Event-ID", In: "header", Schema: &openAPISchema{Type: "integer"}},
+			queryParam("last_event_id", "integer"),
+		},
+		Responses: map[string]*openAPIResponse{
+			"200": {
+				Description: "stream of events",
+				Content:     map[string]*openAPIMediaType{"text/event-stream": {Schema: &openAPISchema{Type: "string"}}},
+			},
+			"default": errorResponseSpec(),
+		},
+	})
+	spec.page("GET", "/notifications", "Notifications of current user", before)
+	spec.redirect("POST", "/notifications/{notification_id}/read", "Mark notification as read", nil, notificationID)
+	spec.redirect("POST", "/notifications/read_all", "Mark all notifications as read", nil)
+	spec.page("GET", "/sessions", "Active sessions of current user")
+	spec.redirect("POST", "/sessions/{session_id}/revoke", "Log out session", nil, sessionID)
+	spec.redirect("POST", "/sessions/revoke_all", "Log out everywhere", nil)
+	spec.page("GET", "/api_tokens", "API tokens of current user")
+	spec.page("POST", "/api_tokens", "Create API token and show it once")
+	spec.Paths["/api_tokens"]["post"].RequestBody = formBody()
+	spec.redirect("POST", "/api_tokens/{token_id}/revoke", "Revoke API token", nil, tokenID)
+	spec.page("GET", "/two_factor", "Two-factor authentication settings of current user")
+	spec.page("POST", "/two_factor/enroll", "Generate secret and show QR code to scan")
+	spec.page("POST", "/two_factor/confirm", "Enable two-factor authentication with code and show recovery codes")
+	spec.Paths["/two_factor/confirm"]["post"].RequestBody = formBody()
+	spec.redirect("POST", "/two_factor/disable", "Disable two-factor authentication with code", formBody())
+	spec.page("GET", "/dialogs", "Dialogs of current user")
+	spec.page("GET", "/dialogs/{user_id}", "Messages of dialog with user", userID, before)
+	spec.page("GET", "/admin/users", "Accounts from the latest signed up, moderators only", emailPrefix, before)
+	spec.redirect("POST", "/admin/users/{user_id}/suspend", "Suspend user and log the user out everywhere, moderators only", nil, userID)
+	spec.redirect("POST", "/admin/users/{user_id}/unsuspend", "Lift suspension of user, moderators only", nil, userID)
+	spec.redirect("POST", "/admin/users/{user_id}/notice", "Send notice to user, moderators only", formBody(), userID)
+	spec.redirect("POST", "/admin/users/{user_id}/role", "Change role of user, admins only", formBody(), userID)
+	spec.redirect("POST", "/admin/users/{user_id}/reset_password", "Reset password of user and email link to set new one, admins only", nil, userID)
+	spec.redirect("POST", "/admin/users/{user_id}/delete", "Delete user with everything the user created, admins only", nil, userID)
+	spec.redirect("POST", "/dialogs/{user_id}", "Send message to user", formBody(), userID)
+	spec.redirect("POST", "/posts", "Create post on wall of current user", formBody())
+	spec.page("GET", "/posts/{post_id}/edit", "Post edit form", postID)
+	spec.redirect("POST", "/posts/{post_id}/edit", "Update post", formBody(), postID)
+	spec.redirect("POST", "/posts/{post_id}/delete", "Delete post", nil, postID)
+
+	spec.api("GET", "/api/openapi.json", "This specification", nil, http.StatusOK, &openAPISchema{Type: "object"})
+	spec.api("POST", "/api/v1/signup", "Create user", jsonBody("UserRequest"), http.StatusCreated, ref("User"))
+	spec.api("POST", "/api/v1/login", "Log in and get session cookie", jsonBody("LogInRequest"), http.StatusOK, ref("User"))
+	spec.Paths["/api/v1/login"]["post"].Responses["202"] = &openAPIResponse{
+		Description: "two-factor code is required, send it to /api/v1/login/2fa",
+		Content:     map[string]*openAPIMediaType{"application/json": {Schema: ref("TwoFactorRequired")}},
+	}
+	spec.Paths["/api/v1/login"]["post"].Responses["429"] = &openAPIResponse{
+		Description: "too many failed log ins, retry after seconds of Retry-After header",
+		Content:     map[string]*openAPIMediaType{"application/json": {Schema: ref("Error")}},
+	}
+	spec.api("POST", "/api/v1/login/2fa", "Finish log in with two-factor or recovery code", jsonBody("TwoFactorCodeRequest"), http.StatusOK, ref("User"))
+	spec.api("POST", "/api/v1/password/forgot", "Send password reset link to email", jsonBody("PasswordForgotRequest"), http.StatusAccepted, nil)
+	spec.api("POST", "/api/v1/password/reset", "Set new password with token from link and log out everywhere", jsonBody("PasswordResetRequest"), http.StatusNoContent, nil)
+	spec.api("POST", "/api/v1/verify", "Verify email with token from link sent to it", jsonBody("VerifyEmailRequest"), http.StatusNoContent, nil)
+	spec.api("POST", "/api/v1/verify/resend", "Send email verification link again", nil, http.StatusAccepted, nil)
+	spec.api("POST", "/api/v1/logout", "Log out", nil, http.StatusNoContent, nil)
+	spec.api("GET", "/api/v1/users", "Users directory page", nil, http.StatusOK, ref("UsersPage"), directoryParams...)
+	spec.api("GET", "/api/v1/users/search", "Search users by name and surname prefixes", nil, http.StatusOK, ref("UsersPage"), searchParams...)
+	spec.api("GET", "/api/v1/users/{user_id}", "User profile", nil, http.StatusOK, ref("User"), userID)
+	spec.api("GET", "/api/v1/users/{user_id}/friends", "Friends of user", nil, http.StatusOK, ref("UsersList"), userID)
+	spec.api("GET", "/api/v1/users/{user_id}/posts", "Posts on wall of user", nil, http.StatusOK, ref("PostsPage"), userID, before)
+	spec.api("GET", "/api/v1/me", "Profile of current user", nil, http.StatusOK, ref("User"))
+	spec.api("PUT", "/api/v1/me", "Update profile of current user", jsonBody("UserRequest"), http.StatusOK, ref("User"))
+	spec.api("GET", "/api/v1/friend_requests", "Incoming and outgoing friend requests of current user", nil, http.StatusOK, ref("FriendRequests"))
+	spec.api("POST", "/api/v1/friend_requests/{friend_id}", "Send friend request", nil, http.StatusCreated, nil, friendID)
+	spec.api("POST", "/api/v1/friend_requests/{friend_id}/accept", "Accept friend request", nil, http.StatusNoContent, nil, friendID)
+	spec.api("POST", "/api/v1/friend_requests/{friend_id}/decline", "Decline friend request", nil, http.StatusNoContent, nil, friendID)
+	spec.api("POST", "/api/v1/friend_requests/{friend_id}/cancel", "Cancel sent friend request", nil, http.StatusNoContent, nil, friendID)
+	spec.api("DELETE", "/api/v1/friends/{friend_id}", "Remove user from friends", nil, http.StatusNoContent, nil, friendID)
+	spec.api("POST", "/api/v1/posts", "Create post on wall of current user", jsonBody("PostRequest"), http.StatusCreated, ref("Post"))
+	spec.api("PUT", "/api/v1/posts/{post_id}", "Update post", jsonBody("PostRequest"), http.StatusOK, ref("Post"), postID)
+	spec.api("DELETE", "/api/v1/posts/{post_id}", "Delete post", nil, http.StatusNoContent, nil, postID)
+	spec.api("GET", "/api/v1/feed", "Posts of friends of current user", nil, http.StatusOK, ref("PostsPage"), before)
+	spec.api("GET", "/api/v1/activity", "Recent activity of friends of current user", nil, http.StatusOK, ref("Activities"))
+	spec.api("GET", "/api/v1/notifications", "Notifications of current user with number of unread ones", nil, http.StatusOK, ref("NotificationsPage"), before)
+	spec.api("POST", "/api/v1/notifications/{notification_id}/read", "Mark notification as read", nil, http.StatusNoContent, nil, notificationID)
+	spec.api("POST", "/api/v1/notifications/read_all", "Mark all notifications as read", nil, http.StatusNoContent, nil)
+	spec.api("GET", "/api/v1/sessions", "Active sessions of current user", nil, http.StatusOK, ref("Sessions"))
+	spec.api("DELETE", "/api/v1/sessions", "Log out everywhere", nil, http.StatusNoContent, nil)
+	spec.api("DELETE", "/api/v1/sessions/{session_id}", "Log out session", nil, http.StatusNoContent, nil, sessionID)
+	spec.api("GET", "/api/v1/api_tokens", "API tokens of current user", nil, http.StatusOK, ref("APITokens"))
+	spec.api("POST", "/api/v1/api_tokens", "Create API token, it is returned only once", jsonBody("APITokenRequest"), http.StatusCreated, ref("APITokenCreated"))
+	spec.api("DELETE", "/api/v1/api_tokens/{token_id}", "Revoke API token", nil, http.StatusNoContent, nil, tokenID)
+	spec.api("GET", "/api/v1/two_factor", "Two-factor authentication status of current user", nil, http.StatusOK, ref("TwoFactorStatus"))
+	spec.api("POST", "/api/v1/two_factor/enroll", "Generate secret with provisioning uri and QR code", nil, http.StatusCreated, ref("TwoFactorEnrollment"))
+	spec.api("POST", "/api/v1/two_factor/confirm", "Enable two-factor authentication with code and get recovery codes", jsonBody("TwoFactorCodeRequest"), http.StatusOK, ref("RecoveryCodes"))
+	spec.api("POST", "/api/v1/two_factor/disable", "Disable two-factor authentication with code", jsonBody("TwoFactorCodeRequest"), http.StatusNoContent, nil)
+	spec.api("GET", "/api/v1/dialogs", "Dialogs of current user", nil, http.StatusOK, ref("Dialogs"))
+	spec.api("GET", "/api/v1/dialogs/{user_id}", "Messages of dialog with user", nil, http.StatusOK, ref("MessagesPage"), userID, before)
+	spec.api("POST", "/api/v1/dialogs/{user_id}", "Send message to user", jsonBody("MessageRequest"), http.StatusCreated, ref("Message"), userID)
+	spec.api("GET", "/api/v1/admin/users", "Accounts from the latest signed up, moderators only", nil, http.StatusOK, ref("AccountsPage"), emailPrefix, before)
+	spec.api("POST", "/api/v1/admin/users/{user_id}/suspend", "Suspend user and log the user out everywhere, moderators only", nil, http.StatusOK, ref("Account"), userID)
+	spec.api("POST", "/api/v1/admin/users/{user_id}/unsuspend", "Lift suspension of user, moderators only", nil, http.StatusOK, ref("Account"), userID)
+	spec.api("POST", "/api/v1/admin/users/{user_id}/notice", "Send notice to user, moderators only", jsonBody("NoticeRequest"), http.StatusOK, ref("Account"), userID)
+	spec.api("PUT", "/api/v1/admin/users/{user_id}/role", "Change role of user, admins only", jsonBody("RoleRequest"), http.StatusOK, ref("Account"), userID)
+	spec.api("POST", "/api/v1/admin/users/{user_id}/reset_password", "Reset password of user and email link to set new one, admins only", nil, http.StatusOK, ref("Account"), userID)
+	spec.api("DELETE", "/api/v1/admin/users/{user_id}", "Delete user with everything the user created, admins only", nil, http.StatusNoContent, nil, userID)
+
+	return spec
+}
+
+func (s *server) handleOpenAPISpec() http.HandlerFunc {
+
+	spec := newOpenAPISpec()
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.respond(w, r, http.StatusOK, spec)
+	}
+}
+
+// undocumentedRoutes returns routes registered in router
+// which are missing in OpenAPI specification
+func (s *server) undocumentedRoutes() []string {
+
+	spec := newOpenAPISpec()
+	missing := make([]string, 0)
+
+	s.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		path := pathVarPatternRe.ReplaceAllString(tmpl, "{$1}")
+		for _, method := range methods {
+			if _, ok := spec.Paths[path][strings.ToLower(method)]; !ok {
+				missing = append(missing, fmt.Sprintf("%s %s", method, path))
+			}
+		}
+
+		return nil
+	})
+
+	sort.Strings(missing)
+
+	return missing
+}
+
+func (spec *openAPISpec) add(method, path string, op *openAPIOperation) {
+
+	if _, ok := spec.Paths[path]; !ok {
+		spec.Paths[path] = make(map[string]*openAPIOperation)
+	}
+
+	spec.Paths[path][strings.ToLower(method)] = op
+}
+
+// page adds route rendering html page
+func (spec *openAPISpec) page(method, path, summary string, params ...*openAPIParameter) {
+
+	spec.add(method, path, &openAPIOperation{
+		Summary:    summary,
+		Tags:       []string{tagPages},
+		Parameters: params,
+		Responses: map[string]*openAPIResponse{
+			"200": {
+				Description: "html page",
+				Content:     map[string]*openAPIMediaType{"text/html": {Schema: &openAPISchema{Type: "string"}}},
+			},
+			"default": errorResponseSpec(),
+		},
+	})
+}
+
+// redirect adds route handling form and redirecting to html page
+func (spec *openAPISpec) redirect(method, path, summary string, body *openAPIBody, params ...*openAPIParameter) {
+
+	spec.add(method, path, &openAPIOperation{
+		Summary:     summary,
+		Tags:        []string{tagPages},
+		Parameters:  params,
+		RequestBody: body,
+		Responses: map[string]*openAPIResponse{
+			"302":     {Description: "redirect to html page"},
+			"default": errorResponseSpec(),
+		},
+	})
+}
+
+// api adds json api route, nil schema means response without body
+func (spec *openAPISpec) api(method, path, summary string, body *openAPIBody, code int, schema *openAPISchema, params ...*openAPIParameter) {
+
+	response := &openAPIResponse{Description: http.StatusText(code)}
+	if schema != nil {
+		response.Content = map[string]*openAPIMediaType{"application/json": {Schema: schema}}
+	}
+
+	spec.add(method, path, &openAPIOperation{
+		Summary:     summary,
+		Tags:        []string{tagAPI},
+		Parameters:  params,
+		RequestBody: body,
+		Responses: map[string]*openAPIResponse{
+			strconv.Itoa(code): response,
+			"default":          errorResponseSpec(),
+		},
+	})
+}
+
+// addSchema adds schema of struct built from its json tags to components
+func (spec *openAPISpec) addSchema(name string, v interface{}) {
+	spec.Components.Schemas[name] = schemaOf(reflect.TypeOf(v))
+}
+
+func schemaOf(t reflect.Type) *openAPISchema {
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaOf(t.Elem())
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &openAPISchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &openAPISchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &openAPISchema{Type: "array", Items: schemaOf(t.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object"}
+	case reflect.Struct:
+		schema := &openAPISchema{Type: "object", Properties: make(map[string]*openAPISchema)}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			schema.Properties[name] = schemaOf(field.Type)
+		}
+		return schema
+	}
+
+	return &openAPISchema{Type: "string"}
+}
+
+func ref(name string) *openAPISchema {
+	return &openAPISchema{Ref: "#/components/schemas/" + name}
+}
+
+func pathParam(name, typ string) *openAPIParameter {
+	return &openAPIParameter{Name: name, In: "path", Required: true, Schema: &openAPISchema{Type: typ}}
+}
+
+func queryParam(name, typ string) *openAPIParameter {
+	return &openAPIParameter{Name: name, In: "query", Schema: &openAPISchema{Type: typ}}
+}
+
+func jsonBody(schema string) *openAPIBody {
+	return &openAPIBody{
+		Required: true,
+		Content:  map[string]*openAPIMediaType{"application/json": {Schema: ref(schema)}},
+	}
+}
+
+func formBody() *openAPIBody {
+	return &openAPIBody{
+		Required: true,
+		Content:  map[string]*openAPIMediaType{"application/x-www-form-urlencoded": {Schema: &openAPISchema{Type: "object"}}},
+	}
+}
+
+func errorResponseSpec() *openAPIResponse {
+	return &openAPIResponse{
+		Description: "error",
+		Content:     map[string]*openAPIMediaType{"application/json": {Schema: ref("Error")}},
+	}
+}
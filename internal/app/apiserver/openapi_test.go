@@ -0,0 +1,77 @@
+package apiserver
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DalerBakhriev/social_network/internal/app/store/teststore"
+	"github.com/gorilla/mux"
+)
+
+var routeVarRe = regexp.MustCompile(`\{([^:}]+)(?::([^}]+))?\}`)
+
+func TestServer_EveryRouteIsDocumented(t *testing.T) {
+
+	s := newTestServer(t, teststore.New(), testConfig())
+
+	if missing := s.undocumentedRoutes(); len(missing) != 0 {
+		t.Fatalf("routes missing in OpenAPI specification: %v", missing)
+	}
+}
+
+func TestServer_PathParametersMatchRoutes(t *testing.T) {
+
+	s := newTestServer(t, teststore.New(), testConfig())
+	spec := newOpenAPISpec()
+
+	s.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		path := pathVarPatternRe.ReplaceAllString(tmpl, "{$1}")
+		for _, method := range methods {
+			op, ok := spec.Paths[path][strings.ToLower(method)]
+			if !ok {
+				continue
+			}
+
+			for _, match := range routeVarRe.FindAllStringSubmatch(tmpl, -1) {
+				expected := "string"
+				if match[2] == "[0-9]+" {
+					expected = "integer"
+				}
+
+				param := findPathParam(op, match[1])
+				if param == nil {
+					t.Errorf("%s %s: path parameter %s is not documented", method, path, match[1])
+					continue
+				}
+				if param.Schema.Type != expected {
+					t.Errorf("%s %s: path parameter %s is %s, expected %s", method, path, match[1], param.Schema.Type, expected)
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+func findPathParam(op *openAPIOperation, name string) *openAPIParameter {
+
+	for _, param := range op.Parameters {
+		if param.In == "path" && param.Name == name {
+			return param
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+package apiserver
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/mailer"
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"github.com/gorilla/mux"
+)
+
+// passwordResetTTL is how long password reset link is valid
+const passwordResetTTL = time.Hour
+
+type passwordForgotRequest struct {
+	Email string `json:"email"`
+}
+
+type passwordResetRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (s *server) handlePasswordForgot() http.HandlerFunc {
+
+	tmpl := parseTemplate("password_forgot.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodPost {
+			s.render(w, r, tmpl, model.PasswordForgotPage{})
+			return
+		}
+
+		if err := s.sendPasswordReset(r.FormValue("email")); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.render(w, r, tmpl, model.PasswordForgotPage{Sent: true})
+	}
+}
+
+func (s *server) handlePasswordReset() http.HandlerFunc {
+
+	tmpl := parseTemplate("password_reset.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		token := mux.Vars(r)["token"]
+
+		if r.Method != http.MethodPost {
+			if _, err := s.store.PasswordReset().FindByToken(hashResetToken(token)); err != nil {
+				s.passwordResetError(w, r, err)
+				return
+			}
+			s.render(w, r, tmpl, model.PasswordResetPage{Token: token})
+			return
+		}
+
+		if err := s.resetPassword(token, r.FormValue("password")); err != nil {
+			s.passwordResetError(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}
+}
+
+func (s *server) handleAPIPasswordForgot() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		req := &passwordForgotRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.sendPasswordReset(req.Email); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusAccepted, nil)
+	}
+}
+
+func (s *server) handleAPIPasswordReset() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		req := &passwordResetRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.resetPassword(req.Token, req.Password); err != nil {
+			s.passwordResetError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// sendPasswordReset emails password reset link to user with email at most
+// once in cooldown, unknown email and cooldown are not reported so that
+// emails of users can not be found out, email is sent in background
+// for the same reason
+func (s *server) sendPasswordReset(email string) error {
+
+	user, err := s.store.User().FindByEmail(email)
+	if err == store.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cooldown := time.Duration(s.config.PasswordResetCooldown) * time.Second
+	marked, err := s.store.User().MarkPasswordResetSent(user.ID, cooldown)
+	if err != nil || !marked {
+		return err
+	}
+
+	return s.sendPasswordResetLink(user)
+}
+
+// sendPasswordResetLink creates password reset of user and emails its link
+func (s *server) sendPasswordResetLink(user *model.User) error {
+
+	token, err := newResetToken()
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.PasswordReset().Create(&model.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashResetToken(token),
+		ExpiresAt: time.Now().UTC().Add(passwordResetTTL),
+	}); err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(s.config.BaseURL, "/") + "/password/reset/" + token
+	go s.sendMail(&mailer.Message{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf(
+			"To set new password open the link:\n%s\n\nThe link is valid for %s. "+
+				"If you did not ask to reset password just ignore this email.\n",
+			link,
+			passwordResetTTL,
+		),
+	})
+
+	return nil
+}
+
+// resetPassword sets new password of user who got the token
+// and logs the user out everywhere, token is used up only after
+// password is changed so that failed change does not burn the link
+func (s *server) resetPassword(token, password string) error {
+
+	if len(password) == 0 {
+		return model.ErrEmptyPassword
+	}
+
+	tokenHash := hashResetToken(token)
+	reset, err := s.store.PasswordReset().FindByToken(tokenHash)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.User().UpdatePassword(&model.User{ID: reset.UserID, Password: password}); err != nil {
+		return err
+	}
+
+	if _, err := s.store.PasswordReset().Use(tokenHash); err != nil {
+		return err
+	}
+
+	return s.store.Session().DeleteByUser(reset.UserID)
+}
+
+func (s *server) passwordResetError(w http.ResponseWriter, r *http.Request, err error) {
+
+	switch err {
+	case store.ErrRecordNotFound:
+		s.error(w, r, http.StatusBadRequest, errInvalidPasswordResetToken)
+	case model.ErrEmptyPassword:
+		s.error(w, r, http.StatusUnprocessableEntity, err)
+	default:
+		s.error(w, r, http.StatusInternalServerError, err)
+	}
+}
+
+func (s *server) sendMail(msg *mailer.Message) {
+	if err := s.mailer.Send(msg); err != nil {
+		s.logger.Errorf("Failed to send %q email: %v", msg.Subject, err)
+	}
+}
+
+func newResetToken() (string, error) {
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,129 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/mailer"
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/sessionstore"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"github.com/DalerBakhriev/social_network/internal/app/store/teststore"
+)
+
+// chanMailer passes sent emails to channel
+type chanMailer chan *mailer.Message
+
+func (m chanMailer) Send(msg *mailer.Message) error {
+	m <- msg
+	return nil
+}
+
+func TestServer_SendPasswordResetCooldown(t *testing.T) {
+
+	st := teststore.New()
+	createTestUser(t, st, "user@example.org", "password")
+	config := testConfig()
+	sent := make(chanMailer, 10)
+	s := newServer(st, sessionstore.NewStore(st.Session(), config.SessionMaxAge, []byte(config.SessionKey)), sent, config)
+
+	for i := 0; i < 3; i++ {
+		if err := s.sendPasswordReset("user@example.org"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("password reset email is not sent")
+	}
+	select {
+	case msg := <-sent:
+		t.Fatalf("another email is sent within cooldown: %q", msg.Subject)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestServer_ResetPasswordKeepsTokenOfFailedChange(t *testing.T) {
+
+	st := teststore.New()
+	s := newTestServer(t, st, testConfig())
+
+	// user the token was sent to is missing, so password can not be changed
+	if err := st.PasswordReset().Create(&model.PasswordReset{
+		UserID:    100,
+		TokenHash: hashResetToken("token"),
+		ExpiresAt: time.Now().UTC().Add(passwordResetTTL),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.resetPassword("token", "new password"); err == nil {
+		t.Fatal("password of missing user is changed")
+	}
+	if _, err := st.PasswordReset().FindByToken(hashResetToken("token")); err != nil {
+		t.Fatalf("token is used up by failed change: %v", err)
+	}
+}
+
+func TestServer_HandleAPIPasswordReset(t *testing.T) {
+
+	st := teststore.New()
+	user := createTestUser(t, st, "user@example.org", "password")
+	srv := httptest.NewServer(newTestServer(t, st, testConfig()))
+	defer srv.Close()
+
+	if err := st.PasswordReset().Create(&model.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashResetToken("token"),
+		ExpiresAt: time.Now().UTC().Add(passwordResetTTL),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestClient(t, srv)
+	for i, expected := range []int{http.StatusNoContent, http.StatusBadRequest} {
+		resp, err := c.client.Post(srv.URL+"/api/v1/password/reset", "application/json",
+			strings.NewReader(`{"token": "token", "password": "new password"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		readBody(t, resp)
+		if resp.StatusCode != expected {
+			t.Fatalf("reset %d: expected status %d, got %d", i+1, expected, resp.StatusCode)
+		}
+	}
+
+	if _, err := st.PasswordReset().FindByToken(hashResetToken("token")); err != store.ErrRecordNotFound {
+		t.Fatalf("token can be used again: %v", err)
+	}
+	c.logIn("user@example.org", "new password")
+}
+
+func TestServer_ResetUserPasswordIgnoresCooldown(t *testing.T) {
+
+	st := teststore.New()
+	user := createTestUser(t, st, "user@example.org", "password")
+	config := testConfig()
+	sent := make(chanMailer, 10)
+	s := newServer(st, sessionstore.NewStore(st.Session(), config.SessionMaxAge, []byte(config.SessionKey)), sent, config)
+
+	if err := s.sendPasswordReset(user.Email); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.resetUserPassword(httptest.NewRequest(http.MethodPost, "/admin/users", nil), user); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-sent:
+		case <-time.After(time.Second):
+			t.Fatalf("email %d is not sent", i+1)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/gorilla/mux"
+)
+
+const numPostsOnOnePage = 20
+
+// postRequest is json body of requests creating or changing post
+type postRequest struct {
+	Text string `json:"text"`
+}
+
+func (s *server) handleCreatePost() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		post := &model.Post{
+			AuthorID: userID,
+			Text:     r.FormValue("text"),
+		}
+
+		if err := s.createPost(post); err != nil {
+			s.postError(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/users/%d", userID), http.StatusFound)
+	}
+}
+
+func (s *server) handleEditPost() http.HandlerFunc {
+
+	tmpl := parseTemplate("post_edit.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		postID, err := getPostID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			post, err := s.store.Post().Find(postID)
+			if err != nil || post.AuthorID != userID {
+				s.error(w, r, http.StatusNotFound, errPostNotFound)
+				return
+			}
+			s.render(w, r, tmpl, post)
+			return
+		}
+
+		post := &model.Post{
+			ID:       postID,
+			AuthorID: userID,
+			Text:     r.FormValue("text"),
+		}
+
+		if err := s.updatePost(post); err != nil {
+			s.postError(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/users/%d", userID), http.StatusFound)
+	}
+}
+
+func (s *server) handleDeletePost() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		postID, err := getPostID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.store.Post().Delete(postID, userID); err != nil {
+			s.postError(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/users/%d", userID), http.StatusFound)
+	}
+}
+
+func (s *server) handleFeed() http.HandlerFunc {
+
+	tmpl := parseTemplate("feed.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		beforeID, err := getBeforeID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		feed, err := s.feed(userID, beforeID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.render(w, r, tmpl, model.Feed{
+			Posts:      feed.Posts,
+			CurrUserID: userID,
+			NextPage:   postsPageURL("/feed", feed.NextBefore),
+		})
+	}
+}
+
+func (s *server) handleAPIGetUserPosts() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := strconv.Atoi(mux.Vars(r)["user_id"])
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		beforeID, err := getBeforeID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		posts, err := s.store.Post().GetByAuthor(userID, beforeID, numPostsOnOnePage)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, posts)
+	}
+}
+
+func (s *server) handleAPICreatePost() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		req := &postRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		post := &model.Post{
+			AuthorID: user.ID,
+			Text:     req.Text,
+		}
+
+		if err := s.createPost(post); err != nil {
+			s.postError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, post)
+	}
+}
+
+func (s *server) handleAPIUpdatePost() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		postID, err := getPostID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &postRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		post := &model.Post{
+			ID:       postID,
+			AuthorID: user.ID,
+			Text:     req.Text,
+		}
+
+		if err := s.updatePost(post); err != nil {
+			s.postError(w, r, err)
+			return
+		}
+
+		post, err = s.store.Post().Find(postID)
+		if err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, post)
+	}
+}
+
+func (s *server) handleAPIDeletePost() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		postID, err := getPostID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.store.Post().Delete(postID, user.ID); err != nil {
+			s.postError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+func (s *server) handleAPIFeed() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		beforeID, err := getBeforeID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		feed, err := s.feed(user.ID, beforeID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, feed)
+	}
+}
+
+func (s *server) createPost(post *model.Post) error {
+
+	if err := post.Validate(); err != nil {
+		return err
+	}
+
+	return s.store.Post().Create(post)
+}
+
+func (s *server) updatePost(post *model.Post) error {
+
+	if err := post.Validate(); err != nil {
+		return err
+	}
+
+	return s.store.Post().Update(post)
+}
+
+// feed returns page of posts of accepted friends of user
+func (s *server) feed(userID, beforeID int) (*model.PostsPage, error) {
+
+	friends, err := s.store.User().GetFriendsList(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	friendIDs := make([]int, 0, len(friends))
+	for _, friend := range friends {
+		friendIDs = append(friendIDs, friend.ID)
+	}
+
+	return s.store.Post().GetFeed(friendIDs, beforeID, numPostsOnOnePage)
+}
+
+// postError responds with status code matching error of post validation or store
+func (s *server) postError(w http.ResponseWriter, r *http.Request, err error) {
+
+	switch err {
+	case model.ErrEmptyPost, model.ErrPostTooLong:
+		s.error(w, r, http.StatusUnprocessableEntity, err)
+	default:
+		s.storeError(w, r, err)
+	}
+}
+
+func getPostID(r *http.Request) (int, error) {
+	return strconv.Atoi(mux.Vars(r)["post_id"])
+}
+
+// getBeforeID reads id of the last seen post from query,
+// zero means the newest posts
+func getBeforeID(r *http.Request) (int, error) {
+
+	value := r.URL.Query().Get("before")
+	if value == "" {
+		return 0, nil
+	}
+
+	beforeID, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errWrongBeforeFormat
+	}
+
+	return beforeID, nil
+}
+
+// postsPageURL returns url of the next page of posts,
+// zero beforeID means there is no such page
+func postsPageURL(base string, beforeID int) string {
+
+	if beforeID == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%s?before=%d", base, beforeID)
+}
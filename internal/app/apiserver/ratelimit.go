@@ -0,0 +1,121 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/sessionstore"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"github.com/gorilla/mux"
+)
+
+// limitRate rejects requests above rate limit of route they matched,
+// requests of logged in users and api tokens are counted per user,
+// others per ip address
+func (s *server) limitRate(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		policy, ok := s.rateLimitOf(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		res, err := s.rateLimiter.Take(policy.Name+":"+s.rateLimitKey(r), policy.limit(), time.Now())
+		if err != nil {
+			s.logger.Errorf("Failed to check rate limit %s: %v", policy.Name, err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+		if !res.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			s.error(w, r, http.StatusTooManyRequests, errTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitOf returns the first rate limit listing route request matched
+func (s *server) rateLimitOf(r *http.Request) (RateLimitConfig, bool) {
+
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return RateLimitConfig{}, false
+	}
+
+	tmpl, err := route.GetPathTemplate()
+	if err != nil {
+		return RateLimitConfig{}, false
+	}
+
+	path := pathVarPatternRe.ReplaceAllString(tmpl, "{$1}")
+	for _, policy := range s.config.RateLimits {
+		if policy.matches(r.Method, path) {
+			return policy, true
+		}
+	}
+
+	return RateLimitConfig{}, false
+}
+
+// rateLimitKey returns key requests are counted with, requests
+// with api token are counted with requests of its user
+func (s *server) rateLimitKey(r *http.Request) string {
+
+	if token, ok := bearerToken(r); ok {
+		apiToken, err := s.store.APIToken().FindByHash(hashAPIToken(token))
+		if err == nil {
+			return fmt.Sprintf("user:%d", apiToken.UserID)
+		}
+		if err != store.ErrRecordNotFound {
+			s.logger.Errorf("Failed to find api token of rate limited request: %v", err)
+		}
+		return "ip:" + remoteIP(r)
+	}
+
+	if session, err := s.sessionStore.Get(r, sessionName); err == nil {
+		if id, ok := session.Values[sessionstore.UserIDKey].(int); ok {
+			return fmt.Sprintf("user:%d", id)
+		}
+	}
+
+	return "ip:" + remoteIP(r)
+}
+
+// unknownRateLimitRoutes returns routes of rate limits
+// which are not registered in router
+func (s *server) unknownRateLimitRoutes() []string {
+
+	known := make(map[string]bool)
+	s.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			known[pathVarPatternRe.ReplaceAllString(tmpl, "{$1}")] = true
+		}
+		return nil
+	})
+
+	unknown := make([]string, 0)
+	for _, policy := range s.config.RateLimits {
+		for _, route := range policy.Routes {
+			if !known[route] {
+				unknown = append(unknown, fmt.Sprintf("%s %s", policy.Name, route))
+			}
+		}
+	}
+
+	return unknown
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
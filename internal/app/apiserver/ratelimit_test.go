@@ -0,0 +1,60 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store/teststore"
+)
+
+func TestServer_RateLimitKey(t *testing.T) {
+
+	st := teststore.New()
+	user := createTestUser(t, st, "user@example.org", "password")
+	if err := st.APIToken().Create(&model.APIToken{
+		UserID:    user.ID,
+		Name:      "script",
+		Scopes:    []string{model.ScopeRead},
+		TokenHash: hashAPIToken("secret"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer(t, st, testConfig())
+
+	testCases := []struct {
+		name          string
+		authorization string
+		expected      string
+	}{
+		{
+			name:     "anonymous",
+			expected: "ip:192.0.2.1",
+		},
+		{
+			name:          "api token",
+			authorization: "Bearer secret",
+			expected:      fmt.Sprintf("user:%d", user.ID),
+		},
+		{
+			name:          "unknown api token",
+			authorization: "Bearer unknown",
+			expected:      "ip:192.0.2.1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+			if tc.authorization != "" {
+				r.Header.Set("Authorization", tc.authorization)
+			}
+
+			if key := s.rateLimitKey(r); key != tc.expected {
+				t.Fatalf("expected key %s, got %s", tc.expected, key)
+			}
+		})
+	}
+}
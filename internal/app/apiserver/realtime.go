@@ -0,0 +1,271 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/realtime"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// eventsBufferSize is the number of undelivered events kept per connection,
+	// connection of client which does not read events in time is closed
+	eventsBufferSize = 16
+	// eventsReplaySize is the number of the latest events kept per user
+	// to be replayed to clients reconnected to events stream
+	eventsReplaySize = 100
+	// eventsHistoryTTL is how long events of user are kept for replay
+	// after the latest of them, events of inactive users are swept
+	eventsHistoryTTL   = time.Hour
+	sseHeartbeatPeriod = 30 * time.Second
+	wsWriteTimeout     = 10 * time.Second
+	wsPongTimeout      = 60 * time.Second
+	wsPingPeriod       = wsPongTimeout * 9 / 10
+	wsMaxMessageSize   = 512
+)
+
+// handleWebSocket streams events of current user as json messages,
+// messages sent by client are ignored
+func (s *server) handleWebSocket() http.HandlerFunc {
+
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// upgrader has already responded with error
+			return
+		}
+		defer conn.Close()
+
+		sub := s.hub.Subscribe(user.ID)
+		defer s.hub.Unsubscribe(sub)
+
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			conn.SetReadLimit(wsMaxMessageSize)
+			conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+			})
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		ticker := time.NewTicker(wsPingPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case event := <-sub.Events():
+				conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+				if err := conn.WriteJSON(event); err != nil {
+					return
+				}
+
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+					return
+				}
+
+			case <-sub.Done():
+				conn.WriteControl(
+					websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "events are not read in time"),
+					time.Now().Add(wsWriteTimeout),
+				)
+				return
+
+			case <-closed:
+				return
+			}
+		}
+	}
+}
+
+// handleEvents streams events of current user as server-sent events for
+// clients which can not use websockets. Reconnected client gets events
+// published after the one named by Last-Event-ID header
+func (s *server) handleEvents() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			s.error(w, r, http.StatusInternalServerError, errStreamingUnsupported)
+			return
+		}
+
+		lastEventID, err := getLastEventID(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		userID := user.ID
+		missed := make([]*model.Event, 0)
+		var sub *realtime.Subscription
+		if lastEventID < 0 {
+			sub = s.hub.Subscribe(userID)
+		} else {
+			sub, missed = s.hub.SubscribeAfter(userID, lastEventID)
+		}
+		defer s.hub.Unsubscribe(sub)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		if lastEventID < 0 {
+			// event without data only sets id client reconnects from,
+			// so that events published while it is away are not missed
+			if _, err := fmt.Fprintf(w, "id: %d\n\n", s.hub.LastEventID()); err != nil {
+				return
+			}
+		}
+		for _, event := range missed {
+			if err := writeServerSentEvent(w, event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		ticker := time.NewTicker(sseHeartbeatPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case event := <-sub.Events():
+				if err := writeServerSentEvent(w, event); err != nil {
+					return
+				}
+				flusher.Flush()
+
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+
+			case <-sub.Done():
+				// client reconnects and gets missed events from replay buffer
+				return
+
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+func writeServerSentEvent(w http.ResponseWriter, event *model.Event) error {
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+
+	return err
+}
+
+// getLastEventID returns id of the last event received by client,
+// it is negative for the first connection
+func getLastEventID(r *http.Request) (int, error) {
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	if lastEventID == "" {
+		return -1, nil
+	}
+
+	id, err := strconv.Atoi(lastEventID)
+	if err != nil || id < 0 {
+		return 0, errWrongLastEventIDFormat
+	}
+
+	return id, nil
+}
+
+// notifyFriendRequest tells addressee about friend request
+func (s *server) notifyFriendRequest(fromID, toID int) {
+	s.publish(model.EventFriendRequest, fromID, toID)
+}
+
+// notifyFriendRequestAccepted tells requester that user accepted the request
+func (s *server) notifyFriendRequestAccepted(userID, requesterID int) {
+	s.publish(model.EventFriendRequestAccepted, userID, requesterID)
+}
+
+// notifyProfileUpdated tells friends of user that user changed profile
+func (s *server) notifyProfileUpdated(userID int) {
+
+	friends, err := s.store.User().GetFriendsList(userID)
+	if err != nil {
+		s.logger.Errorf("Failed to notify friends of user %d about profile update: %v", userID, err)
+		return
+	}
+
+	ids := make([]int, 0, len(friends))
+	for _, friend := range friends {
+		ids = append(ids, friend.ID)
+	}
+
+	s.publish(model.EventProfileUpdated, userID, ids...)
+}
+
+// publish stores notifications about event done by user and delivers
+// the event to connected recipients, failure is only logged
+// since the change is already saved
+func (s *server) publish(eventType string, userID int, recipients ...int) {
+
+	if len(recipients) == 0 {
+		return
+	}
+
+	s.notifyUsers(eventType, userID, recipients...)
+
+	user, err := s.store.User().Find(userID)
+	if err != nil {
+		s.logger.Errorf("Failed to publish %s event of user %d: %v", eventType, userID, err)
+		return
+	}
+
+	event := &model.Event{
+		Type:        eventType,
+		UserID:      user.ID,
+		UserName:    user.Name,
+		UserSurname: user.Surname,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	for _, id := range recipients {
+		s.hub.Publish(id, event)
+	}
+}
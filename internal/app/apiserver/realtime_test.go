@@ -0,0 +1,115 @@
+package apiserver
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/realtime"
+	"github.com/DalerBakhriev/social_network/internal/app/store/teststore"
+)
+
+// readEventIDs returns ids of the first n events of stream
+func readEventIDs(t *testing.T, stream *bufio.Reader, n int) []string {
+
+	t.Helper()
+
+	ids := make([]string, 0, n)
+	for len(ids) < n {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read events: %v, got ids %v", err, ids)
+		}
+		if strings.HasPrefix(line, "id: ") {
+			ids = append(ids, strings.TrimSpace(strings.TrimPrefix(line, "id: ")))
+		}
+	}
+
+	return ids
+}
+
+func TestServer_HandleEventsReplay(t *testing.T) {
+
+	testCases := []struct {
+		name        string
+		lastEventID string
+		expectedIDs []string
+	}{
+		{
+			name:        "first connection",
+			expectedIDs: []string{"5", "6"},
+		},
+		{
+			name:        "reconnect",
+			lastEventID: "3",
+			expectedIDs: []string{"4", "5", "6"},
+		},
+		{
+			name:        "reconnect after nothing missed",
+			lastEventID: "5",
+			expectedIDs: []string{"6"},
+		},
+		{
+			name:        "last event id older than buffer",
+			lastEventID: "1",
+			expectedIDs: []string{"3", "4", "5", "6"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st := teststore.New()
+			user := createTestUser(t, st, "user@example.org", "password")
+			s := newTestServer(t, st, testConfig())
+			s.hub = realtime.NewHub(eventsBufferSize, 3, time.Hour)
+			srv := httptest.NewServer(s)
+			defer srv.Close()
+
+			for i := 0; i < 5; i++ {
+				s.hub.Publish(user.ID, &model.Event{Type: model.EventProfileUpdated})
+			}
+
+			c := newTestClient(t, srv)
+			c.logIn(user.Email, "password")
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tc.lastEventID)
+			}
+			resp, err := c.client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+			}
+
+			stream := bufio.NewReader(resp.Body)
+			replayed := len(tc.expectedIDs) - 1
+			if tc.lastEventID == "" {
+				// first connection only gets id to reconnect from
+				replayed = 1
+			}
+			ids := readEventIDs(t, stream, replayed)
+
+			s.hub.Publish(user.ID, &model.Event{Type: model.EventProfileUpdated})
+			ids = append(ids, readEventIDs(t, stream, 1)...)
+
+			if fmt.Sprint(ids) != fmt.Sprint(tc.expectedIDs) {
+				t.Fatalf("expected events %v, got %v", tc.expectedIDs, ids)
+			}
+		})
+	}
+}
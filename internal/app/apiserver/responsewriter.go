@@ -1,6 +1,11 @@
 package apiserver
 
-import "net/http"
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
 
 type responseWriter struct {
 	http.ResponseWriter
@@ -11,3 +16,21 @@ func (w *responseWriter) WriteHeader(statusCode int) {
 	w.code = statusCode
 	w.ResponseWriter.WriteHeader(statusCode)
 }
+
+// Hijack lets websocket connections take over underlying connection
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+
+	return hijacker.Hijack()
+}
+
+// Flush lets server-sent events reach client immediately
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
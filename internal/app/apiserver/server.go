@@ -5,9 +5,16 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/DalerBakhriev/social_network/internal/app/activity"
+	"github.com/DalerBakhriev/social_network/internal/app/identity"
+	"github.com/DalerBakhriev/social_network/internal/app/mailer"
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/ratelimit"
+	"github.com/DalerBakhriev/social_network/internal/app/realtime"
 	"github.com/DalerBakhriev/social_network/internal/app/store"
-	"github.com/gorilla/handlers"
+	"github.com/DalerBakhriev/social_network/internal/app/twofactor"
 	"github.com/gorilla/mux"
+	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
 	"go.uber.org/zap"
 )
@@ -16,22 +23,33 @@ const (
 	sessionName        = "some_session"
 	ctxKeyUser  ctxKey = iota
 	ctxKeyRequestID
+	ctxKeyAPIToken
 )
 
 type ctxKey int8
 
 type server struct {
 	router       *mux.Router
+	handler      http.Handler
 	logger       *zap.SugaredLogger
 	store        store.Store
 	sessionStore sessions.Store
+	mailer       mailer.Mailer
+	activityFeed *activity.Feed
+	hub          *realtime.Hub
+	rateLimiter  ratelimit.Backend
+	config       *Config
+
+	verificationCodec *securecookie.SecureCookie
+	twoFactorCipher   *twofactor.Cipher
+	identityProviders []identity.Provider
 }
 
 func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	s.router.ServeHTTP(w, r)
+	s.handler.ServeHTTP(w, r)
 }
 
-func newServer(store store.Store, sessionStore sessions.Store) *server {
+func newServer(store store.Store, sessionStore sessions.Store, mailer mailer.Mailer, config *Config) *server {
 
 	logger, err := zap.NewProduction()
 	if err != nil {
@@ -46,10 +64,31 @@ func newServer(store store.Store, sessionStore sessions.Store) *server {
 		logger:       sugaredLogger,
 		store:        store,
 		sessionStore: sessionStore,
+		mailer:       mailer,
+		activityFeed: activity.NewFeed(store, activity.NewMemoryCache(activityFeedSize), activityFeedSize),
+		hub:          realtime.NewHub(eventsBufferSize, eventsReplaySize, eventsHistoryTTL),
+		rateLimiter:  ratelimit.NewMemoryBackend(),
+		config:       config,
+
+		verificationCodec: newVerificationCodec(config),
+		twoFactorCipher:   newTwoFactorCipher(config),
+		identityProviders: newIdentityProviders(config),
+	}
+
+	if s.twoFactorCipher == nil {
+		s.logger.Warn("two_factor_key is not set, two-factor authentication is unavailable")
 	}
 
 	s.configureRouter()
 
+	for _, route := range s.undocumentedRoutes() {
+		s.logger.Warnf("Route %s is missing in OpenAPI specification", route)
+	}
+
+	for _, route := range s.unknownRateLimitRoutes() {
+		s.logger.Warnf("Rate limit route %s is not registered", route)
+	}
+
 	return s
 }
 
@@ -58,6 +97,9 @@ func (s *server) error(w http.ResponseWriter, r *http.Request, statusCode int, e
 }
 
 func (s *server) respond(w http.ResponseWriter, r *http.Request, statusCode int, data interface{}) {
+	if data != nil {
+		w.Header().Set("Content-Type", "application/json")
+	}
 	w.WriteHeader(statusCode)
 	if data != nil {
 		json.NewEncoder(w).Encode(data)
@@ -68,18 +110,68 @@ func (s *server) configureRouter() {
 
 	s.router.Use(s.setRequestID)
 	s.router.Use(s.logRequest)
-	s.router.Use(handlers.CORS(handlers.AllowedOrigins([]string{"*"})))
+	s.router.Use(s.limitRate)
+	s.router.Use(s.protectFromCSRF)
 	s.router.HandleFunc("/signup", s.handleSignUp()).Methods("GET", "POST")
 	s.router.HandleFunc("/login", s.handleLogIn()).Methods("GET", "POST")
-	s.router.HandleFunc("/logout", s.handleLogOut()).Methods("GET", "POST")
+	s.router.HandleFunc("/login/2fa", s.handleTwoFactorLogIn()).Methods("GET", "POST")
+	s.router.HandleFunc("/auth/{provider:[a-z0-9_-]+}", s.handleExternalLogIn()).Methods("GET")
+	s.router.HandleFunc("/auth/{provider:[a-z0-9_-]+}/callback", s.handleExternalLogInCallback()).Methods("GET")
+	s.router.HandleFunc("/logout", s.handleLogOut()).Methods("POST")
+	s.router.HandleFunc("/password/forgot", s.handlePasswordForgot()).Methods("GET", "POST")
+	s.router.HandleFunc("/password/reset/{token}", s.handlePasswordReset()).Methods("GET", "POST")
+	s.router.HandleFunc("/verify/resend", s.handleResendVerification()).Methods("POST")
+	s.router.HandleFunc("/verify/{token}", s.handleVerifyEmail()).Methods("GET")
 	s.router.HandleFunc("/user_edit", s.handleUserEdit()).Methods("GET", "POST")
 	s.router.HandleFunc("/", s.handleMainPage()).Methods("GET")
+	s.router.HandleFunc("/users/search", s.handleSearchUsers()).Methods("GET")
 	s.router.HandleFunc("/users/{user_id:[0-9]+}", s.handleGetSingleUser()).Methods("GET")
 	s.router.HandleFunc("/users/{user_id:[0-9]+}/friends", s.handleGetFriendsList()).Methods("GET")
 	s.router.HandleFunc("/users/{user_id:[0-9]+}/friends_requests", s.handleGetFriendsRequests()).Methods("GET")
-	s.router.HandleFunc("/users/send_friend_request/{friend_id:[0-9]+}", s.handleSendFriendsRequest()).Methods("GET", "POST")
-	s.router.HandleFunc("/users/{user_id:[0-9]+}/accept_friend_request/{friend_id:[0-9]+}", s.handleAcceptFriendsRequest()).Methods("GET", "POST")
-
-	private := s.router.PathPrefix("/private").Subrouter()
-	private.Use(s.authenticateUser)
+	s.router.HandleFunc("/users/send_friend_request/{friend_id:[0-9]+}", s.requireVerifiedEmail(actionFriendRequests, s.handleSendFriendsRequest())).Methods("POST")
+	s.router.HandleFunc("/users/{user_id:[0-9]+}/accept_friend_request/{friend_id:[0-9]+}", s.requireVerifiedEmail(actionFriendRequests, s.handleAcceptFriendsRequest())).Methods("POST")
+	s.router.HandleFunc("/users/{user_id:[0-9]+}/decline_friend_request/{friend_id:[0-9]+}", s.handleDeclineFriendsRequest()).Methods("POST")
+	s.router.HandleFunc("/users/{user_id:[0-9]+}/cancel_friend_request/{friend_id:[0-9]+}", s.handleCancelFriendsRequest()).Methods("POST")
+	s.router.HandleFunc("/users/{user_id:[0-9]+}/remove_friend/{friend_id:[0-9]+}", s.handleRemoveFriend()).Methods("POST")
+	s.router.HandleFunc("/feed", s.handleFeed()).Methods("GET")
+	s.router.HandleFunc("/activity", s.handleActivity()).Methods("GET")
+	s.router.Handle("/ws", s.authenticateUser(s.handleWebSocket())).Methods("GET")
+	s.router.Handle("/events", s.authenticateUser(s.handleEvents())).Methods("GET")
+	s.router.HandleFunc("/notifications", s.handleNotifications()).Methods("GET")
+	s.router.HandleFunc("/notifications/{notification_id:[0-9]+}/read", s.handleReadNotification()).Methods("POST")
+	s.router.HandleFunc("/notifications/read_all", s.handleReadAllNotifications()).Methods("POST")
+	s.router.HandleFunc("/sessions", s.handleSessions()).Methods("GET")
+	s.router.HandleFunc("/sessions/{session_id:[0-9a-f]+}/revoke", s.handleRevokeSession()).Methods("POST")
+	s.router.HandleFunc("/sessions/revoke_all", s.handleRevokeAllSessions()).Methods("POST")
+	s.router.HandleFunc("/two_factor", s.handleTwoFactor()).Methods("GET")
+	s.router.HandleFunc("/two_factor/enroll", s.handleTwoFactorEnroll()).Methods("POST")
+	s.router.HandleFunc("/two_factor/confirm", s.handleTwoFactorConfirm()).Methods("POST")
+	s.router.HandleFunc("/two_factor/disable", s.handleTwoFactorDisable()).Methods("POST")
+	s.router.HandleFunc("/api_tokens", s.handleAPITokens()).Methods("GET", "POST")
+	s.router.HandleFunc("/api_tokens/{token_id:[0-9]+}/revoke", s.handleRevokeAPIToken()).Methods("POST")
+	s.router.HandleFunc("/dialogs", s.handleDialogs()).Methods("GET")
+	s.router.HandleFunc("/dialogs/{user_id:[0-9]+}", s.requireVerifiedEmail(actionMessages, s.handleDialog())).Methods("GET", "POST")
+	s.router.HandleFunc("/posts", s.requireVerifiedEmail(actionPosts, s.handleCreatePost())).Methods("POST")
+	s.router.HandleFunc("/posts/{post_id:[0-9]+}/edit", s.handleEditPost()).Methods("GET", "POST")
+	s.router.HandleFunc("/posts/{post_id:[0-9]+}/delete", s.handleDeletePost()).Methods("POST")
+
+	admin := s.router.PathPrefix("/admin").Subrouter()
+	admin.Use(s.authenticateUser)
+	admin.HandleFunc("/users", s.requireRole(model.RoleModerator, s.handleAdminUsers())).Methods("GET")
+	admin.HandleFunc("/users/{user_id:[0-9]+}/suspend", s.requireRole(model.RoleModerator, s.handleAdminAction(s.suspendUser))).Methods("POST")
+	admin.HandleFunc("/users/{user_id:[0-9]+}/unsuspend", s.requireRole(model.RoleModerator, s.handleAdminAction(s.unsuspendUser))).Methods("POST")
+	admin.HandleFunc("/users/{user_id:[0-9]+}/notice", s.requireRole(model.RoleModerator, s.handleAdminAction(func(r *http.Request, target *model.User) error {
+		return s.sendNotice(r, target, r.FormValue("text"))
+	}))).Methods("POST")
+	admin.HandleFunc("/users/{user_id:[0-9]+}/role", s.requireRole(model.RoleAdmin, s.handleAdminAction(func(r *http.Request, target *model.User) error {
+		return s.setRole(r, target, r.FormValue("role"))
+	}))).Methods("POST")
+	admin.HandleFunc("/users/{user_id:[0-9]+}/reset_password", s.requireRole(model.RoleAdmin, s.handleAdminAction(s.resetUserPassword))).Methods("POST")
+	admin.HandleFunc("/users/{user_id:[0-9]+}/delete", s.requireRole(model.RoleAdmin, s.handleAdminAction(s.deleteUser))).Methods("POST")
+
+	s.router.HandleFunc("/api/openapi.json", s.handleOpenAPISpec()).Methods("GET")
+	s.configureAPIRouter(s.router.PathPrefix("/api/v1").Subrouter())
+
+	// CORS wraps router to answer preflight requests, they match no route
+	s.handler = s.allowCORS(s.router)
 }
@@ -0,0 +1,168 @@
+package apiserver
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DalerBakhriev/social_network/internal/app/mailer"
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/sessionstore"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var csrfTokenRe = regexp.MustCompile(`name="gorilla.csrf.Token" value="([^"]+)"`)
+
+func TestMain(m *testing.M) {
+
+	// templates are parsed relative to root of repository
+	if err := os.Chdir("../../.."); err != nil {
+		panic(err)
+	}
+
+	if err := model.SetPasswordCost(bcrypt.MinCost); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+func testConfig() *Config {
+
+	config := NewConfig()
+	config.SessionKey = "test_session_key"
+
+	return config
+}
+
+// newTestServer returns server over in-memory store
+func newTestServer(t *testing.T, st store.Store, config *Config) *server {
+
+	t.Helper()
+
+	sessionStore := sessionstore.NewStore(st.Session(), config.SessionMaxAge, []byte(config.SessionKey))
+
+	return newServer(st, sessionStore, mailer.NewLogMailer(ioutil.Discard, config.Mailer.From), config)
+}
+
+// testClient is a browser of one user, it keeps cookies
+// and does not follow redirects
+type testClient struct {
+	t      *testing.T
+	srv    *httptest.Server
+	client *http.Client
+}
+
+func newTestClient(t *testing.T, srv *httptest.Server) *testClient {
+
+	t.Helper()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testClient{
+		t:   t,
+		srv: srv,
+		client: &http.Client{
+			Jar: jar,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (c *testClient) get(path string) (*http.Response, string) {
+
+	c.t.Helper()
+
+	resp, err := c.client.Get(c.srv.URL + path)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	return resp, readBody(c.t, resp)
+}
+
+// postForm posts form with csrf token taken from log in page
+func (c *testClient) postForm(path string, form url.Values) *http.Response {
+
+	c.t.Helper()
+
+	_, page := c.get("/login")
+	match := csrfTokenRe.FindStringSubmatch(page)
+	if match == nil {
+		c.t.Fatal("csrf token is missing on log in page")
+	}
+
+	if form == nil {
+		form = url.Values{}
+	}
+	form.Set("gorilla.csrf.Token", match[1])
+
+	resp, err := c.client.PostForm(c.srv.URL+path, form)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	readBody(c.t, resp)
+
+	return resp
+}
+
+func (c *testClient) logIn(email, password string) {
+
+	c.t.Helper()
+
+	resp := c.postForm("/login", url.Values{"email": {email}, "password": {password}})
+	if resp.StatusCode != http.StatusFound {
+		c.t.Fatalf("log in of %s: status %d", email, resp.StatusCode)
+	}
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+
+	t.Helper()
+
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+// createTestUser creates user with verified email
+func createTestUser(t *testing.T, st store.Store, email, password string) *model.User {
+
+	t.Helper()
+
+	user := &model.User{
+		Email:    email,
+		Password: password,
+		Name:     strings.Split(email, "@")[0],
+		Surname:  "Test",
+		City:     "Moscow",
+		Age:      30,
+		Sex:      "female",
+	}
+	if err := st.User().Create(user); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := st.User().VerifyEmail(user.ID, user.Email); err != nil {
+		t.Fatal(err)
+	}
+
+	return user
+}
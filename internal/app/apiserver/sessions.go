@@ -0,0 +1,269 @@
+package apiserver
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/sessionstore"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
+)
+
+// cleanupPeriod is how often expired sessions are deleted
+const cleanupPeriod = 10 * time.Minute
+
+// sessionsResponse is json body of response with sessions of user
+type sessionsResponse struct {
+	Sessions []*model.Session `json:"sessions"`
+}
+
+func (s *server) handleSessions() http.HandlerFunc {
+
+	tmpl := parseTemplate("sessions.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		sessions, err := s.userSessions(r, userID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.render(w, r, tmpl, model.Sessions{
+			Sessions:   sessions,
+			CurrUserID: userID,
+		})
+	}
+}
+
+func (s *server) handleRevokeSession() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		current, err := s.revokeSession(w, r, userID, mux.Vars(r)["session_id"])
+		if err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		if current {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+
+		http.Redirect(w, r, "/sessions", http.StatusFound)
+	}
+}
+
+// handleRevokeAllSessions logs user out everywhere including current session
+func (s *server) handleRevokeAllSessions() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		if err := s.revokeAllSessions(w, r, userID); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}
+}
+
+func (s *server) handleAPISessions() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		sessions, err := s.userSessions(r, user.ID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, sessionsResponse{Sessions: sessions})
+	}
+}
+
+func (s *server) handleAPIRevokeSession() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		if _, err := s.revokeSession(w, r, user.ID, mux.Vars(r)["session_id"]); err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+func (s *server) handleAPIRevokeAllSessions() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		if err := s.revokeAllSessions(w, r, user.ID); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// userSessions returns active sessions of user with
+// the session of request marked as current
+func (s *server) userSessions(r *http.Request, userID int) ([]*model.Session, error) {
+
+	sessions, err := s.store.Session().GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	currentID, err := s.currentSessionID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == currentID
+	}
+
+	return sessions, nil
+}
+
+// revokeSession deletes session of user and tells whether it was
+// the session of request, then its cookie is removed as well
+func (s *server) revokeSession(w http.ResponseWriter, r *http.Request, userID int, id string) (bool, error) {
+
+	session, err := s.store.Session().Find(id)
+	if err != nil {
+		return false, err
+	}
+	if session.UserID != userID {
+		return false, store.ErrRecordNotFound
+	}
+
+	currentID, err := s.currentSessionID(r)
+	if err != nil {
+		return false, err
+	}
+
+	if id == currentID {
+		return true, s.endSession(w, r)
+	}
+
+	return false, s.store.Session().Delete(id)
+}
+
+func (s *server) revokeAllSessions(w http.ResponseWriter, r *http.Request, userID int) error {
+
+	if err := s.store.Session().DeleteByUser(userID); err != nil {
+		return err
+	}
+
+	return s.endSession(w, r)
+}
+
+// endSession deletes session of request and its cookies
+func (s *server) endSession(w http.ResponseWriter, r *http.Request) error {
+
+	session, err := s.sessionStore.Get(r, sessionName)
+	if err != nil {
+		return err
+	}
+
+	delete(session.Values, sessionstore.UserIDKey)
+	session.Options.MaxAge = -1
+	dropCSRFToken(w)
+
+	return session.Save(r, w)
+}
+
+// renewSession drops stored session before privilege of its owner changes,
+// session is saved with new token then. So token known before log in,
+// e.g. planted into browser of victim, does not authenticate after it
+func (s *server) renewSession(session *sessions.Session) error {
+
+	if session.ID != "" {
+		if err := s.store.Session().Delete(sessionstore.ID(session)); err != nil && err != store.ErrRecordNotFound {
+			return err
+		}
+	}
+	session.ID = ""
+
+	return nil
+}
+
+func (s *server) currentSessionID(r *http.Request) (string, error) {
+
+	session, err := s.sessionStore.Get(r, sessionName)
+	if err != nil {
+		return "", err
+	}
+
+	return sessionstore.ID(session), nil
+}
+
+// cleanup periodically deletes expired sessions, failed log ins
+// which do not count anymore and events of inactive users kept
+// for replay until done is closed
+func (s *server) cleanup(sessionStore *sessionstore.Store, done <-chan struct{}) {
+
+	ticker := time.NewTicker(cleanupPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			n, err := sessionStore.Cleanup()
+			if err != nil {
+				s.logger.Errorf("Failed to delete expired sessions: %v", err)
+			} else if n > 0 {
+				s.logger.Infof("Deleted %d expired sessions", n)
+			}
+
+			forgetBefore := time.Now().Add(-s.config.LoginThrottle.lockoutDuration())
+			if _, err := s.store.LoginAttempt().DeleteBefore(forgetBefore); err != nil {
+				s.logger.Errorf("Failed to delete old failed log ins: %v", err)
+			}
+
+			s.hub.Sweep(time.Now())
+
+		case <-done:
+			return
+		}
+	}
+}
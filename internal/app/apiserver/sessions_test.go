@@ -0,0 +1,63 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/DalerBakhriev/social_network/internal/app/store/teststore"
+)
+
+// sessionCookie returns session cookie client keeps for server
+func (c *testClient) sessionCookie() *http.Cookie {
+
+	c.t.Helper()
+
+	u, err := url.Parse(c.srv.URL)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	for _, cookie := range c.client.Jar.Cookies(u) {
+		if cookie.Name == sessionName {
+			return cookie
+		}
+	}
+
+	return nil
+}
+
+func TestServer_LogInRenewsSession(t *testing.T) {
+
+	st := teststore.New()
+	createTestUser(t, st, "alice@example.org", "password")
+	createTestUser(t, st, "bob@example.org", "password")
+	srv := httptest.NewServer(newTestServer(t, st, testConfig()))
+	defer srv.Close()
+
+	c := newTestClient(t, srv)
+	c.logIn("alice@example.org", "password")
+	before := c.sessionCookie()
+	if before == nil {
+		t.Fatal("session cookie is not set")
+	}
+
+	c.logIn("bob@example.org", "password")
+	after := c.sessionCookie()
+	if after == nil || after.Value == before.Value {
+		t.Fatal("session kept its token on log in")
+	}
+
+	// token of session before log in must not authenticate anybody
+	old := newTestClient(t, srv)
+	u, _ := url.Parse(srv.URL)
+	old.client.Jar.SetCookies(u, []*http.Cookie{{Name: sessionName, Value: before.Value}})
+	if resp, _ := old.get("/api/v1/me"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("session token before log in: status %d", resp.StatusCode)
+	}
+
+	if resp, _ := c.get("/api/v1/me"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("session token after log in: status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,585 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/sessionstore"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"github.com/DalerBakhriev/social_network/internal/app/twofactor"
+)
+
+const (
+	twoFactorIssuer = "Social network"
+	// twoFactorLoginTTL is how long user has to enter code
+	// after password was accepted
+	twoFactorLoginTTL         = 5 * time.Minute
+	numTwoFactorRecoveryCodes = 10
+)
+
+// session values kept between password and code steps of login
+const (
+	pendingUserIDKey = "two_factor_user_id"
+	pendingAtKey     = "two_factor_at"
+)
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type twoFactorStatusResponse struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+type twoFactorEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	QRCode string `json:"qr_code"`
+}
+
+type twoFactorRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type twoFactorRequiredResponse struct {
+	TwoFactorRequired bool `json:"two_factor_required"`
+}
+
+// newTwoFactorCipher returns cipher for totp secrets or nil
+// when key is not configured and two-factor authentication is off
+func newTwoFactorCipher(config *Config) *twofactor.Cipher {
+
+	cipher, err := twofactor.NewCipher(config.TwoFactorKey)
+	if err != nil {
+		return nil
+	}
+
+	return cipher
+}
+
+func (s *server) handleTwoFactor() http.HandlerFunc {
+
+	tmpl := parseTemplate("two_factor.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		status, err := s.twoFactorStatus(userID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.render(w, r, tmpl, model.TwoFactorPage{
+			Enabled:      status.Enabled,
+			RecoveryLeft: status.RecoveryCodesLeft,
+			CurrUserID:   userID,
+		})
+	}
+}
+
+func (s *server) handleTwoFactorEnroll() http.HandlerFunc {
+
+	tmpl := parseTemplate("two_factor.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		enrollment, err := s.enrollTwoFactor(userID)
+		if err != nil {
+			s.twoFactorError(w, r, err)
+			return
+		}
+
+		s.render(w, r, tmpl, model.TwoFactorPage{
+			Secret:     enrollment.Secret,
+			URI:        template.URL(enrollment.URI),
+			QRCode:     template.URL(enrollment.QRCode),
+			CurrUserID: userID,
+		})
+	}
+}
+
+func (s *server) handleTwoFactorConfirm() http.HandlerFunc {
+
+	tmpl := parseTemplate("two_factor.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		codes, err := s.confirmTwoFactor(userID, r.FormValue("code"))
+		if err != nil {
+			s.twoFactorError(w, r, err)
+			return
+		}
+
+		s.render(w, r, tmpl, model.TwoFactorPage{
+			Enabled:       true,
+			RecoveryCodes: codes,
+			RecoveryLeft:  len(codes),
+			CurrUserID:    userID,
+		})
+	}
+}
+
+func (s *server) handleTwoFactorDisable() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		if err := s.disableTwoFactor(userID, r.FormValue("code")); err != nil {
+			s.twoFactorError(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, "/two_factor", http.StatusFound)
+	}
+}
+
+func (s *server) handleTwoFactorLogIn() http.HandlerFunc {
+
+	tmpl := parseTemplate("login_two_factor.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodPost {
+			s.render(w, r, tmpl, nil)
+			return
+		}
+
+		userID, err := s.completeTwoFactorLogIn(w, r, r.FormValue("code"))
+		if err != nil {
+			s.twoFactorError(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/users/%d", userID), http.StatusFound)
+	}
+}
+
+func (s *server) handleAPITwoFactor() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		status, err := s.twoFactorStatus(user.ID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, status)
+	}
+}
+
+func (s *server) handleAPITwoFactorEnroll() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		enrollment, err := s.enrollTwoFactor(user.ID)
+		if err != nil {
+			s.twoFactorError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, enrollment)
+	}
+}
+
+func (s *server) handleAPITwoFactorConfirm() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		req := &twoFactorCodeRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		codes, err := s.confirmTwoFactor(user.ID, req.Code)
+		if err != nil {
+			s.twoFactorError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, &twoFactorRecoveryCodesResponse{RecoveryCodes: codes})
+	}
+}
+
+func (s *server) handleAPITwoFactorDisable() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		req := &twoFactorCodeRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.disableTwoFactor(user.ID, req.Code); err != nil {
+			s.twoFactorError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+func (s *server) handleAPITwoFactorLogIn() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		req := &twoFactorCodeRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		userID, err := s.completeTwoFactorLogIn(w, r, req.Code)
+		if err != nil {
+			s.twoFactorError(w, r, err)
+			return
+		}
+
+		user, err := s.store.User().Find(userID)
+		if err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		user.Sanitize()
+		s.respond(w, r, http.StatusOK, user)
+	}
+}
+
+// logIn starts session of user whose password is accepted,
+// when user has two-factor authentication enabled session only
+// remembers him until he enters code and true is returned
+func (s *server) logIn(w http.ResponseWriter, r *http.Request, user *model.User) (bool, error) {
+
+	session, err := s.sessionStore.Get(r, sessionName)
+	if err != nil {
+		return false, err
+	}
+
+	enrollment, err := s.store.TwoFactor().Find(user.ID)
+	if err != nil && err != store.ErrRecordNotFound {
+		return false, err
+	}
+
+	if err := s.renewSession(session); err != nil {
+		return false, err
+	}
+
+	if enrollment.Enabled() {
+		delete(session.Values, sessionstore.UserIDKey)
+		session.Values[pendingUserIDKey] = user.ID
+		session.Values[pendingAtKey] = time.Now().Unix()
+		return true, session.Save(r, w)
+	}
+
+	session.Values[sessionstore.UserIDKey] = user.ID
+	if err := session.Save(r, w); err != nil {
+		return false, err
+	}
+	dropCSRFToken(w)
+
+	return false, nil
+}
+
+// completeTwoFactorLogIn checks code of user who passed password step
+// and authenticates session, invalid codes are counted with failed log ins
+// of user so that they are limited across sessions
+func (s *server) completeTwoFactorLogIn(w http.ResponseWriter, r *http.Request, code string) (int, error) {
+
+	session, err := s.sessionStore.Get(r, sessionName)
+	if err != nil {
+		return 0, err
+	}
+
+	userID, ok := session.Values[pendingUserIDKey].(int)
+	startedAt, _ := session.Values[pendingAtKey].(int64)
+	if !ok || time.Since(time.Unix(startedAt, 0)) > twoFactorLoginTTL {
+		return 0, errTwoFactorLoginExpired
+	}
+
+	limit := s.userLoginLimit(userID)
+	attempts, _, err := s.reserveLogIn(limit)
+	if err == errTooManyLoginAttempts {
+		clearPendingLogIn(session.Values)
+		if err := session.Save(r, w); err != nil {
+			return 0, err
+		}
+		return 0, errTooManyTwoFactorAttempts
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.checkTwoFactorCode(userID, code); err != nil {
+		if err != errInvalidTwoFactorCode {
+			s.releaseLogIn(attempts[0])
+		} else if attempts[0].Failures == s.config.LoginThrottle.LockoutAttempts {
+			s.audit(&model.AuditEvent{
+				Type:    model.AuditLoginLockout,
+				UserID:  userID,
+				IP:      remoteIP(r),
+				Details: fmt.Sprintf("%d invalid two-factor codes of user %d", attempts[0].Failures, userID),
+			})
+		}
+		return 0, err
+	}
+
+	if err := s.store.LoginAttempt().Delete(limit.key); err != nil {
+		s.logger.Errorf("Failed to reset invalid two-factor codes of user %d: %v", userID, err)
+	}
+
+	if err := s.renewSession(session); err != nil {
+		return 0, err
+	}
+
+	clearPendingLogIn(session.Values)
+	session.Values[sessionstore.UserIDKey] = userID
+	if err := session.Save(r, w); err != nil {
+		return 0, err
+	}
+	dropCSRFToken(w)
+
+	return userID, nil
+}
+
+func clearPendingLogIn(values map[interface{}]interface{}) {
+	delete(values, pendingUserIDKey)
+	delete(values, pendingAtKey)
+}
+
+func (s *server) twoFactorStatus(userID int) (*twoFactorStatusResponse, error) {
+
+	enrollment, err := s.store.TwoFactor().Find(userID)
+	if err != nil && err != store.ErrRecordNotFound {
+		return nil, err
+	}
+
+	status := &twoFactorStatusResponse{Enabled: enrollment.Enabled()}
+	if status.Enabled {
+		if status.RecoveryCodesLeft, err = s.store.TwoFactor().CountRecoveryCodes(userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+// enrollTwoFactor generates new secret for user,
+// it is used after user confirms it with a code
+func (s *server) enrollTwoFactor(userID int) (*twoFactorEnrollResponse, error) {
+
+	if s.twoFactorCipher == nil {
+		return nil, errTwoFactorUnavailable
+	}
+
+	user, err := s.store.User().Find(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := twofactor.NewSecret(twoFactorIssuer, user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := s.twoFactorCipher.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.TwoFactor().Create(&model.TwoFactor{
+		UserID:          userID,
+		EncryptedSecret: encrypted,
+	}); err != nil {
+		return nil, err
+	}
+
+	uri := twofactor.ProvisioningURI(twoFactorIssuer, user.Email, secret)
+	qrCode, err := twofactor.QRCode(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return &twoFactorEnrollResponse{
+		Secret: secret,
+		URI:    uri,
+		QRCode: qrCode,
+	}, nil
+}
+
+// confirmTwoFactor enables two-factor authentication when code
+// matches enrolled secret and returns recovery codes, they are
+// shown to user only once
+func (s *server) confirmTwoFactor(userID int, code string) ([]string, error) {
+
+	enrollment, err := s.store.TwoFactor().Find(userID)
+	if err != nil {
+		if err == store.ErrRecordNotFound {
+			return nil, errTwoFactorNotEnrolled
+		}
+		return nil, err
+	}
+
+	if enrollment.Enabled() {
+		return nil, store.ErrTwoFactorAlreadyEnabled
+	}
+
+	step, err := s.validateTOTP(enrollment, code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := twofactor.NewRecoveryCodes(numTwoFactorRecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		hashes = append(hashes, twofactor.HashRecoveryCode(c))
+	}
+
+	if err := s.store.TwoFactor().Confirm(userID, hashes); err != nil {
+		if err == store.ErrRecordNotFound {
+			return nil, store.ErrTwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	// code used to confirm can not be used to log in
+	if err := s.store.TwoFactor().UseStep(userID, step); err != nil && err != store.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// disableTwoFactor turns two-factor authentication off,
+// user has to enter code once more to do it
+func (s *server) disableTwoFactor(userID int, code string) error {
+
+	if err := s.checkTwoFactorCode(userID, code); err != nil {
+		return err
+	}
+
+	return s.store.TwoFactor().Delete(userID)
+}
+
+// checkTwoFactorCode accepts either current totp code which was
+// not used before or unused recovery code of user
+func (s *server) checkTwoFactorCode(userID int, code string) error {
+
+	enrollment, err := s.store.TwoFactor().Find(userID)
+	if err != nil {
+		if err == store.ErrRecordNotFound {
+			return errTwoFactorNotEnabled
+		}
+		return err
+	}
+
+	if !enrollment.Enabled() {
+		return errTwoFactorNotEnabled
+	}
+
+	step, err := s.validateTOTP(enrollment, code)
+	if err == nil {
+		err = s.store.TwoFactor().UseStep(userID, step)
+		if err == store.ErrRecordNotFound {
+			return errInvalidTwoFactorCode
+		}
+		return err
+	}
+	if err != errInvalidTwoFactorCode {
+		return err
+	}
+
+	if err := s.store.TwoFactor().UseRecoveryCode(userID, twofactor.HashRecoveryCode(code)); err != nil {
+		if err == store.ErrRecordNotFound {
+			return errInvalidTwoFactorCode
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *server) validateTOTP(enrollment *model.TwoFactor, code string) (int64, error) {
+
+	if s.twoFactorCipher == nil {
+		return 0, errTwoFactorUnavailable
+	}
+
+	secret, err := s.twoFactorCipher.Decrypt(enrollment.EncryptedSecret)
+	if err != nil {
+		return 0, err
+	}
+
+	step, ok := twofactor.Validate(secret, code, time.Now())
+	if !ok {
+		return 0, errInvalidTwoFactorCode
+	}
+
+	return step, nil
+}
+
+func (s *server) twoFactorError(w http.ResponseWriter, r *http.Request, err error) {
+
+	switch err {
+	case errInvalidTwoFactorCode, errTwoFactorLoginExpired:
+		s.error(w, r, http.StatusUnauthorized, err)
+	case errTooManyTwoFactorAttempts:
+		s.error(w, r, http.StatusTooManyRequests, err)
+	case errTwoFactorNotEnrolled, errTwoFactorNotEnabled, store.ErrTwoFactorAlreadyEnabled:
+		s.error(w, r, http.StatusConflict, err)
+	case errTwoFactorUnavailable:
+		s.error(w, r, http.StatusServiceUnavailable, err)
+	default:
+		s.storeError(w, r, err)
+	}
+}
@@ -0,0 +1,102 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"github.com/DalerBakhriev/social_network/internal/app/store/teststore"
+	"github.com/DalerBakhriev/social_network/internal/app/twofactor"
+)
+
+// enableTestTwoFactor turns two-factor authentication of user on
+func enableTestTwoFactor(t *testing.T, st store.Store, config *Config, user *model.User) {
+
+	t.Helper()
+
+	cipher, err := twofactor.NewCipher(config.TwoFactorKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := twofactor.NewSecret(twoFactorIssuer, user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := cipher.Encrypt(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := st.TwoFactor().Create(&model.TwoFactor{UserID: user.ID, EncryptedSecret: encrypted}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.TwoFactor().Confirm(user.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServer_TwoFactorLogInLimitsCodesAcrossSessions(t *testing.T) {
+
+	st := teststore.New()
+	user := createTestUser(t, st, "user@example.org", "password")
+	config := testConfig()
+	config.TwoFactorKey = "test_two_factor_key"
+	enableTestTwoFactor(t, st, config, user)
+	srv := httptest.NewServer(newTestServer(t, st, config))
+	defer srv.Close()
+
+	allowed := config.LoginThrottle.FreeAttempts + 1
+	codes := 0
+	for session := 0; codes < allowed; session++ {
+		c := newTestClient(t, srv)
+		c.logIn("user@example.org", "password")
+		for i := 0; i < 2 && codes < allowed; i++ {
+			resp := c.postForm("/login/2fa", url.Values{"code": {"invalid"}})
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Fatalf("invalid code %d: status %d", codes+1, resp.StatusCode)
+			}
+			codes++
+		}
+	}
+
+	c := newTestClient(t, srv)
+	resp := c.postForm("/login", url.Values{"email": {"user@example.org"}, "password": {"password"}})
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("log in after %d invalid codes: status %d", codes, resp.StatusCode)
+	}
+}
+
+func TestServer_TwoFactorEnrollAfterConfirm(t *testing.T) {
+
+	st := teststore.New()
+	user := createTestUser(t, st, "user@example.org", "password")
+	config := testConfig()
+	config.TwoFactorKey = "test_two_factor_key"
+	srv := httptest.NewServer(newTestServer(t, st, config))
+	defer srv.Close()
+
+	c := newTestClient(t, srv)
+	c.logIn("user@example.org", "password")
+	enableTestTwoFactor(t, st, config, user)
+	before, err := st.TwoFactor().Find(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if resp := c.postForm("/two_factor/enroll", nil); resp.StatusCode != http.StatusConflict {
+			t.Fatalf("enrollment %d after confirm: status %d", i+1, resp.StatusCode)
+		}
+	}
+
+	after, err := st.TwoFactor().Find(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.EncryptedSecret != before.EncryptedSecret || !after.Enabled() {
+		t.Fatal("confirmed enrollment was replaced")
+	}
+}
@@ -0,0 +1,235 @@
+package apiserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/mailer"
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/securecookie"
+)
+
+// actions which may be forbidden to users with unverified email
+const (
+	actionFriendRequests = "friend_requests"
+	actionMessages       = "messages"
+	actionPosts          = "posts"
+)
+
+// verificationName is the name signed verification tokens are bound to
+const verificationName = "email_verification"
+
+// emailVerification is signed into link sent to user,
+// link stops working when user changes email
+type emailVerification struct {
+	UserID int
+	Email  string
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// newVerificationCodec returns codec signing verification links,
+// links expire in LinkTTL seconds
+func newVerificationCodec(config *Config) *securecookie.SecureCookie {
+
+	key := sha256.Sum256([]byte("verify:" + config.SessionKey))
+	codec := securecookie.New(key[:], nil)
+	codec.MaxAge(config.Verification.LinkTTL)
+
+	return codec
+}
+
+func (s *server) handleVerifyEmail() http.HandlerFunc {
+
+	tmpl := parseTemplate("email_verified.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if err := s.verifyEmail(mux.Vars(r)["token"]); err != nil {
+			s.verificationError(w, r, err)
+			return
+		}
+
+		s.render(w, r, tmpl, nil)
+	}
+}
+
+func (s *server) handleResendVerification() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, err := s.getUserID(w, r)
+		if err != nil {
+			s.userIDError(w, r, err)
+			return
+		}
+
+		user, err := s.store.User().Find(userID)
+		if err != nil {
+			s.storeError(w, r, err)
+			return
+		}
+
+		if err := s.sendVerification(user); err != nil {
+			s.verificationError(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/users/%d", userID), http.StatusFound)
+	}
+}
+
+func (s *server) handleAPIVerifyEmail() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		req := &verifyEmailRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.verifyEmail(req.Token); err != nil {
+			s.verificationError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+func (s *server) handleAPIResendVerification() http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		user, ok := s.requireCurrentUser(w, r)
+		if !ok {
+			return
+		}
+
+		if err := s.sendVerification(user); err != nil {
+			s.verificationError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusAccepted, nil)
+	}
+}
+
+func (s *server) verifyEmail(token string) error {
+
+	v := &emailVerification{}
+	if err := s.verificationCodec.Decode(verificationName, token, v); err != nil {
+		return errInvalidVerificationLink
+	}
+
+	if err := s.store.User().VerifyEmail(v.UserID, v.Email); err != nil {
+		if err == store.ErrRecordNotFound {
+			return errInvalidVerificationLink
+		}
+		return err
+	}
+
+	return nil
+}
+
+// sendVerification emails verification link to user,
+// it is sent at most once in resend cooldown
+func (s *server) sendVerification(user *model.User) error {
+
+	if user.EmailVerified() {
+		return errEmailAlreadyVerified
+	}
+
+	cooldown := time.Duration(s.config.Verification.ResendCooldown) * time.Second
+	marked, err := s.store.User().MarkVerificationSent(user.ID, cooldown)
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return errVerificationCooldown
+	}
+
+	token, err := s.verificationCodec.Encode(verificationName, &emailVerification{
+		UserID: user.ID,
+		Email:  user.Email,
+	})
+	if err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(s.config.BaseURL, "/") + "/verify/" + token
+	go s.sendMail(&mailer.Message{
+		To:      user.Email,
+		Subject: "Email verification",
+		Body: fmt.Sprintf(
+			"To confirm your email open the link:\n%s\n\nThe link is valid for %s.\n",
+			link,
+			time.Duration(s.config.Verification.LinkTTL)*time.Second,
+		),
+	})
+
+	return nil
+}
+
+func (s *server) verificationError(w http.ResponseWriter, r *http.Request, err error) {
+
+	switch err {
+	case errInvalidVerificationLink:
+		s.error(w, r, http.StatusBadRequest, err)
+	case errEmailAlreadyVerified:
+		s.error(w, r, http.StatusConflict, err)
+	case errVerificationCooldown:
+		w.Header().Set("Retry-After", fmt.Sprint(s.config.Verification.ResendCooldown))
+		s.error(w, r, http.StatusTooManyRequests, err)
+	default:
+		s.error(w, r, http.StatusInternalServerError, err)
+	}
+}
+
+// requireVerifiedEmail forbids unsafe requests of action to users with
+// unverified email when action is restricted in config
+func (s *server) requireVerifiedEmail(action string, next http.HandlerFunc) http.HandlerFunc {
+
+	if !s.config.Verification.restricts(action) {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next(w, r)
+			return
+		}
+
+		user, ok := currentUser(r)
+		if !ok {
+			userID, err := s.getUserID(w, r)
+			if err != nil {
+				// handler tells user to log in
+				next(w, r)
+				return
+			}
+			if user, err = s.store.User().Find(userID); err != nil {
+				s.storeError(w, r, err)
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), ctxKeyUser, user))
+		}
+
+		if !user.EmailVerified() {
+			s.error(w, r, http.StatusForbidden, errEmailNotVerified)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,58 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+var (
+	// ErrNonceMismatch is returned when id token was issued for another login
+	ErrNonceMismatch = errors.New("ID token nonce does not match")
+
+	// ErrNoIDToken is returned when token response has no id token
+	ErrNoIDToken = errors.New("Token response has no ID token")
+)
+
+// Identity is user account at identity provider
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Surname       string
+}
+
+// Provider lets users sign in with accounts of another site
+type Provider interface {
+	Name() string
+	DisplayName() string
+	// AuthCodeURL returns url user is redirected to for sign in,
+	// verifier is PKCE code verifier kept until callback
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	// Exchange trades authorization code for identity of user
+	Exchange(ctx context.Context, code, nonce, verifier string) (*Identity, error)
+}
+
+// RandomString returns url-safe random string, it is used for state,
+// nonce and PKCE code verifier
+func RandomString() (string, error) {
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// codeChallenge returns S256 PKCE challenge of verifier
+func codeChallenge(verifier string) string {
+
+	sum := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,161 @@
+package identity
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc"
+	"golang.org/x/oauth2"
+)
+
+// httpClient is used for requests to issuers, they must not hang logins
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// OIDCConfig describes OpenID Connect provider
+type OIDCConfig struct {
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RedirectURL  string
+}
+
+// OIDCProvider signs users in with authorization code flow with PKCE,
+// id tokens are verified with keys published by issuer
+type OIDCProvider struct {
+	config OIDCConfig
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCProvider returns provider, issuer is discovered on first
+// use so that server starts when issuer is not available
+func NewOIDCProvider(config OIDCConfig) *OIDCProvider {
+
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"email", "profile"}
+	}
+
+	return &OIDCProvider{config: config}
+}
+
+// Name ...
+func (p *OIDCProvider) Name() string {
+	return p.config.Name
+}
+
+// DisplayName ...
+func (p *OIDCProvider) DisplayName() string {
+
+	if p.config.DisplayName == "" {
+		return p.config.Name
+	}
+
+	return p.config.DisplayName
+}
+
+// AuthCodeURL ...
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+
+	config, _, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	return config.AuthCodeURL(
+		state,
+		oidc.Nonce(nonce),
+		oauth2.SetAuthURLParam("code_challenge", codeChallenge(verifier)),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	), nil
+}
+
+// Exchange ...
+func (p *OIDCProvider) Exchange(ctx context.Context, code, nonce, verifier string) (*Identity, error) {
+
+	config, idTokenVerifier, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := config.Exchange(oidc.ClientContext(ctx, httpClient), code, oauth2.SetAuthURLParam("code_verifier", verifier))
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, ErrNoIDToken
+	}
+
+	idToken, err := idTokenVerifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		return nil, ErrNonceMismatch
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		GivenName     string `json:"given_name"`
+		FamilyName    string `json:"family_name"`
+		Name          string `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Provider:      p.config.Name,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.GivenName,
+		Surname:       claims.FamilyName,
+	}
+	if identity.Name == "" {
+		identity.Name = claims.Name
+	}
+
+	return identity, nil
+}
+
+// discover fetches issuer metadata once it succeeds
+func (p *OIDCProvider) discover() (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth2 != nil {
+		return p.oauth2, p.verifier, nil
+	}
+
+	// key set keeps context to refresh keys later, so it must
+	// not be the context of request which starts discovery
+	discoveryCtx := oidc.ClientContext(context.Background(), httpClient)
+	provider, err := oidc.NewProvider(discoveryCtx, p.config.Issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  p.config.RedirectURL,
+		Scopes:       append([]string{oidc.ScopeOpenID}, p.config.Scopes...),
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.config.ClientID})
+
+	return p.oauth2, p.verifier, nil
+}
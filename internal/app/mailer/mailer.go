@@ -0,0 +1,99 @@
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails to users
+type Mailer interface {
+	Send(*Message) error
+}
+
+// SMTPMailer sends emails through SMTP server
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer returns mailer sending emails from address from,
+// PLAIN authentication is used when username is set
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+
+	m := &SMTPMailer{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		from: from,
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return m
+}
+
+// Send ...
+func (m *SMTPMailer) Send(msg *Message) error {
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, format(m.from, msg))
+}
+
+// LogMailer writes emails to out instead of sending them,
+// it is used for local runs and tests
+type LogMailer struct {
+	mu   sync.Mutex
+	from string
+	out  io.Writer
+}
+
+// NewLogMailer ...
+func NewLogMailer(out io.Writer, from string) *LogMailer {
+	return &LogMailer{
+		from: from,
+		out:  out,
+	}
+}
+
+// Send ...
+func (m *LogMailer) Send(msg *Message) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.out, "%s\n", format(m.from, msg))
+
+	return err
+}
+
+// format builds email with headers, header values
+// are stripped of line breaks to prevent header injection
+func format(from string, msg *Message) []byte {
+
+	header := func(s string) string {
+		return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", header(from))
+	fmt.Fprintf(&b, "To: %s\r\n", header(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", header(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
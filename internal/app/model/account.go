@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+// Account is user as administration sees it
+type Account struct {
+	ID          int        `json:"id"`
+	Email       string     `json:"email"`
+	Name        string     `json:"name"`
+	Surname     string     `json:"surname"`
+	Role        string     `json:"role"`
+	SuspendedAt *time.Time `json:"suspended_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// NewAccount ...
+func NewAccount(u *User) *Account {
+	return &Account{
+		ID:          u.ID,
+		Email:       u.Email,
+		Name:        u.Name,
+		Surname:     u.Surname,
+		Role:        u.Role,
+		SuspendedAt: u.SuspendedAt,
+		CreatedAt:   u.CreatedAt,
+	}
+}
+
+// AccountsPage is a page of accounts ordered from newer to older
+type AccountsPage struct {
+	Accounts []*Account `json:"accounts"`
+	// NextBefore is id to pass as before parameter
+	// to get the next page, zero when it is the last one
+	NextBefore int `json:"next_before,omitempty"`
+}
+
+// NewAccountsPage builds page from users selected with one extra row
+// which tells whether there are older accounts
+func NewAccountsPage(users []*User, limit int) *AccountsPage {
+
+	page := &AccountsPage{Accounts: make([]*Account, 0, len(users))}
+	if len(users) > limit {
+		users = users[:limit]
+		page.NextBefore = users[limit-1].ID
+	}
+	for _, u := range users {
+		page.Accounts = append(page.Accounts, NewAccount(u))
+	}
+
+	return page
+}
+
+// AdminPage ...
+type AdminPage struct {
+	*AccountsPage
+	Query      string
+	Roles      []string
+	IsAdmin    bool
+	CurrUserID int
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Types of activity of users shown to their friends
+const (
+	ActivityFriendAdded    = "friend_added"
+	ActivityProfileUpdated = "profile_updated"
+)
+
+// Activity is an event happened to user which is shown to friends of the user.
+// Subject is another user involved in the event, if any
+type Activity struct {
+	ID             int       `json:"id"`
+	Type           string    `json:"type"`
+	ActorID        int       `json:"actor_id"`
+	ActorName      string    `json:"actor_name"`
+	ActorSurname   string    `json:"actor_surname"`
+	SubjectID      int       `json:"subject_id,omitempty"`
+	SubjectName    string    `json:"subject_name,omitempty"`
+	SubjectSurname string    `json:"subject_surname,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Activities ...
+type Activities struct {
+	Activities []*Activity `json:"activities"`
+	CurrUserID int         `json:"-"`
+}
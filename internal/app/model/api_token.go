@@ -0,0 +1,88 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// scopes of api tokens
+const (
+	// ScopeRead allows reading everything user can read
+	ScopeRead = "read"
+	// ScopeFriends allows sending, answering friend requests and removing friends
+	ScopeFriends = "friends"
+	// ScopeMessages allows sending messages
+	ScopeMessages = "messages"
+)
+
+// MaxAPITokenNameLength is the max number of characters in token name
+const MaxAPITokenNameLength = 100
+
+var (
+	// ErrEmptyAPITokenName ...
+	ErrEmptyAPITokenName = errors.New("Token name must not be empty")
+
+	// ErrAPITokenNameTooLong ...
+	ErrAPITokenNameTooLong = errors.New("Token name is too long")
+
+	// ErrNoAPITokenScopes ...
+	ErrNoAPITokenScopes = errors.New("Token must have at least one scope")
+
+	// ErrUnknownAPITokenScope ...
+	ErrUnknownAPITokenScope = errors.New("Unknown token scope, must be read, friends or messages")
+)
+
+// APIToken is personal token user gives to scripts, only hash
+// of the token is stored, token itself is shown once on creation
+type APIToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	TokenHash  string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// Validate ...
+func (t *APIToken) Validate() error {
+
+	t.Name = strings.TrimSpace(t.Name)
+
+	switch {
+	case len(t.Name) == 0:
+		return ErrEmptyAPITokenName
+	case len([]rune(t.Name)) > MaxAPITokenNameLength:
+		return ErrAPITokenNameTooLong
+	case len(t.Scopes) == 0:
+		return ErrNoAPITokenScopes
+	}
+
+	for _, scope := range t.Scopes {
+		if scope != ScopeRead && scope != ScopeFriends && scope != ScopeMessages {
+			return ErrUnknownAPITokenScope
+		}
+	}
+
+	return nil
+}
+
+// HasScope ...
+func (t *APIToken) HasScope(scope string) bool {
+
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// APITokensPage ...
+type APITokensPage struct {
+	Tokens     []*APIToken
+	NewToken   string
+	CurrUserID int
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// types of audit events
+const (
+	// AuditLoginLockout is recorded when log in to account is locked
+	AuditLoginLockout = "login_lockout"
+	// AuditLoginIPLockout is recorded when log ins from ip address are locked
+	AuditLoginIPLockout = "login_ip_lockout"
+	// AuditUserSuspended is recorded when moderator suspends user
+	AuditUserSuspended = "user_suspended"
+	// AuditUserUnsuspended is recorded when suspension of user is lifted
+	AuditUserUnsuspended = "user_unsuspended"
+	// AuditRoleChanged is recorded when admin changes role of user
+	AuditRoleChanged = "role_changed"
+	// AuditPasswordReset is recorded when admin resets password of user
+	AuditPasswordReset = "password_reset"
+	// AuditUserDeleted is recorded when admin deletes account
+	AuditUserDeleted = "user_deleted"
+	// AuditNoticeSent is recorded when moderator sends notice to user
+	AuditNoticeSent = "notice_sent"
+)
+
+// AuditEvent is security relevant event kept for administrators,
+// UserID is zero when event is not related to existing user
+type AuditEvent struct {
+	ID        int       `json:"id"`
+	Type      string    `json:"type"`
+	UserID    int       `json:"user_id,omitempty"`
+	IP        string    `json:"ip"`
+	Details   string    `json:"details"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Types of events delivered to users in real time
+const (
+	EventFriendRequest         = "friend_request"
+	EventFriendRequestAccepted = "friend_request_accepted"
+	EventProfileUpdated        = "profile_updated"
+)
+
+// Event notifies user about something done by another user,
+// ids of events of every user are increasing
+type Event struct {
+	ID          int       `json:"id"`
+	Type        string    `json:"type"`
+	UserID      int       `json:"user_id"`
+	UserName    string    `json:"user_name"`
+	UserSurname string    `json:"user_surname"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// ExternalIdentity links account at identity provider to user
+type ExternalIdentity struct {
+	ID        int
+	UserID    int
+	Provider  string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
+
+// IdentityProviderLink is sign in button of login page
+type IdentityProviderLink struct {
+	Name        string
+	DisplayName string
+}
+
+// LogInPage ...
+type LogInPage struct {
+	Providers []IdentityProviderLink
+}
@@ -0,0 +1,14 @@
+package model
+
+// Statuses of friendship stored in friends table
+const (
+	FriendshipPending  = "pending"
+	FriendshipAccepted = "accepted"
+)
+
+// FriendRequests ...
+type FriendRequests struct {
+	Incoming   []*User `json:"incoming"`
+	Outgoing   []*User `json:"outgoing"`
+	CurrUserID int     `json:"-"`
+}
@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// LoginAttempts counts failed log ins with the same key,
+// key is made of email or ip address log ins come from
+type LoginAttempts struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	// PreviousFailureAt is LastFailureAt before attempt was reserved,
+	// it is restored when reserved attempt does not fail
+	PreviousFailureAt time.Time
+}
+
+// LoginLimit limits log ins with the same key. Log ins after
+// FreeAttempts failures wait backoff doubling up to MaxBackoff,
+// after LockoutAttempts failures they wait LockoutDuration
+type LoginLimit struct {
+	FreeAttempts    int
+	LockoutAttempts int
+	MaxBackoff      time.Duration
+	LockoutDuration time.Duration
+}
+
+// Expired tells whether failures of a are old enough to be forgotten
+func (l LoginLimit) Expired(a *LoginAttempts, now time.Time) bool {
+	return a.LastFailureAt.Before(now.Add(-l.LockoutDuration))
+}
+
+// BlockedUntil returns time the next log in with attempts a is allowed at
+func (l LoginLimit) BlockedUntil(a *LoginAttempts) time.Time {
+
+	if a.Failures >= l.LockoutAttempts {
+		return a.LastFailureAt.Add(l.LockoutDuration)
+	}
+
+	if a.Failures <= l.FreeAttempts {
+		return time.Time{}
+	}
+
+	backoff := l.MaxBackoff
+	if n := a.Failures - l.FreeAttempts - 1; n < 32 && time.Second<<uint(n) < backoff {
+		backoff = time.Second << uint(n)
+	}
+
+	return a.LastFailureAt.Add(backoff)
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// MaxMessageLength is the max number of characters in message text
+const MaxMessageLength = 4000
+
+var (
+	// ErrEmptyMessage ...
+	ErrEmptyMessage = errors.New("Message must not be empty")
+
+	// ErrMessageTooLong ...
+	ErrMessageTooLong = errors.New("Message is too long")
+)
+
+// Message is a private message from one user to another
+type Message struct {
+	ID        int        `json:"id"`
+	FromID    int        `json:"from_id"`
+	ToID      int        `json:"to_id"`
+	Text      string     `json:"text"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
+
+// Validate ...
+func (m *Message) Validate() error {
+
+	m.Text = strings.TrimSpace(m.Text)
+
+	switch {
+	case len(m.Text) == 0:
+		return ErrEmptyMessage
+	case len([]rune(m.Text)) > MaxMessageLength:
+		return ErrMessageTooLong
+	}
+
+	return nil
+}
+
+// Dialog is a conversation of user with another user
+type Dialog struct {
+	PeerID      int      `json:"peer_id"`
+	PeerName    string   `json:"peer_name"`
+	PeerSurname string   `json:"peer_surname"`
+	LastMessage *Message `json:"last_message"`
+	UnreadCount int      `json:"unread_count"`
+}
+
+// Dialogs ...
+type Dialogs struct {
+	Dialogs    []*Dialog `json:"dialogs"`
+	CurrUserID int       `json:"-"`
+}
+
+// MessagesPage is a page of messages ordered from newer to older
+type MessagesPage struct {
+	Messages []*Message `json:"messages"`
+	// NextBefore is id to pass as before parameter
+	// to get the next page, zero when it is the last one
+	NextBefore int `json:"next_before,omitempty"`
+}
+
+// NewMessagesPage builds page from messages selected with one extra row
+// which tells whether there are older messages
+func NewMessagesPage(messages []*Message, limit int) *MessagesPage {
+
+	page := &MessagesPage{Messages: messages}
+	if len(messages) > limit {
+		page.Messages = messages[:limit]
+		page.NextBefore = page.Messages[limit-1].ID
+	}
+
+	return page
+}
+
+// Conversation ...
+type Conversation struct {
+	Peer       *User
+	Messages   []*Message
+	CurrUserID int
+	NextPage   string
+}
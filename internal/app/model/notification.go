@@ -0,0 +1,80 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// NotificationAdminNotice is type of notice sent to user by administration,
+// notifications about actions of other users have types of events
+const NotificationAdminNotice = "admin_notice"
+
+// MaxNoticeLength is the max number of characters in text of admin notice
+const MaxNoticeLength = 1000
+
+var (
+	// ErrEmptyNotice ...
+	ErrEmptyNotice = errors.New("Notice must not be empty")
+
+	// ErrNoticeTooLong ...
+	ErrNoticeTooLong = errors.New("Notice is too long")
+)
+
+// Notification is stored notice for user, actor is user whose action
+// caused notification, text is set for admin notices only
+type Notification struct {
+	ID           int        `json:"id"`
+	UserID       int        `json:"-"`
+	Type         string     `json:"type"`
+	ActorID      int        `json:"actor_id,omitempty"`
+	ActorName    string     `json:"actor_name,omitempty"`
+	ActorSurname string     `json:"actor_surname,omitempty"`
+	Text         string     `json:"text,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ReadAt       *time.Time `json:"read_at,omitempty"`
+}
+
+// NewAdminNotice returns notice for user with text from administration
+func NewAdminNotice(userID int, text string) (*Notification, error) {
+
+	text = strings.TrimSpace(text)
+
+	switch {
+	case len(text) == 0:
+		return nil, ErrEmptyNotice
+	case len([]rune(text)) > MaxNoticeLength:
+		return nil, ErrNoticeTooLong
+	}
+
+	return &Notification{UserID: userID, Type: NotificationAdminNotice, Text: text}, nil
+}
+
+// NotificationsPage is a page of notifications ordered from newer to older
+type NotificationsPage struct {
+	Notifications []*Notification `json:"notifications"`
+	// NextBefore is id to pass as before parameter
+	// to get the next page, zero when it is the last one
+	NextBefore int `json:"next_before,omitempty"`
+	Unread     int `json:"unread"`
+}
+
+// NewNotificationsPage builds page from notifications selected
+// with one extra row which tells whether there are older ones
+func NewNotificationsPage(notifications []*Notification, limit int) *NotificationsPage {
+
+	page := &NotificationsPage{Notifications: notifications}
+	if len(notifications) > limit {
+		page.Notifications = notifications[:limit]
+		page.NextBefore = page.Notifications[limit-1].ID
+	}
+
+	return page
+}
+
+// Notifications ...
+type Notifications struct {
+	Notifications []*Notification
+	CurrUserID    int
+	NextPage      string
+}
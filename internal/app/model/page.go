@@ -0,0 +1,94 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor ...
+var ErrInvalidCursor = errors.New("Invalid page cursor")
+
+// UserFilter restricts users shown in users directory,
+// zero values mean no restriction
+type UserFilter struct {
+	City      string
+	Sex       string
+	MinAge    int
+	MaxAge    int
+	Interests string
+}
+
+// Cursor points to position in list of users ordered by name and id.
+// Backward cursor selects users before the position, forward one - after it
+type Cursor struct {
+	Name     string `json:"n"`
+	ID       int    `json:"i"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// Encode returns cursor representation safe to use in urls
+func (c *Cursor) Encode() string {
+
+	b, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses cursor made by Encode
+func DecodeCursor(s string) (*Cursor, error) {
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	c := &Cursor{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// UsersPage is a single page of users directory
+type UsersPage struct {
+	Users      []*User `json:"users"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	PrevCursor string  `json:"prev_cursor,omitempty"`
+}
+
+// NewUsersPage builds page from users selected with one extra row
+// which tells whether there are more users in the direction of cursor.
+// Users must be ordered by name and id
+func NewUsersPage(users []*User, cursor *Cursor, limit int) *UsersPage {
+
+	hasMore := len(users) > limit
+	if hasMore {
+		if cursor != nil && cursor.Backward {
+			users = users[1:]
+		} else {
+			users = users[:limit]
+		}
+	}
+
+	page := &UsersPage{Users: users}
+	if len(users) == 0 {
+		return page
+	}
+
+	hasPrev := cursor != nil && (!cursor.Backward || hasMore)
+	hasNext := hasMore || (cursor != nil && cursor.Backward)
+
+	if hasPrev {
+		first := users[0]
+		page.PrevCursor = (&Cursor{Name: first.Name, ID: first.ID, Backward: true}).Encode()
+	}
+
+	if hasNext {
+		last := users[len(users)-1]
+		page.NextCursor = (&Cursor{Name: last.Name, ID: last.ID}).Encode()
+	}
+
+	return page
+}
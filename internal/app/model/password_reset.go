@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// PasswordReset is request to reset password of user, only hash
+// of the token sent to user is stored, token can be used once
+type PasswordReset struct {
+	ID        int
+	UserID    int
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// PasswordForgotPage ...
+type PasswordForgotPage struct {
+	Sent bool
+}
+
+// PasswordResetPage ...
+type PasswordResetPage struct {
+	Token string
+}
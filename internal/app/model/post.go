@@ -0,0 +1,80 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// MaxPostLength is the max number of characters in post text
+const MaxPostLength = 10000
+
+var (
+	// ErrEmptyPost ...
+	ErrEmptyPost = errors.New("Post must not be empty")
+
+	// ErrPostTooLong ...
+	ErrPostTooLong = errors.New("Post is too long")
+)
+
+// Post is a record on user wall
+type Post struct {
+	ID            int       `json:"id"`
+	AuthorID      int       `json:"author_id"`
+	AuthorName    string    `json:"author_name,omitempty"`
+	AuthorSurname string    `json:"author_surname,omitempty"`
+	Text          string    `json:"text"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Validate ...
+func (p *Post) Validate() error {
+
+	p.Text = strings.TrimSpace(p.Text)
+
+	switch {
+	case len(p.Text) == 0:
+		return ErrEmptyPost
+	case len([]rune(p.Text)) > MaxPostLength:
+		return ErrPostTooLong
+	}
+
+	return nil
+}
+
+// Wall ...
+type Wall struct {
+	User
+	Posts      []*Post
+	CurrUserID int
+	NextPage   string
+}
+
+// Feed ...
+type Feed struct {
+	Posts      []*Post
+	CurrUserID int
+	NextPage   string
+}
+
+// PostsPage is a page of posts ordered from newer to older
+type PostsPage struct {
+	Posts []*Post `json:"posts"`
+	// NextBefore is id to pass as before parameter
+	// to get the next page, zero when it is the last one
+	NextBefore int `json:"next_before,omitempty"`
+}
+
+// NewPostsPage builds page from posts selected with one extra row
+// which tells whether there are older posts
+func NewPostsPage(posts []*Post, limit int) *PostsPage {
+
+	page := &PostsPage{Posts: posts}
+	if len(posts) > limit {
+		page.Posts = posts[:limit]
+		page.NextBefore = page.Posts[limit-1].ID
+	}
+
+	return page
+}
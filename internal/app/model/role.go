@@ -0,0 +1,52 @@
+package model
+
+import "errors"
+
+// roles of users, every next role is allowed everything the previous one is
+const (
+	RoleUser = "user"
+	// RoleModerator may view accounts and suspend ordinary users
+	RoleModerator = "moderator"
+	// RoleAdmin may also change roles, reset passwords and delete accounts
+	RoleAdmin = "admin"
+)
+
+// ErrUnknownRole ...
+var ErrUnknownRole = errors.New("Unknown role, must be user, moderator or admin")
+
+var roleRanks = map[string]int{
+	RoleUser:      0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+// ValidateRole ...
+func ValidateRole(role string) error {
+
+	if _, ok := roleRanks[role]; !ok {
+		return ErrUnknownRole
+	}
+
+	return nil
+}
+
+// HasRole tells whether user has role or a higher one
+func (u *User) HasRole(role string) bool {
+	return roleRanks[u.Role] >= roleRanks[role]
+}
+
+// CanManage tells whether user may suspend or otherwise manage account
+// of another user, moderators manage only ordinary users
+func (u *User) CanManage(other *User) bool {
+
+	if u.ID == other.ID || !u.HasRole(RoleModerator) {
+		return false
+	}
+
+	return u.Role == RoleAdmin || roleRanks[other.Role] < roleRanks[u.Role]
+}
+
+// Suspended tells whether user is forbidden to log in
+func (u *User) Suspended() bool {
+	return u.SuspendedAt != nil
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Session is server-side state of user session, ID is hash of the
+// token kept in cookie so that stored sessions can not be used to log in
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     int       `json:"-"`
+	Data       []byte    `json:"-"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// Sessions ...
+type Sessions struct {
+	Sessions   []*Session
+	CurrUserID int
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"html/template"
+	"time"
+)
+
+// TwoFactor is totp enrollment of user, secret is kept encrypted,
+// enrollment takes effect after user confirms it with a code
+type TwoFactor struct {
+	UserID          int
+	EncryptedSecret string
+	ConfirmedAt     *time.Time
+	LastUsedStep    int64
+	CreatedAt       time.Time
+}
+
+// Enabled ...
+func (t *TwoFactor) Enabled() bool {
+	return t != nil && t.ConfirmedAt != nil
+}
+
+// TwoFactorPage ...
+type TwoFactorPage struct {
+	Enabled       bool
+	Secret        string
+	URI           template.URL
+	QRCode        template.URL
+	RecoveryCodes []string
+	RecoveryLeft  int
+	CurrUserID    int
+}
+
+// TwoFactorLoginPage ...
+type TwoFactorLoginPage struct {
+	Error string
+}
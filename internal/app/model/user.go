@@ -1,11 +1,55 @@
 package model
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidEmail ...
+	ErrInvalidEmail = errors.New("Invalid email")
+
+	// ErrEmptyPassword ...
+	ErrEmptyPassword = errors.New("Password must not be empty")
+
+	// ErrInvalidAge ...
+	ErrInvalidAge = errors.New("Age must be positive number")
+
+	// ErrInvalidPasswordCost ...
+	ErrInvalidPasswordCost = errors.New("bcrypt cost must be between 4 and 31")
+)
+
+// DefaultPasswordCost is bcrypt cost passwords are encrypted with by default
+const DefaultPasswordCost = 12
+
+// passwordCost is bcrypt cost new passwords are encrypted with
+var passwordCost = DefaultPasswordCost
+
+var (
+	dummyPasswordMu sync.Mutex
+	dummyPassword   []byte
+)
+
+// SetPasswordCost sets bcrypt cost new passwords are encrypted with,
+// stored passwords with lower cost are rehashed on log in
+func SetPasswordCost(cost int) error {
+
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return ErrInvalidPasswordCost
+	}
+	passwordCost = cost
+
+	return nil
+}
 
 // User ...
 type User struct {
 	ID                int    `json:"id"`
-	Email             string `json:"email"`
+	Email             string `json:"email,omitempty"`
 	Name              string `json:"name"`
 	Surname           string `json:"surname"`
 	Age               int    `json:"age"`
@@ -13,12 +57,30 @@ type User struct {
 	Interests         string `json:"interests"`
 	City              string `json:"city"`
 	Password          string `json:"password,omitempty"`
-	EncryptedPassword string `json:""`
+	EncryptedPassword string `json:"-"`
+	// EmailVerifiedAt is nil until user opens link sent to email
+	EmailVerifiedAt *time.Time `json:"-"`
+	Role            string     `json:"-"`
+	// SuspendedAt is not nil while user is forbidden to log in
+	SuspendedAt *time.Time `json:"-"`
+	CreatedAt   time.Time  `json:"-"`
 }
 
 // Users ...
 type Users struct {
-	Users []*User
+	Users    []*User
+	Filter   *UserFilter
+	NextPage string
+	PrevPage string
+}
+
+// UsersSearch ...
+type UsersSearch struct {
+	Users     []*User
+	FirstName string
+	LastName  string
+	NextPage  string
+	PrevPage  string
 }
 
 // FriendsAndRequests ...
@@ -52,6 +114,49 @@ func (u *User) ComparePassword(password string) bool {
 	return bcrypt.CompareHashAndPassword([]byte(u.EncryptedPassword), []byte(password)) == nil
 }
 
+// CompareDummyPassword compares password with hash of current cost
+// nobody has, log ins to unknown emails take as long as log ins of users
+func CompareDummyPassword(password string) {
+
+	dummyPasswordMu.Lock()
+	if cost, err := bcrypt.Cost(dummyPassword); err != nil || cost != passwordCost {
+		dummyPassword, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), passwordCost)
+	}
+	hash := dummyPassword
+	dummyPasswordMu.Unlock()
+
+	bcrypt.CompareHashAndPassword(hash, []byte(password))
+}
+
+// PasswordNeedsRehash tells whether stored password
+// is encrypted with cost lower than current one
+func (u *User) PasswordNeedsRehash() bool {
+
+	cost, err := bcrypt.Cost([]byte(u.EncryptedPassword))
+
+	return err == nil && cost < passwordCost
+}
+
+// EmailVerified tells whether user has confirmed email
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// Validate checks fields required to create user
+func (u *User) Validate() error {
+
+	switch {
+	case !strings.Contains(u.Email, "@"):
+		return ErrInvalidEmail
+	case len(u.Password) == 0 && len(u.EncryptedPassword) == 0:
+		return ErrEmptyPassword
+	case u.Age <= 0:
+		return ErrInvalidAge
+	}
+
+	return nil
+}
+
 // Sanitize ...
 func (u *User) Sanitize() {
 	u.Password = ""
@@ -59,7 +164,7 @@ func (u *User) Sanitize() {
 
 func encryptString(s string) (string, error) {
 
-	b, err := bcrypt.GenerateFromPassword([]byte(s), bcrypt.MinCost)
+	b, err := bcrypt.GenerateFromPassword([]byte(s), passwordCost)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepPeriod is how often memory backend drops buckets which are full again
+const sweepPeriod = time.Minute
+
+// Limit allows Requests per Period on average with bursts up to Burst
+// requests, Burst equal to zero means Requests
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+func (l Limit) burst() float64 {
+
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+
+	return float64(l.Requests)
+}
+
+// rate returns number of tokens added to bucket per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Result tells whether request is allowed and describes state of bucket after it
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is time until the next request is allowed, it is zero when allowed
+	RetryAfter time.Duration
+	// Reset is time until bucket is full again
+	Reset time.Duration
+}
+
+// Backend keeps token buckets of keys. Memory backend is enough for
+// a single server, several servers need a shared one.
+// Implementations must be safe for concurrent use
+type Backend interface {
+	// Take takes token for request from bucket of key
+	Take(key string, limit Limit, now time.Time) (Result, error)
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+// MemoryBackend is a Backend keeping buckets in process memory
+type MemoryBackend struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryBackend ...
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Take ...
+func (b *MemoryBackend) Take(key string, limit Limit, now time.Time) (Result, error) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Sub(b.lastSweep) >= sweepPeriod {
+		b.sweep(now)
+	}
+
+	burst, rate := limit.burst(), limit.rate()
+
+	bk, ok := b.buckets[key]
+	if !ok {
+		bk = &bucket{tokens: burst, updated: now}
+		b.buckets[key] = bk
+	}
+
+	if elapsed := now.Sub(bk.updated).Seconds(); elapsed > 0 {
+		bk.tokens = math.Min(burst, bk.tokens+elapsed*rate)
+		bk.updated = now
+	}
+
+	res := Result{Limit: int(burst)}
+	if bk.tokens >= 1 {
+		bk.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - bk.tokens) / rate)
+	}
+
+	res.Remaining = int(bk.tokens)
+	res.Reset = seconds((burst - bk.tokens) / rate)
+	bk.full = now.Add(res.Reset)
+
+	return res, nil
+}
+
+// sweep drops buckets which are full again, they are
+// the same as buckets of keys seen for the first time
+func (b *MemoryBackend) sweep(now time.Time) {
+
+	for key, bk := range b.buckets {
+		if !bk.full.After(now) {
+			delete(b.buckets, key)
+		}
+	}
+	b.lastSweep = now
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
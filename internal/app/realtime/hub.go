@@ -0,0 +1,202 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+)
+
+// Hub delivers events published for user to all subscriptions
+// of the user, for example to every open browser tab. The latest
+// events of every user are kept to be replayed to reconnected clients
+// until no events are published for the user during history ttl.
+// It is safe for concurrent use
+type Hub struct {
+	mu            sync.Mutex
+	bufferSize    int
+	replaySize    int
+	historyTTL    time.Duration
+	lastID        int
+	subscriptions map[int]map[*Subscription]struct{}
+	histories     map[int]*history
+}
+
+// history is the latest events of user from older to newer
+type history struct {
+	lastID    int
+	events    []*model.Event
+	updatedAt time.Time
+}
+
+// NewHub returns hub buffering at most bufferSize undelivered events
+// per subscription and keeping replaySize latest events per user
+// for historyTTL after the latest of them
+func NewHub(bufferSize, replaySize int, historyTTL time.Duration) *Hub {
+	return &Hub{
+		bufferSize:    bufferSize,
+		replaySize:    replaySize,
+		historyTTL:    historyTTL,
+		subscriptions: make(map[int]map[*Subscription]struct{}),
+		histories:     make(map[int]*history),
+	}
+}
+
+// Subscribe returns new subscription to events of user,
+// it must be cancelled with Unsubscribe when not needed
+func (h *Hub) Subscribe(userID int) *Subscription {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.subscribe(userID)
+}
+
+// SubscribeAfter returns new subscription to events of user together
+// with kept events published after event with lastEventID, so that
+// reconnected client misses no events unless it was away too long
+func (h *Hub) SubscribeAfter(userID, lastEventID int) (*Subscription, []*model.Event) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	missed := make([]*model.Event, 0)
+	if hist, ok := h.histories[userID]; ok && lastEventID <= hist.lastID {
+		for _, event := range hist.events {
+			if event.ID > lastEventID {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	return h.subscribe(userID), missed
+}
+
+// LastEventID returns id of the latest published event,
+// events published for user later have greater ids
+func (h *Hub) LastEventID() int {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.lastID
+}
+
+// subscribe must be called with mu locked
+func (h *Hub) subscribe(userID int) *Subscription {
+
+	sub := &Subscription{
+		UserID: userID,
+		events: make(chan *model.Event, h.bufferSize),
+		done:   make(chan struct{}),
+	}
+
+	if _, ok := h.subscriptions[userID]; !ok {
+		h.subscriptions[userID] = make(map[*Subscription]struct{})
+	}
+	h.subscriptions[userID][sub] = struct{}{}
+
+	return sub
+}
+
+// Unsubscribe ...
+func (h *Hub) Unsubscribe(sub *Subscription) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(sub)
+}
+
+// Publish assigns the next id of events to copy of event, keeps it for
+// replay and delivers it to subscriptions of user without blocking.
+// Ids are shared by all users, so that they keep increasing for user
+// whose history was swept, and client reconnected with id from before
+// gets all events published since.
+// Subscription which buffer is full is dropped, so that slow client
+// does not hold events of others, the client may subscribe again
+func (h *Hub) Publish(userID int, e *model.Event) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hist, ok := h.histories[userID]
+	if !ok {
+		hist = &history{events: make([]*model.Event, 0, h.replaySize)}
+		h.histories[userID] = hist
+	}
+
+	event := *e
+	h.lastID++
+	event.ID = h.lastID
+	hist.lastID = event.ID
+	hist.updatedAt = time.Now()
+	if h.replaySize > 0 {
+		if len(hist.events) == h.replaySize {
+			hist.events = append(hist.events[:0], hist.events[1:]...)
+		}
+		hist.events = append(hist.events, &event)
+	}
+
+	for sub := range h.subscriptions[userID] {
+		select {
+		case sub.events <- &event:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// Sweep forgets events of users for whom nothing was published
+// during history ttl before now and returns the number of such users
+func (h *Hub) Sweep(now time.Time) int {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := 0
+	for userID, hist := range h.histories {
+		if now.Sub(hist.updatedAt) >= h.historyTTL {
+			delete(h.histories, userID)
+			n++
+		}
+	}
+
+	return n
+}
+
+// remove must be called with mu locked
+func (h *Hub) remove(sub *Subscription) {
+
+	subs, ok := h.subscriptions[sub.UserID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscriptions, sub.UserID)
+	}
+	close(sub.done)
+}
+
+// Subscription receives events of single user
+type Subscription struct {
+	UserID int
+	events chan *model.Event
+	done   chan struct{}
+}
+
+// Events returns channel of events of subscription
+func (s *Subscription) Events() <-chan *model.Event {
+	return s.events
+}
+
+// Done returns channel closed when subscription is cancelled
+// or dropped because events were not read in time
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
@@ -0,0 +1,198 @@
+package realtime
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+)
+
+// receive returns event waiting in subscription, it fails
+// when there is none because Publish delivers synchronously
+func receive(t *testing.T, sub *Subscription) *model.Event {
+
+	t.Helper()
+
+	select {
+	case event := <-sub.Events():
+		return event
+	default:
+		t.Fatal("no event is delivered")
+		return nil
+	}
+}
+
+func assertNoEvent(t *testing.T, sub *Subscription) {
+
+	t.Helper()
+
+	select {
+	case event := <-sub.Events():
+		t.Fatalf("unexpected event %+v", event)
+	default:
+	}
+}
+
+func assertDone(t *testing.T, sub *Subscription, done bool) {
+
+	t.Helper()
+
+	select {
+	case <-sub.Done():
+		if !done {
+			t.Fatal("subscription is cancelled")
+		}
+	default:
+		if done {
+			t.Fatal("subscription is not cancelled")
+		}
+	}
+}
+
+func TestHub_Publish(t *testing.T) {
+
+	h := NewHub(10, 10, time.Hour)
+	first := h.Subscribe(1)
+	second := h.Subscribe(1)
+	another := h.Subscribe(2)
+
+	h.Publish(1, &model.Event{Type: model.EventFriendRequest, UserID: 3})
+
+	for _, sub := range []*Subscription{first, second} {
+		event := receive(t, sub)
+		if event.ID != 1 || event.Type != model.EventFriendRequest || event.UserID != 3 {
+			t.Fatalf("unexpected event %+v", event)
+		}
+	}
+	assertNoEvent(t, another)
+
+	if id := h.LastEventID(); id != 1 {
+		t.Fatalf("expected last event id 1, got %d", id)
+	}
+}
+
+func TestHub_PublishDropsFullSubscription(t *testing.T) {
+
+	h := NewHub(1, 10, time.Hour)
+	slow := h.Subscribe(1)
+	fast := h.Subscribe(1)
+
+	h.Publish(1, &model.Event{Type: model.EventProfileUpdated})
+	receive(t, fast)
+	h.Publish(1, &model.Event{Type: model.EventProfileUpdated})
+
+	assertDone(t, slow, true)
+	assertDone(t, fast, false)
+	if event := receive(t, fast); event.ID != 2 {
+		t.Fatalf("expected event 2, got %d", event.ID)
+	}
+
+	// dropped subscription keeps buffered event and gets no new ones
+	receive(t, slow)
+	h.Publish(1, &model.Event{Type: model.EventProfileUpdated})
+	assertNoEvent(t, slow)
+	receive(t, fast)
+}
+
+func TestHub_Unsubscribe(t *testing.T) {
+
+	h := NewHub(10, 10, time.Hour)
+	sub := h.Subscribe(1)
+	other := h.Subscribe(1)
+
+	h.Unsubscribe(sub)
+	assertDone(t, sub, true)
+
+	// cancelled subscription may be unsubscribed again
+	h.Unsubscribe(sub)
+
+	h.Publish(1, &model.Event{Type: model.EventProfileUpdated})
+	assertNoEvent(t, sub)
+	receive(t, other)
+
+	h.Unsubscribe(other)
+	if _, ok := h.subscriptions[1]; ok {
+		t.Fatal("subscriptions of user are kept after the last one is cancelled")
+	}
+}
+
+func TestHub_SubscribeAfter(t *testing.T) {
+
+	testCases := []struct {
+		name        string
+		lastEventID int
+		expectedIDs []int
+	}{
+		{
+			name:        "missed events",
+			lastEventID: 3,
+			expectedIDs: []int{4, 5},
+		},
+		{
+			name:        "nothing missed",
+			lastEventID: 6,
+			expectedIDs: []int{},
+		},
+		{
+			name:        "older than buffer",
+			lastEventID: 1,
+			expectedIDs: []int{3, 4, 5},
+		},
+		{
+			name:        "newer than published",
+			lastEventID: 100,
+			expectedIDs: []int{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHub(10, 3, time.Hour)
+			for i := 0; i < 5; i++ {
+				h.Publish(1, &model.Event{Type: model.EventProfileUpdated})
+			}
+			h.Publish(2, &model.Event{Type: model.EventProfileUpdated})
+
+			sub, missed := h.SubscribeAfter(1, tc.lastEventID)
+
+			ids := make([]int, 0, len(missed))
+			for _, event := range missed {
+				ids = append(ids, event.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tc.expectedIDs) {
+				t.Fatalf("expected events %v, got %v", tc.expectedIDs, ids)
+			}
+
+			h.Publish(1, &model.Event{Type: model.EventProfileUpdated})
+			if event := receive(t, sub); event.ID != 7 {
+				t.Fatalf("expected event 7 after replay, got %d", event.ID)
+			}
+		})
+	}
+}
+
+func TestHub_Sweep(t *testing.T) {
+
+	h := NewHub(10, 10, time.Hour)
+	h.Publish(1, &model.Event{Type: model.EventProfileUpdated})
+	lastEventID := h.LastEventID()
+
+	if n := h.Sweep(time.Now()); n != 0 {
+		t.Fatalf("swept %d active users", n)
+	}
+	if n := h.Sweep(time.Now().Add(time.Hour)); n != 1 {
+		t.Fatalf("expected 1 inactive user swept, got %d", n)
+	}
+	if _, missed := h.SubscribeAfter(1, 0); len(missed) != 0 {
+		t.Fatalf("swept events are replayed: %v", missed)
+	}
+
+	// ids keep increasing, so that client reconnected with id
+	// from before sweep gets events published since
+	h.Publish(1, &model.Event{Type: model.EventFriendRequest})
+	_, missed := h.SubscribeAfter(1, lastEventID)
+	if len(missed) != 1 || missed[0].ID <= lastEventID || missed[0].Type != model.EventFriendRequest {
+		t.Fatalf("unexpected events after sweep %v", missed)
+	}
+}
@@ -0,0 +1,202 @@
+package sessionstore
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+const (
+	// UserIDKey is the key of session values holding id of logged in user,
+	// sessions are listed and revoked per user by it
+	UserIDKey = "user_id"
+	// touchPeriod is how often last seen time of session is updated
+	touchPeriod        = time.Minute
+	maxUserAgentLength = 255
+)
+
+// serializer encodes session values the same way cookie store does
+var serializer = securecookie.GobEncoder{}
+
+// Store keeps sessions in store repository, cookie only holds
+// signed random token, so that session can be revoked on server
+type Store struct {
+	repo    store.SessionRepository
+	codecs  []securecookie.Codec
+	Options *sessions.Options
+}
+
+// NewStore returns store of sessions living for maxAge seconds
+// since they were used last time, keyPairs sign session cookie
+// like in sessions.NewCookieStore
+func NewStore(repo store.SessionRepository, maxAge int, keyPairs ...[]byte) *Store {
+
+	codecs := securecookie.CodecsFromPairs(keyPairs...)
+	for _, codec := range codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(maxAge)
+		}
+	}
+
+	return &Store{
+		repo:   repo,
+		codecs: codecs,
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   maxAge,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		},
+	}
+}
+
+// ID returns id session is stored with, it is safe to show
+// as opposed to session.ID which is the token from cookie
+func ID(session *sessions.Session) string {
+	return hashToken(session.ID)
+}
+
+// Get returns session cached for request or loads it
+func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads session named by cookie of request, new session is
+// returned if there is no cookie or session was revoked or expired
+func (s *Store) New(r *http.Request, name string) (*sessions.Session, error) {
+
+	session := sessions.NewSession(s, name)
+	options := *s.Options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var token string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &token, s.codecs...); err != nil {
+		// cookie signed with another key or expired, user logs in again
+		return session, nil
+	}
+
+	stored, err := s.repo.Find(hashToken(token))
+	if err == store.ErrRecordNotFound {
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+
+	now := time.Now().UTC()
+	if !stored.ExpiresAt.After(now) {
+		return session, nil
+	}
+
+	if err := serializer.Deserialize(stored.Data, &session.Values); err != nil {
+		return session, err
+	}
+	session.ID = token
+	session.IsNew = false
+
+	if now.Sub(stored.LastSeenAt) >= touchPeriod {
+		s.describe(stored, r, now, session.Options.MaxAge)
+		if err := s.repo.Update(stored); err != nil && err != store.ErrRecordNotFound {
+			return session, err
+		}
+	}
+
+	return session, nil
+}
+
+// Save stores session and sets cookie with its token,
+// session with negative MaxAge is deleted
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.repo.Delete(hashToken(session.ID)); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	data, err := serializer.Serialize(session.Values)
+	if err != nil {
+		return err
+	}
+
+	stored := &model.Session{Data: data}
+	stored.UserID, _ = session.Values[UserIDKey].(int)
+	s.describe(stored, r, time.Now().UTC(), session.Options.MaxAge)
+
+	if session.ID != "" {
+		stored.ID = hashToken(session.ID)
+		err = s.repo.Update(stored)
+	}
+	if session.ID == "" || err == store.ErrRecordNotFound {
+		// session revoked while request was served gets new token
+		session.ID = newToken()
+		stored.ID = hashToken(session.ID)
+		err = s.repo.Create(stored)
+	}
+	if err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+
+	return nil
+}
+
+// Cleanup deletes expired sessions and returns their number
+func (s *Store) Cleanup() (int, error) {
+	return s.repo.DeleteExpired(time.Now().UTC())
+}
+
+// describe sets client of session and prolongs it since now
+func (s *Store) describe(stored *model.Session, r *http.Request, now time.Time, maxAge int) {
+
+	stored.UserAgent = r.UserAgent()
+	if len(stored.UserAgent) > maxUserAgentLength {
+		stored.UserAgent = stored.UserAgent[:maxUserAgentLength]
+	}
+	stored.IP = clientIP(r)
+	stored.LastSeenAt = now.Truncate(time.Second)
+	stored.ExpiresAt = stored.LastSeenAt.Add(time.Duration(maxAge) * time.Second)
+}
+
+func clientIP(r *http.Request) string {
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func newToken() string {
+	return strings.TrimRight(base32.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(32)), "=")
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
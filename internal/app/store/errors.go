@@ -8,4 +8,16 @@ var (
 
 	// ErrFriendRequestWasAlreadySent ...
 	ErrFriendRequestWasAlreadySent = errors.New("Friend request was already sent")
+
+	// ErrEmailAlreadyExists ...
+	ErrEmailAlreadyExists = errors.New("User with such email already exists")
+
+	// ErrTwoFactorAlreadyEnabled ...
+	ErrTwoFactorAlreadyEnabled = errors.New("Two-factor authentication is already enabled")
+
+	// ErrIdentityAlreadyLinked ...
+	ErrIdentityAlreadyLinked = errors.New("External account is already linked to user")
+
+	// ErrLoginAttemptsExceeded ...
+	ErrLoginAttemptsExceeded = errors.New("Too many failed log ins")
 )
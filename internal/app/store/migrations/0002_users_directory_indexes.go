@@ -0,0 +1,18 @@
+package migrations
+
+func init() {
+	register(shardLocal(&Migration{
+		Version: 2,
+		Name:    "users_directory_indexes",
+		Up: []string{
+			`CREATE INDEX users_name_id_idx ON users (name, id)`,
+			`CREATE INDEX users_city_name_id_idx ON users (city, name, id)`,
+			`CREATE INDEX users_sex_age_idx ON users (sex, age)`,
+		},
+		Down: []string{
+			`DROP INDEX users_sex_age_idx ON users`,
+			`DROP INDEX users_city_name_id_idx ON users`,
+			`DROP INDEX users_name_id_idx ON users`,
+		},
+	}))
+}
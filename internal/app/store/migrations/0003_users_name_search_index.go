@@ -0,0 +1,14 @@
+package migrations
+
+func init() {
+	register(shardLocal(&Migration{
+		Version: 3,
+		Name:    "users_name_search_index",
+		Up: []string{
+			`CREATE INDEX users_name_surname_id_idx ON users (name, surname, id)`,
+		},
+		Down: []string{
+			`DROP INDEX users_name_surname_id_idx ON users`,
+		},
+	}))
+}
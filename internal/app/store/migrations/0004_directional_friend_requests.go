@@ -0,0 +1,35 @@
+package migrations
+
+// Friendship is still stored as two symmetric rows, so that friends of a user
+// are selected by user_id alone, but every row now records who sent the request.
+// Pending requests created before this migration do not tell who sent them,
+// the user who signed up earlier is considered the requester.
+func init() {
+	register(shardLocal(&Migration{
+		Version: 4,
+		Name:    "directional_friend_requests",
+		Up: []string{
+			`ALTER TABLE friends
+				ADD COLUMN requester_id INT NULL,
+				ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'pending',
+				ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP`,
+			`UPDATE friends
+			 SET status = IF(is_accepted, 'accepted', 'pending'),
+			     requester_id = LEAST(user_id, friend_id)`,
+			`ALTER TABLE friends
+				MODIFY COLUMN requester_id INT NOT NULL,
+				DROP COLUMN is_accepted,
+				ADD INDEX friends_user_id_status_idx (user_id, status)`,
+		},
+		Down: []string{
+			`ALTER TABLE friends
+				ADD COLUMN is_accepted BOOLEAN NOT NULL DEFAULT FALSE`,
+			`UPDATE friends SET is_accepted = (status = 'accepted')`,
+			`ALTER TABLE friends
+				DROP INDEX friends_user_id_status_idx,
+				DROP COLUMN created_at,
+				DROP COLUMN status,
+				DROP COLUMN requester_id`,
+		},
+	}))
+}
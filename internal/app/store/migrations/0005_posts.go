@@ -0,0 +1,25 @@
+package migrations
+
+func init() {
+	register(&Migration{
+		Version: 5,
+		Name:    "posts",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS posts (
+				id INT NOT NULL AUTO_INCREMENT,
+				author_id INT NOT NULL,
+				text TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (id),
+				INDEX posts_author_id_id_idx (author_id, id),
+				FOREIGN KEY (author_id)
+					REFERENCES users (id)
+					ON UPDATE RESTRICT ON DELETE CASCADE
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS posts`,
+		},
+	})
+}
@@ -0,0 +1,28 @@
+package migrations
+
+func init() {
+	register(&Migration{
+		Version: 6,
+		Name:    "activities",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS activities (
+				id INT NOT NULL AUTO_INCREMENT,
+				type VARCHAR(32) NOT NULL,
+				actor_id INT NOT NULL,
+				subject_id INT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (id),
+				INDEX activities_actor_id_id_idx (actor_id, id),
+				FOREIGN KEY (actor_id)
+					REFERENCES users (id)
+					ON UPDATE RESTRICT ON DELETE CASCADE,
+				FOREIGN KEY (subject_id)
+					REFERENCES users (id)
+					ON UPDATE RESTRICT ON DELETE CASCADE
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS activities`,
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+func init() {
+	register(&Migration{
+		Version: 7,
+		Name:    "messages",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS messages (
+				id INT NOT NULL AUTO_INCREMENT,
+				from_id INT NOT NULL,
+				to_id INT NOT NULL,
+				text TEXT NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				read_at TIMESTAMP NULL,
+				PRIMARY KEY (id),
+				INDEX messages_from_id_to_id_id_idx (from_id, to_id, id),
+				INDEX messages_to_id_from_id_id_idx (to_id, from_id, id),
+				INDEX messages_to_id_read_at_idx (to_id, read_at),
+				FOREIGN KEY (from_id)
+					REFERENCES users (id)
+					ON UPDATE RESTRICT ON DELETE CASCADE,
+				FOREIGN KEY (to_id)
+					REFERENCES users (id)
+					ON UPDATE RESTRICT ON DELETE CASCADE
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS messages`,
+		},
+	})
+}
@@ -0,0 +1,68 @@
+package migrations
+
+// Users and their friendships may be partitioned across several shard
+// databases while posts, activities and messages stay in the main database.
+// Ids and emails of all users are registered in user_ids table of the main
+// database, it generates ids of new users and keeps emails unique across
+// shards. Foreign keys to users can not be kept in such layout and are dropped,
+// shard databases hold only users and friends, so only foreign keys of friends
+// are dropped there.
+func init() {
+	register(&Migration{
+		Version: 8,
+		Name:    "user_shards",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS user_ids (
+				id INT NOT NULL AUTO_INCREMENT,
+				email VARCHAR(100) NOT NULL,
+				PRIMARY KEY (id),
+				UNIQUE INDEX user_ids_email_idx (email)
+			)`,
+			`INSERT INTO user_ids (id, email)
+			 SELECT id, email FROM users`,
+			`ALTER TABLE friends
+				DROP FOREIGN KEY friends_ibfk_1,
+				DROP FOREIGN KEY friends_ibfk_2`,
+			`ALTER TABLE posts DROP FOREIGN KEY posts_ibfk_1`,
+			`ALTER TABLE activities
+				DROP FOREIGN KEY activities_ibfk_1,
+				DROP FOREIGN KEY activities_ibfk_2`,
+			`ALTER TABLE messages
+				DROP FOREIGN KEY messages_ibfk_1,
+				DROP FOREIGN KEY messages_ibfk_2`,
+		},
+		Down: []string{
+			`ALTER TABLE messages
+				ADD CONSTRAINT messages_ibfk_1 FOREIGN KEY (from_id)
+					REFERENCES users (id) ON UPDATE RESTRICT ON DELETE CASCADE,
+				ADD CONSTRAINT messages_ibfk_2 FOREIGN KEY (to_id)
+					REFERENCES users (id) ON UPDATE RESTRICT ON DELETE CASCADE`,
+			`ALTER TABLE activities
+				ADD CONSTRAINT activities_ibfk_1 FOREIGN KEY (actor_id)
+					REFERENCES users (id) ON UPDATE RESTRICT ON DELETE CASCADE,
+				ADD CONSTRAINT activities_ibfk_2 FOREIGN KEY (subject_id)
+					REFERENCES users (id) ON UPDATE RESTRICT ON DELETE CASCADE`,
+			`ALTER TABLE posts
+				ADD CONSTRAINT posts_ibfk_1 FOREIGN KEY (author_id)
+					REFERENCES users (id) ON UPDATE RESTRICT ON DELETE CASCADE`,
+			`ALTER TABLE friends
+				ADD CONSTRAINT friends_ibfk_1 FOREIGN KEY (user_id)
+					REFERENCES users (id) ON UPDATE RESTRICT ON DELETE CASCADE,
+				ADD CONSTRAINT friends_ibfk_2 FOREIGN KEY (friend_id)
+					REFERENCES users (id) ON UPDATE RESTRICT ON DELETE CASCADE`,
+			`DROP TABLE IF EXISTS user_ids`,
+		},
+		ShardUp: []string{
+			`ALTER TABLE friends
+				DROP FOREIGN KEY friends_ibfk_1,
+				DROP FOREIGN KEY friends_ibfk_2`,
+		},
+		ShardDown: []string{
+			`ALTER TABLE friends
+				ADD CONSTRAINT friends_ibfk_1 FOREIGN KEY (user_id)
+					REFERENCES users (id) ON UPDATE RESTRICT ON DELETE CASCADE,
+				ADD CONSTRAINT friends_ibfk_2 FOREIGN KEY (friend_id)
+					REFERENCES users (id) ON UPDATE RESTRICT ON DELETE CASCADE`,
+		},
+	})
+}
@@ -0,0 +1,25 @@
+package migrations
+
+func init() {
+	register(&Migration{
+		Version: 9,
+		Name:    "notifications",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS notifications (
+				id INT NOT NULL AUTO_INCREMENT,
+				user_id INT NOT NULL,
+				type VARCHAR(32) NOT NULL,
+				actor_id INT NULL,
+				text TEXT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				read_at TIMESTAMP NULL,
+				PRIMARY KEY (id),
+				INDEX notifications_user_id_id_idx (user_id, id),
+				INDEX notifications_user_id_read_at_idx (user_id, read_at)
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS notifications`,
+		},
+	})
+}
@@ -0,0 +1,26 @@
+package migrations
+
+func init() {
+	register(&Migration{
+		Version: 10,
+		Name:    "sessions",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS sessions (
+				id CHAR(64) NOT NULL,
+				user_id INT NULL,
+				data BLOB NOT NULL,
+				user_agent VARCHAR(255) NOT NULL DEFAULT '',
+				ip VARCHAR(45) NOT NULL DEFAULT '',
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (id),
+				INDEX sessions_user_id_idx (user_id),
+				INDEX sessions_expires_at_idx (expires_at)
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS sessions`,
+		},
+	})
+}
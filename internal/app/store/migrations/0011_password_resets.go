@@ -0,0 +1,24 @@
+package migrations
+
+func init() {
+	register(&Migration{
+		Version: 11,
+		Name:    "password_resets",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS password_resets (
+				id INT NOT NULL AUTO_INCREMENT,
+				user_id INT NOT NULL,
+				token_hash CHAR(64) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				used_at TIMESTAMP NULL,
+				PRIMARY KEY (id),
+				UNIQUE INDEX password_resets_token_hash_idx (token_hash),
+				INDEX password_resets_user_id_idx (user_id)
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS password_resets`,
+		},
+	})
+}
@@ -0,0 +1,20 @@
+package migrations
+
+// Accounts created before verification was introduced are treated as verified.
+func init() {
+	register(shardLocal(&Migration{
+		Version: 12,
+		Name:    "email_verification",
+		Up: []string{
+			`ALTER TABLE users
+				ADD COLUMN email_verified_at TIMESTAMP NULL,
+				ADD COLUMN verification_sent_at TIMESTAMP NULL`,
+			`UPDATE users SET email_verified_at = CURRENT_TIMESTAMP`,
+		},
+		Down: []string{
+			`ALTER TABLE users
+				DROP COLUMN email_verified_at,
+				DROP COLUMN verification_sent_at`,
+		},
+	}))
+}
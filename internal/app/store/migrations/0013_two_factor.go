@@ -0,0 +1,30 @@
+package migrations
+
+func init() {
+	register(&Migration{
+		Version: 13,
+		Name:    "two_factor",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS two_factor (
+				user_id INT NOT NULL,
+				secret VARCHAR(255) NOT NULL,
+				confirmed_at TIMESTAMP NULL,
+				last_used_step BIGINT NOT NULL DEFAULT 0,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (user_id)
+			)`,
+			`CREATE TABLE IF NOT EXISTS recovery_codes (
+				id INT NOT NULL AUTO_INCREMENT,
+				user_id INT NOT NULL,
+				code_hash CHAR(64) NOT NULL,
+				used_at TIMESTAMP NULL,
+				PRIMARY KEY (id),
+				UNIQUE INDEX recovery_codes_user_id_code_hash_idx (user_id, code_hash)
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS recovery_codes`,
+			`DROP TABLE IF EXISTS two_factor`,
+		},
+	})
+}
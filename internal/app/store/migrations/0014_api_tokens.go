@@ -0,0 +1,25 @@
+package migrations
+
+func init() {
+	register(&Migration{
+		Version: 14,
+		Name:    "api_tokens",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS api_tokens (
+				id INT NOT NULL AUTO_INCREMENT,
+				user_id INT NOT NULL,
+				name VARCHAR(100) NOT NULL,
+				scopes VARCHAR(255) NOT NULL,
+				token_hash CHAR(64) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				last_used_at TIMESTAMP NULL,
+				PRIMARY KEY (id),
+				UNIQUE INDEX api_tokens_token_hash_idx (token_hash),
+				INDEX api_tokens_user_id_idx (user_id)
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS api_tokens`,
+		},
+	})
+}
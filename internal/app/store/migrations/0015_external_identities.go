@@ -0,0 +1,24 @@
+package migrations
+
+func init() {
+	register(&Migration{
+		Version: 15,
+		Name:    "external_identities",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS external_identities (
+				id INT NOT NULL AUTO_INCREMENT,
+				user_id INT NOT NULL,
+				provider VARCHAR(50) NOT NULL,
+				subject VARCHAR(255) NOT NULL,
+				email VARCHAR(255) NOT NULL DEFAULT '',
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (id),
+				UNIQUE INDEX external_identities_provider_subject_idx (provider, subject),
+				INDEX external_identities_user_id_idx (user_id)
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS external_identities`,
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package migrations
+
+func init() {
+	register(&Migration{
+		Version: 16,
+		Name:    "login_attempts",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS login_attempts (
+				attempt_key VARCHAR(255) NOT NULL,
+				failures INT NOT NULL DEFAULT 0,
+				last_failure_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (attempt_key),
+				INDEX login_attempts_last_failure_at_idx (last_failure_at)
+			)`,
+			`CREATE TABLE IF NOT EXISTS audit_events (
+				id INT NOT NULL AUTO_INCREMENT,
+				type VARCHAR(50) NOT NULL,
+				user_id INT NOT NULL DEFAULT 0,
+				ip VARCHAR(45) NOT NULL DEFAULT '',
+				details VARCHAR(1000) NOT NULL DEFAULT '',
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (id),
+				INDEX audit_events_created_at_idx (created_at)
+			)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS audit_events`,
+			`DROP TABLE IF EXISTS login_attempts`,
+		},
+	})
+}
@@ -0,0 +1,23 @@
+package migrations
+
+// Roles and suspensions are kept in registry of users of the main database,
+// so they are checked without shards and listed for administrators in
+// order of sign up. Users signed up before get time of the migration.
+func init() {
+	register(&Migration{
+		Version: 17,
+		Name:    "roles",
+		Up: []string{
+			`ALTER TABLE user_ids
+				ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user',
+				ADD COLUMN suspended_at TIMESTAMP NULL,
+				ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP`,
+		},
+		Down: []string{
+			`ALTER TABLE user_ids
+				DROP COLUMN role,
+				DROP COLUMN suspended_at,
+				DROP COLUMN created_at`,
+		},
+	})
+}
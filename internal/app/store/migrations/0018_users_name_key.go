@@ -0,0 +1,27 @@
+package migrations
+
+// Users are listed in order of name_key, it is the name compared byte by byte.
+// Pages merged from several shards are ordered the same way in the application,
+// which can not reproduce case insensitive collation of the database exactly.
+func init() {
+	register(shardLocal(&Migration{
+		Version: 18,
+		Name:    "users_name_key",
+		Up: []string{
+			`ALTER TABLE users
+				ADD COLUMN name_key VARBINARY(400) AS (CAST(name AS BINARY)) STORED,
+				ADD INDEX users_name_key_id_idx (name_key, id),
+				ADD INDEX users_city_name_key_id_idx (city, name_key, id),
+				DROP INDEX users_name_id_idx,
+				DROP INDEX users_city_name_id_idx`,
+		},
+		Down: []string{
+			`ALTER TABLE users
+				ADD INDEX users_name_id_idx (name, id),
+				ADD INDEX users_city_name_id_idx (city, name, id),
+				DROP INDEX users_city_name_key_id_idx,
+				DROP INDEX users_name_key_id_idx,
+				DROP COLUMN name_key`,
+		},
+	}))
+}
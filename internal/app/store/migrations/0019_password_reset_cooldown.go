@@ -0,0 +1,16 @@
+package migrations
+
+// Password reset emails are sent to user at most once in cooldown,
+// time of the last one is kept in registry of users of the main database.
+func init() {
+	register(&Migration{
+		Version: 19,
+		Name:    "password_reset_cooldown",
+		Up: []string{
+			`ALTER TABLE user_ids ADD COLUMN password_reset_sent_at TIMESTAMP NULL`,
+		},
+		Down: []string{
+			`ALTER TABLE user_ids DROP COLUMN password_reset_sent_at`,
+		},
+	})
+}
@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+var (
+	migrationNameRe   = regexp.MustCompile(`[^a-z0-9]+`)
+	migrationTemplate = template.Must(template.New("migration").Parse(`package migrations
+
+func init() {
+	register(&Migration{
+		Version: {{.Version}},
+		Name:    "{{.Name}}",
+		Up: []string{
+			` + "``" + `,
+		},
+		Down: []string{
+			` + "``" + `,
+		},
+	})
+}
+`))
+)
+
+// Create writes skeleton of a new migration with the next version
+// into dir and returns path to the created file
+func Create(dir, name string) (string, error) {
+
+	name = strings.Trim(migrationNameRe.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", fmt.Errorf("migration name must contain letters or digits")
+	}
+
+	version := 1
+	if all := All(); len(all) != 0 {
+		version = all[len(all)-1].Version + 1
+	}
+
+	filePath := filepath.Join(dir, fmt.Sprintf("%04d_%s.go", version, name))
+	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if err := migrationTemplate.Execute(f, &Migration{Version: version, Name: name}); err != nil {
+		return "", err
+	}
+
+	return filePath, nil
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import "errors"
+
+var (
+	// ErrChecksumMismatch ...
+	ErrChecksumMismatch = errors.New("Migration was changed after it had been applied")
+
+	// ErrUnknownMigration ...
+	ErrUnknownMigration = errors.New("Database has migration unknown to application")
+
+	// ErrNothingToRollback ...
+	ErrNothingToRollback = errors.New("There are no applied migrations to roll back")
+)
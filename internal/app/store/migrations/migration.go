@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Migration is a single versioned schema change.
+// Up and Down hold sql statements executed one by one in order
+// in the main database. ShardUp and ShardDown hold statements executed
+// in shard databases, migration without them is not applied to shards
+type Migration struct {
+	Version   int
+	Name      string
+	Up        []string
+	Down      []string
+	ShardUp   []string
+	ShardDown []string
+}
+
+// Checksum returns sha256 of statements of the main database,
+// it is used to detect migrations changed after they were applied
+func (m *Migration) Checksum() string {
+	return checksum(m.Up, m.Down)
+}
+
+// ShardChecksum returns sha256 of statements of shard databases
+func (m *Migration) ShardChecksum() string {
+	return checksum(m.ShardUp, m.ShardDown)
+}
+
+// Sharded tells whether migration is applied to shard databases
+func (m *Migration) Sharded() bool {
+	return len(m.ShardUp) != 0 || len(m.ShardDown) != 0
+}
+
+func checksum(up, down []string) string {
+
+	h := sha256.New()
+	for _, stmt := range up {
+		h.Write([]byte(strings.TrimSpace(stmt)))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte{0})
+	for _, stmt := range down {
+		h.Write([]byte(strings.TrimSpace(stmt)))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// shardLocal marks migration of tables partitioned across shards,
+// the same statements are executed in shard databases
+func shardLocal(m *Migration) *Migration {
+
+	m.ShardUp, m.ShardDown = m.Up, m.Down
+
+	return m
+}
+
+var registry = make(map[int]*Migration)
+
+// register adds migration to the list of known migrations,
+// it is called from init functions of migration files
+func register(m *Migration) {
+
+	if _, ok := registry[m.Version]; ok {
+		panic(fmt.Sprintf("migration with version %d is already registered", m.Version))
+	}
+
+	registry[m.Version] = m
+}
+
+// All returns all registered migrations ordered by version
+func All() []*Migration {
+
+	migrations := make([]*Migration, 0, len(registry))
+	for _, m := range registry {
+		migrations = append(migrations, m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations
+}
@@ -0,0 +1,266 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Migrator applies and rolls back migrations keeping
+// track of applied ones in schema_migrations table
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration
+	shard      bool
+}
+
+// Status describes state of a single migration in database
+type Status struct {
+	*Migration
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool
+}
+
+type appliedMigration struct {
+	version   int
+	checksum  string
+	appliedAt time.Time
+}
+
+// NewMigrator returns migrator of the main database
+// working with all registered migrations
+func NewMigrator(db *sql.DB) *Migrator {
+	return &Migrator{
+		db:         db,
+		migrations: All(),
+	}
+}
+
+// NewShardMigrator returns migrator of shard database working with
+// migrations of tables partitioned across shards, their shard statements
+// are executed
+func NewShardMigrator(db *sql.DB) *Migrator {
+
+	migrations := make([]*Migration, 0)
+	for _, m := range All() {
+		if m.Sharded() {
+			migrations = append(migrations, m)
+		}
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		shard:      true,
+	}
+}
+
+// Up applies all pending migrations in order of their versions
+// and returns the applied ones
+func (m *Migrator) Up() ([]*Migration, error) {
+
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+
+	done := make([]*Migration, 0)
+	for _, migration := range m.migrations {
+
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		if err := m.exec(migration, m.up(migration)); err != nil {
+			return done, err
+		}
+
+		if _, err := m.db.Exec(
+			`INSERT INTO schema_migrations (version, name, checksum)
+			 VALUES (?, ?, ?)`,
+			migration.Version,
+			migration.Name,
+			m.checksum(migration),
+		); err != nil {
+			return done, err
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down rolls back given number of the latest applied migrations
+// and returns the rolled back ones
+func (m *Migrator) Down(steps int) ([]*Migration, error) {
+
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(applied) == 0 {
+		return nil, ErrNothingToRollback
+	}
+
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+
+	done := make([]*Migration, 0)
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		if err := m.exec(migration, m.down(migration)); err != nil {
+			return done, err
+		}
+
+		if _, err := m.db.Exec(
+			`DELETE FROM schema_migrations WHERE version = ?`,
+			migration.Version,
+		); err != nil {
+			return done, err
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Status returns state of every known migration
+func (m *Migrator) Status() ([]*Status, error) {
+
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+
+		status := &Status{Migration: migration}
+		if a, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = a.appliedAt
+			status.Modified = a.checksum != m.checksum(migration)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func (m *Migrator) ensureTable() error {
+
+	_, err := m.db.Exec(
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (version)
+		)`,
+	)
+
+	return err
+}
+
+func (m *Migrator) appliedMigrations() (map[int]*appliedMigration, error) {
+
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(
+		`SELECT version, checksum, applied_at
+		 FROM schema_migrations
+		 ORDER BY version`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]*appliedMigration)
+	for rows.Next() {
+		a := &appliedMigration{}
+		if err := rows.Scan(&a.version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[a.version] = a
+	}
+
+	return applied, rows.Err()
+}
+
+// verify checks that every applied migration is known
+// and was not changed since it had been applied
+func (m *Migrator) verify(applied map[int]*appliedMigration) error {
+
+	known := make(map[int]*Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, a := range applied {
+		migration, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: version %d", ErrUnknownMigration, version)
+		}
+		if a.checksum != m.checksum(migration) {
+			return fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, version, migration.Name)
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) up(migration *Migration) []string {
+
+	if m.shard {
+		return migration.ShardUp
+	}
+
+	return migration.Up
+}
+
+func (m *Migrator) down(migration *Migration) []string {
+
+	if m.shard {
+		return migration.ShardDown
+	}
+
+	return migration.Down
+}
+
+func (m *Migrator) checksum(migration *Migration) string {
+
+	if m.shard {
+		return migration.ShardChecksum()
+	}
+
+	return migration.Checksum()
+}
+
+func (m *Migrator) exec(migration *Migration, statements []string) error {
+
+	for _, stmt := range statements {
+		if _, err := m.db.Exec(stmt); err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package migrations
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+)
+
+var createTableRe = regexp.MustCompile(`CREATE TABLE IF NOT EXISTS (\w+)`)
+
+func TestShardMigrations_ChangeOnlyPartitionedTables(t *testing.T) {
+
+	mainTables := make([]*regexp.Regexp, 0)
+	for _, m := range All() {
+		if m.Sharded() {
+			continue
+		}
+		for _, stmt := range m.Up {
+			for _, match := range createTableRe.FindAllStringSubmatch(stmt, -1) {
+				mainTables = append(mainTables, regexp.MustCompile(`\b`+match[1]+`\b`))
+			}
+		}
+	}
+
+	if len(mainTables) == 0 {
+		t.Fatal("no tables of the main database found")
+	}
+
+	for _, m := range All() {
+		for _, stmt := range append(append([]string{}, m.ShardUp...), m.ShardDown...) {
+			for _, table := range mainTables {
+				if table.MatchString(stmt) {
+					t.Errorf("%04d_%s: shard statement changes table %s of the main database", m.Version, m.Name, table)
+				}
+			}
+		}
+	}
+}
+
+func TestMigrator_Verify(t *testing.T) {
+
+	shard := NewShardMigrator(nil)
+	main := NewMigrator(nil)
+	sharded := registry[8]
+	mainOnly := registry[5]
+
+	testCases := []struct {
+		name     string
+		migrator *Migrator
+		applied  []*appliedMigration
+		err      error
+	}{
+		{
+			name:     "shard migration",
+			migrator: shard,
+			applied:  []*appliedMigration{{version: sharded.Version, checksum: sharded.ShardChecksum()}},
+		},
+		{
+			name:     "main database checksum in shard",
+			migrator: shard,
+			applied:  []*appliedMigration{{version: sharded.Version, checksum: sharded.Checksum()}},
+			err:      ErrChecksumMismatch,
+		},
+		{
+			name:     "main database migration in shard",
+			migrator: shard,
+			applied:  []*appliedMigration{{version: mainOnly.Version, checksum: mainOnly.Checksum()}},
+			err:      ErrUnknownMigration,
+		},
+		{
+			name:     "changed shard migration",
+			migrator: shard,
+			applied:  []*appliedMigration{{version: sharded.Version, checksum: "changed"}},
+			err:      ErrChecksumMismatch,
+		},
+		{
+			name:     "unknown migration in shard",
+			migrator: shard,
+			applied:  []*appliedMigration{{version: 9999, checksum: "unknown"}},
+			err:      ErrUnknownMigration,
+		},
+		{
+			name:     "shard checksum in the main database",
+			migrator: main,
+			applied:  []*appliedMigration{{version: sharded.Version, checksum: sharded.ShardChecksum()}},
+			err:      ErrChecksumMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			applied := make(map[int]*appliedMigration)
+			for _, a := range tc.applied {
+				applied[a.version] = a
+			}
+
+			if err := tc.migrator.verify(applied); !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
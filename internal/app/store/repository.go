@@ -1,6 +1,10 @@
 package store
 
-import "github.com/DalerBakhriev/social_network/internal/app/model"
+import (
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+)
 
 // UserRepository ...
 type UserRepository interface {
@@ -8,10 +12,115 @@ type UserRepository interface {
 	FindByEmail(string) (*model.User, error)
 	Find(int) (*model.User, error)
 	Update(*model.User) error
-	GetTopUsers(int) ([]*model.User, error)
+	UpdatePassword(*model.User) error
+	VerifyEmail(int, string) error
+	MarkVerificationSent(int, time.Duration) (bool, error)
+	MarkPasswordResetSent(int, time.Duration) (bool, error)
+	GetUsersPage(*model.UserFilter, *model.Cursor, int) (*model.UsersPage, error)
+	Search(string, string, int, *model.Cursor) (*model.UsersPage, error)
 	GetFriendsList(int) ([]*model.User, error)
-	GetFriendsRequests(int) ([]*model.User, error)
+	GetIncomingFriendsRequests(int) ([]*model.User, error)
+	GetOutgoingFriendsRequests(int) ([]*model.User, error)
 	SendFriendRequest(int, int) error
 	AcceptFriendRequest(int, int) error
+	DeclineFriendRequest(int, int) error
+	CancelFriendRequest(int, int) error
+	RemoveFriend(int, int) error
 	RequestWasAlreadySent(int, int) bool
+	AreFriends(int, int) (bool, error)
+	GetAccounts(string, int, int) (*model.AccountsPage, error)
+	SetRole(int, string) error
+	SetSuspended(int, *time.Time) error
+	Delete(int) error
+}
+
+// PostRepository ...
+type PostRepository interface {
+	Create(*model.Post) error
+	Find(int) (*model.Post, error)
+	Update(*model.Post) error
+	Delete(int, int) error
+	GetByAuthor(int, int, int) (*model.PostsPage, error)
+	GetFeed([]int, int, int) (*model.PostsPage, error)
+}
+
+// ActivityRepository ...
+type ActivityRepository interface {
+	Create(*model.Activity) error
+	GetByActors([]int, int) ([]*model.Activity, error)
+}
+
+// DialogRepository ...
+type DialogRepository interface {
+	Send(*model.Message) error
+	GetDialogs(int) ([]*model.Dialog, error)
+	GetMessages(int, int, int, int) (*model.MessagesPage, error)
+	MarkRead(int, int) error
+}
+
+// NotificationRepository ...
+type NotificationRepository interface {
+	Create(*model.Notification) error
+	GetByUser(int, int, int) (*model.NotificationsPage, error)
+	CountUnread(int) (int, error)
+	MarkRead(int, int) error
+	MarkAllRead(int) error
+}
+
+// SessionRepository ...
+type SessionRepository interface {
+	Create(*model.Session) error
+	Find(string) (*model.Session, error)
+	Update(*model.Session) error
+	Delete(string) error
+	GetByUser(int) ([]*model.Session, error)
+	DeleteByUser(int) error
+	DeleteExpired(time.Time) (int, error)
+}
+
+// PasswordResetRepository ...
+type PasswordResetRepository interface {
+	Create(*model.PasswordReset) error
+	FindByToken(string) (*model.PasswordReset, error)
+	Use(string) (*model.PasswordReset, error)
+}
+
+// TwoFactorRepository ...
+type TwoFactorRepository interface {
+	Find(int) (*model.TwoFactor, error)
+	Create(*model.TwoFactor) error
+	Confirm(int, []string) error
+	UseStep(int, int64) error
+	UseRecoveryCode(int, string) error
+	CountRecoveryCodes(int) (int, error)
+	Delete(int) error
+}
+
+// APITokenRepository ...
+type APITokenRepository interface {
+	Create(*model.APIToken) error
+	FindByHash(string) (*model.APIToken, error)
+	GetByUser(int) ([]*model.APIToken, error)
+	Touch(int, time.Time) error
+	Delete(int, int) error
+}
+
+// ExternalIdentityRepository ...
+type ExternalIdentityRepository interface {
+	Create(*model.ExternalIdentity) error
+	Find(string, string) (*model.ExternalIdentity, error)
+}
+
+// LoginAttemptRepository ...
+type LoginAttemptRepository interface {
+	Find(string) (*model.LoginAttempts, error)
+	Reserve(string, model.LoginLimit) (*model.LoginAttempts, error)
+	Release(*model.LoginAttempts) error
+	Delete(string) error
+	DeleteBefore(time.Time) (int, error)
+}
+
+// AuditRepository ...
+type AuditRepository interface {
+	Create(*model.AuditEvent) error
 }
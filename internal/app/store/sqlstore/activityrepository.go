@@ -0,0 +1,123 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+)
+
+// ActivityRepository ...
+type ActivityRepository struct {
+	store *Store
+}
+
+// Create ...
+func (r *ActivityRepository) Create(a *model.Activity) error {
+
+	a.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	subjectID := sql.NullInt64{Int64: int64(a.SubjectID), Valid: a.SubjectID != 0}
+	res, err := r.store.db.Exec(
+		`INSERT INTO activities (type, actor_id, subject_id, created_at)
+		 VALUES (?, ?, ?, ?)`,
+		a.Type,
+		a.ActorID,
+		subjectID,
+		a.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	a.ID = int(id)
+
+	return nil
+}
+
+// GetByActors returns the latest activities of given users from newer to older
+func (r *ActivityRepository) GetByActors(actorIDs []int, limit int) ([]*model.Activity, error) {
+
+	activities := make([]*model.Activity, 0)
+	if len(actorIDs) == 0 {
+		return activities, nil
+	}
+
+	args := make([]interface{}, 0, len(actorIDs)+1)
+	for _, id := range actorIDs {
+		args = append(args, id)
+	}
+	args = append(args, limit)
+
+	rows, err := r.store.db.Query(
+		`SELECT id,
+				type,
+				actor_id,
+				subject_id,
+				created_at
+		 FROM activities
+		 WHERE actor_id IN (`+placeholders(len(actorIDs))+`)
+		 ORDER BY id DESC
+		 LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		a := &model.Activity{}
+		var subjectID sql.NullInt64
+		if err := rows.Scan(
+			&a.ID,
+			&a.Type,
+			&a.ActorID,
+			&subjectID,
+			&a.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		a.SubjectID = int(subjectID.Int64)
+		activities = append(activities, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return activities, r.setNames(activities)
+}
+
+// setNames fills names of actors and subjects of activities,
+// users are kept in shards apart from activities
+func (r *ActivityRepository) setNames(activities []*model.Activity) error {
+
+	ids := make([]int, 0, 2*len(activities))
+	for _, a := range activities {
+		ids = append(ids, a.ActorID)
+		if a.SubjectID != 0 {
+			ids = append(ids, a.SubjectID)
+		}
+	}
+
+	users, err := r.store.users(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range activities {
+		if actor, ok := users[a.ActorID]; ok {
+			a.ActorName, a.ActorSurname = actor.Name, actor.Surname
+		}
+		if subject, ok := users[a.SubjectID]; ok {
+			a.SubjectName, a.SubjectSurname = subject.Name, subject.Surname
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// APITokenRepository ...
+type APITokenRepository struct {
+	store *Store
+}
+
+// Create ...
+func (r *APITokenRepository) Create(t *model.APIToken) error {
+
+	t.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	res, err := r.store.db.Exec(
+		`INSERT INTO api_tokens (user_id, name, scopes, token_hash, created_at)
+		 VALUES (?, ?, ?, ?, ?)`,
+		t.UserID,
+		t.Name,
+		strings.Join(t.Scopes, ","),
+		t.TokenHash,
+		t.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	t.ID = int(id)
+
+	return nil
+}
+
+// FindByHash ...
+func (r *APITokenRepository) FindByHash(tokenHash string) (*model.APIToken, error) {
+
+	t, err := scanAPIToken(r.store.db.QueryRow(
+		`SELECT id,
+				user_id,
+				name,
+				scopes,
+				token_hash,
+				created_at,
+				last_used_at
+		 FROM api_tokens
+		 WHERE token_hash = ?`,
+		tokenHash,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// GetByUser returns tokens of user, newest first
+func (r *APITokenRepository) GetByUser(userID int) ([]*model.APIToken, error) {
+
+	rows, err := r.store.db.Query(
+		`SELECT id,
+				user_id,
+				name,
+				scopes,
+				token_hash,
+				created_at,
+				last_used_at
+		 FROM api_tokens
+		 WHERE user_id = ?
+		 ORDER BY id DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]*model.APIToken, 0)
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+
+	return tokens, rows.Err()
+}
+
+// Touch records time token was last used at
+func (r *APITokenRepository) Touch(id int, usedAt time.Time) error {
+
+	_, err := r.store.db.Exec(
+		`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`,
+		usedAt.UTC().Truncate(time.Second),
+		id,
+	)
+
+	return err
+}
+
+// Delete revokes token with id if it belongs to user
+func (r *APITokenRepository) Delete(userID, id int) error {
+
+	res, err := r.store.db.Exec(
+		`DELETE FROM api_tokens WHERE id = ? AND user_id = ?`,
+		id,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+func scanAPIToken(row interface{ Scan(...interface{}) error }) (*model.APIToken, error) {
+
+	t := &model.APIToken{}
+	var scopes string
+	if err := row.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Name,
+		&scopes,
+		&t.TokenHash,
+		&t.CreatedAt,
+		&t.LastUsedAt,
+	); err != nil {
+		return nil, err
+	}
+	t.Scopes = strings.Split(scopes, ",")
+
+	return t, nil
+}
@@ -0,0 +1,39 @@
+package sqlstore
+
+import (
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+)
+
+// AuditRepository ...
+type AuditRepository struct {
+	store *Store
+}
+
+// Create ...
+func (r *AuditRepository) Create(e *model.AuditEvent) error {
+
+	e.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	res, err := r.store.db.Exec(
+		`INSERT INTO audit_events (type, user_id, ip, details, created_at)
+		 VALUES (?, ?, ?, ?, ?)`,
+		e.Type,
+		e.UserID,
+		e.IP,
+		e.Details,
+		e.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	e.ID = int(id)
+
+	return nil
+}
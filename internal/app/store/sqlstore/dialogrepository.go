@@ -0,0 +1,192 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+)
+
+// DialogRepository ...
+type DialogRepository struct {
+	store *Store
+}
+
+// Send ...
+func (r *DialogRepository) Send(m *model.Message) error {
+
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	m.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	res, err := r.store.db.Exec(
+		`INSERT INTO messages (from_id, to_id, text, created_at)
+		 VALUES (?, ?, ?, ?)`,
+		m.FromID,
+		m.ToID,
+		m.Text,
+		m.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	m.ID = int(id)
+
+	return nil
+}
+
+// GetDialogs returns dialogs of user with the last message
+// and number of unread messages, dialogs with the latest messages go first
+func (r *DialogRepository) GetDialogs(userID int) ([]*model.Dialog, error) {
+
+	rows, err := r.store.db.Query(
+		`SELECT d.peer_id,
+				m.id,
+				m.from_id,
+				m.to_id,
+				m.text,
+				m.created_at,
+				m.read_at,
+				(SELECT COUNT(*)
+				 FROM messages unread
+				 WHERE unread.from_id = d.peer_id
+				   AND unread.to_id = ?
+				   AND unread.read_at IS NULL)
+		 FROM (SELECT IF(from_id = ?, to_id, from_id) AS peer_id,
+					  MAX(id) AS last_id
+			   FROM messages
+			   WHERE from_id = ? OR to_id = ?
+			   GROUP BY peer_id) d
+		 JOIN messages m ON m.id = d.last_id
+		 ORDER BY m.id DESC`,
+		userID, userID, userID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dialogs := make([]*model.Dialog, 0)
+	for rows.Next() {
+		d := &model.Dialog{LastMessage: &model.Message{}}
+		var readAt sql.NullTime
+		if err := rows.Scan(
+			&d.PeerID,
+			&d.LastMessage.ID,
+			&d.LastMessage.FromID,
+			&d.LastMessage.ToID,
+			&d.LastMessage.Text,
+			&d.LastMessage.CreatedAt,
+			&readAt,
+			&d.UnreadCount,
+		); err != nil {
+			return nil, err
+		}
+		if readAt.Valid {
+			d.LastMessage.ReadAt = &readAt.Time
+		}
+		dialogs = append(dialogs, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(dialogs))
+	for _, d := range dialogs {
+		ids = append(ids, d.PeerID)
+	}
+
+	peers, err := r.store.users(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range dialogs {
+		if peer, ok := peers[d.PeerID]; ok {
+			d.PeerName, d.PeerSurname = peer.Name, peer.Surname
+		}
+	}
+
+	return dialogs, nil
+}
+
+// GetMessages returns page of messages between two users
+// older than message with beforeID, zero beforeID means the newest messages
+func (r *DialogRepository) GetMessages(userID, peerID, beforeID, limit int) (*model.MessagesPage, error) {
+
+	args := []interface{}{userID, peerID, peerID, userID}
+
+	before := ""
+	if beforeID > 0 {
+		before = "AND id < ?"
+		args = append(args, beforeID)
+	}
+	args = append(args, limit+1)
+
+	rows, err := r.store.db.Query(
+		`SELECT id,
+				from_id,
+				to_id,
+				text,
+				created_at,
+				read_at
+		 FROM messages
+		 WHERE ((from_id = ? AND to_id = ?) OR (from_id = ? AND to_id = ?)) `+before+`
+		 ORDER BY id DESC
+		 LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]*model.Message, 0, limit+1)
+	for rows.Next() {
+		m := &model.Message{}
+		var readAt sql.NullTime
+		if err := rows.Scan(
+			&m.ID,
+			&m.FromID,
+			&m.ToID,
+			&m.Text,
+			&m.CreatedAt,
+			&readAt,
+		); err != nil {
+			return nil, err
+		}
+		if readAt.Valid {
+			m.ReadAt = &readAt.Time
+		}
+		messages = append(messages, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return model.NewMessagesPage(messages, limit), nil
+}
+
+// MarkRead marks all messages sent by peer to user as read
+func (r *DialogRepository) MarkRead(userID, peerID int) error {
+
+	_, err := r.store.db.Exec(
+		`UPDATE messages
+		 SET read_at = ?
+		 WHERE to_id = ? AND from_id = ? AND read_at IS NULL`,
+		time.Now().UTC().Truncate(time.Second),
+		userID,
+		peerID,
+	)
+
+	return err
+}
@@ -0,0 +1,76 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// ExternalIdentityRepository ...
+type ExternalIdentityRepository struct {
+	store *Store
+}
+
+// Create links identity to user, each identity is linked once
+func (r *ExternalIdentityRepository) Create(i *model.ExternalIdentity) error {
+
+	i.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	res, err := r.store.db.Exec(
+		`INSERT INTO external_identities (user_id, provider, subject, email, created_at)
+		 VALUES (?, ?, ?, ?, ?)`,
+		i.UserID,
+		i.Provider,
+		i.Subject,
+		i.Email,
+		i.CreatedAt,
+	)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			return store.ErrIdentityAlreadyLinked
+		}
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	i.ID = int(id)
+
+	return nil
+}
+
+// Find returns identity with subject at provider
+func (r *ExternalIdentityRepository) Find(provider, subject string) (*model.ExternalIdentity, error) {
+
+	i := &model.ExternalIdentity{}
+	if err := r.store.db.QueryRow(
+		`SELECT id,
+				user_id,
+				provider,
+				subject,
+				email,
+				created_at
+		 FROM external_identities
+		 WHERE provider = ? AND subject = ?`,
+		provider,
+		subject,
+	).Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Provider,
+		&i.Subject,
+		&i.Email,
+		&i.CreatedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return i, nil
+}
@@ -0,0 +1,150 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// LoginAttemptRepository ...
+type LoginAttemptRepository struct {
+	store *Store
+}
+
+// Find ...
+func (r *LoginAttemptRepository) Find(key string) (*model.LoginAttempts, error) {
+
+	a := &model.LoginAttempts{}
+	if err := r.store.db.QueryRow(
+		`SELECT attempt_key,
+				failures,
+				last_failure_at
+		 FROM login_attempts
+		 WHERE attempt_key = ?`,
+		key,
+	).Scan(
+		&a.Key,
+		&a.Failures,
+		&a.LastFailureAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Reserve counts log in with key as failed before its password is checked,
+// so that concurrent log ins can not pass the limit together. Log in that
+// has to wait gets ErrLoginAttemptsExceeded and attempts it waits for
+func (r *LoginAttemptRepository) Reserve(key string, limit model.LoginLimit) (*model.LoginAttempts, error) {
+
+	tx, err := r.store.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// row is created first so that it can be locked by the first log in too
+	if _, err := tx.Exec(
+		`INSERT INTO login_attempts (attempt_key, failures, last_failure_at)
+		 VALUES (?, 0, ?)
+		 ON DUPLICATE KEY UPDATE attempt_key = attempt_key`,
+		key,
+		now,
+	); err != nil {
+		return nil, err
+	}
+
+	a := &model.LoginAttempts{Key: key}
+	if err := tx.QueryRow(
+		`SELECT failures,
+				last_failure_at
+		 FROM login_attempts
+		 WHERE attempt_key = ?
+		 FOR UPDATE`,
+		key,
+	).Scan(
+		&a.Failures,
+		&a.LastFailureAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if limit.Expired(a, now) {
+		a.Failures = 0
+	}
+	if limit.BlockedUntil(a).After(now) {
+		return a, store.ErrLoginAttemptsExceeded
+	}
+
+	a.Failures++
+	a.PreviousFailureAt = a.LastFailureAt
+	a.LastFailureAt = now
+	if _, err := tx.Exec(
+		`UPDATE login_attempts
+		 SET failures = ?,
+			 last_failure_at = ?
+		 WHERE attempt_key = ?`,
+		a.Failures,
+		a.LastFailureAt,
+		key,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Release takes back reserved log in that did not fail, time of the last
+// failure is restored unless another log in was reserved after it, so
+// that successful log ins do not keep failures from expiring
+func (r *LoginAttemptRepository) Release(a *model.LoginAttempts) error {
+
+	// last_failure_at is assigned first so that it sees reserved failures
+	_, err := r.store.db.Exec(
+		`UPDATE login_attempts
+		 SET last_failure_at = IF(last_failure_at = ? AND failures > 0, ?, last_failure_at),
+			 failures = GREATEST(failures - 1, 0)
+		 WHERE attempt_key = ?`,
+		a.LastFailureAt,
+		a.PreviousFailureAt,
+		a.Key,
+	)
+
+	return err
+}
+
+// Delete forgets failures with key after successful log in
+func (r *LoginAttemptRepository) Delete(key string) error {
+
+	_, err := r.store.db.Exec(`DELETE FROM login_attempts WHERE attempt_key = ?`, key)
+
+	return err
+}
+
+// DeleteBefore deletes failures nobody repeated since t
+func (r *LoginAttemptRepository) DeleteBefore(t time.Time) (int, error) {
+
+	res, err := r.store.db.Exec(`DELETE FROM login_attempts WHERE last_failure_at < ?`, t.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n), nil
+}
@@ -0,0 +1,109 @@
+package sqlstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+func TestLoginAttemptRepository_Reserve(t *testing.T) {
+
+	limit := model.LoginLimit{
+		FreeAttempts:    3,
+		LockoutAttempts: 10,
+		MaxBackoff:      time.Minute,
+		LockoutDuration: 15 * time.Minute,
+	}
+
+	testCases := []struct {
+		name          string
+		failures      int
+		lastFailureAt time.Time
+		reserved      bool
+		expected      int
+	}{
+		{
+			name:          "free attempt",
+			failures:      3,
+			lastFailureAt: time.Now(),
+			reserved:      true,
+			expected:      4,
+		},
+		{
+			name:          "backoff",
+			failures:      4,
+			lastFailureAt: time.Now(),
+			expected:      4,
+		},
+		{
+			name:          "expired failures",
+			failures:      10,
+			lastFailureAt: time.Now().Add(-time.Hour),
+			reserved:      true,
+			expected:      1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, mock := newMockStore(t)
+
+			mock.ExpectBegin()
+			mock.ExpectExec(`INSERT INTO login_attempts`).
+				WithArgs("email:user@example.org", sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(`SELECT failures,\s+last_failure_at\s+FROM login_attempts\s+WHERE attempt_key = \?\s+FOR UPDATE`).
+				WithArgs("email:user@example.org").
+				WillReturnRows(sqlmock.NewRows([]string{"failures", "last_failure_at"}).AddRow(tc.failures, tc.lastFailureAt))
+			if tc.reserved {
+				mock.ExpectExec(`UPDATE login_attempts`).
+					WithArgs(tc.expected, sqlmock.AnyArg(), "email:user@example.org").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			a, err := s.LoginAttempt().Reserve("email:user@example.org", limit)
+			if tc.reserved && err != nil {
+				t.Fatal(err)
+			}
+			if !tc.reserved && err != store.ErrLoginAttemptsExceeded {
+				t.Fatalf("expected error %v, got %v", store.ErrLoginAttemptsExceeded, err)
+			}
+			if a.Failures != tc.expected {
+				t.Fatalf("expected %d failures, got %d", tc.expected, a.Failures)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestLoginAttemptRepository_Release(t *testing.T) {
+
+	s, mock := newMockStore(t)
+	previous := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	reserved := previous.Add(time.Minute)
+
+	mock.ExpectExec(`UPDATE login_attempts\s+SET last_failure_at = IF\(last_failure_at = \? AND failures > 0, \?, last_failure_at\),\s+failures = GREATEST\(failures - 1, 0\)`).
+		WithArgs(reserved, previous, "ip:192.0.2.1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := s.LoginAttempt().Release(&model.LoginAttempts{
+		Key:               "ip:192.0.2.1",
+		Failures:          2,
+		LastFailureAt:     reserved,
+		PreviousFailureAt: previous,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,186 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+)
+
+// NotificationRepository ...
+type NotificationRepository struct {
+	store *Store
+}
+
+// Create ...
+func (r *NotificationRepository) Create(n *model.Notification) error {
+
+	n.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	actorID := sql.NullInt64{Int64: int64(n.ActorID), Valid: n.ActorID != 0}
+	text := sql.NullString{String: n.Text, Valid: n.Text != ""}
+	res, err := r.store.db.Exec(
+		`INSERT INTO notifications (user_id, type, actor_id, text, created_at)
+		 VALUES (?, ?, ?, ?, ?)`,
+		n.UserID,
+		n.Type,
+		actorID,
+		text,
+		n.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	n.ID = int(id)
+
+	return nil
+}
+
+// GetByUser returns page of notifications of user older than notification
+// with beforeID together with number of unread ones, zero beforeID
+// means the newest notifications
+func (r *NotificationRepository) GetByUser(userID, beforeID, limit int) (*model.NotificationsPage, error) {
+
+	args := []interface{}{userID}
+
+	before := ""
+	if beforeID > 0 {
+		before = "AND id < ?"
+		args = append(args, beforeID)
+	}
+	args = append(args, limit+1)
+
+	rows, err := r.store.db.Query(
+		`SELECT id,
+				type,
+				actor_id,
+				text,
+				created_at,
+				read_at
+		 FROM notifications
+		 WHERE user_id = ? `+before+`
+		 ORDER BY id DESC
+		 LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := make([]*model.Notification, 0, limit+1)
+	for rows.Next() {
+		n := &model.Notification{UserID: userID}
+		var actorID sql.NullInt64
+		var text sql.NullString
+		var readAt sql.NullTime
+		if err := rows.Scan(
+			&n.ID,
+			&n.Type,
+			&actorID,
+			&text,
+			&n.CreatedAt,
+			&readAt,
+		); err != nil {
+			return nil, err
+		}
+		n.ActorID = int(actorID.Int64)
+		n.Text = text.String
+		if readAt.Valid {
+			n.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.setActors(notifications); err != nil {
+		return nil, err
+	}
+
+	page := model.NewNotificationsPage(notifications, limit)
+	if page.Unread, err = r.CountUnread(userID); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// setActors fills names of actors of notifications,
+// users are kept in shards apart from notifications
+func (r *NotificationRepository) setActors(notifications []*model.Notification) error {
+
+	ids := make([]int, 0, len(notifications))
+	for _, n := range notifications {
+		if n.ActorID != 0 {
+			ids = append(ids, n.ActorID)
+		}
+	}
+
+	actors, err := r.store.users(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range notifications {
+		if actor, ok := actors[n.ActorID]; ok {
+			n.ActorName, n.ActorSurname = actor.Name, actor.Surname
+		}
+	}
+
+	return nil
+}
+
+// CountUnread ...
+func (r *NotificationRepository) CountUnread(userID int) (int, error) {
+
+	var n int
+	err := r.store.db.QueryRow(
+		`SELECT COUNT(*)
+		 FROM notifications
+		 WHERE user_id = ? AND read_at IS NULL`,
+		userID,
+	).Scan(&n)
+
+	return n, err
+}
+
+// MarkRead marks notification as read, only recipient
+// of notification can read it
+func (r *NotificationRepository) MarkRead(id, userID int) error {
+
+	res, err := r.store.db.Exec(
+		`UPDATE notifications
+		 SET read_at = COALESCE(read_at, ?)
+		 WHERE id = ? AND user_id = ?`,
+		time.Now().UTC().Truncate(time.Second),
+		id,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+// MarkAllRead ...
+func (r *NotificationRepository) MarkAllRead(userID int) error {
+
+	_, err := r.store.db.Exec(
+		`UPDATE notifications
+		 SET read_at = ?
+		 WHERE user_id = ? AND read_at IS NULL`,
+		time.Now().UTC().Truncate(time.Second),
+		userID,
+	)
+
+	return err
+}
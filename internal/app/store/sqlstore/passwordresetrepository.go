@@ -0,0 +1,113 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// PasswordResetRepository ...
+type PasswordResetRepository struct {
+	store *Store
+}
+
+// Create ...
+func (r *PasswordResetRepository) Create(p *model.PasswordReset) error {
+
+	p.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	res, err := r.store.db.Exec(
+		`INSERT INTO password_resets (user_id, token_hash, created_at, expires_at)
+		 VALUES (?, ?, ?, ?)`,
+		p.UserID,
+		p.TokenHash,
+		p.CreatedAt,
+		p.ExpiresAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	p.ID = int(id)
+
+	return nil
+}
+
+// FindByToken returns reset with token hash which
+// is neither used nor expired yet
+func (r *PasswordResetRepository) FindByToken(tokenHash string) (*model.PasswordReset, error) {
+
+	p := &model.PasswordReset{}
+	if err := r.store.db.QueryRow(
+		`SELECT id,
+				user_id,
+				token_hash,
+				created_at,
+				expires_at
+		 FROM password_resets
+		 WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?`,
+		tokenHash,
+		time.Now().UTC(),
+	).Scan(
+		&p.ID,
+		&p.UserID,
+		&p.TokenHash,
+		&p.CreatedAt,
+		&p.ExpiresAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Use marks reset with token hash as used, so that concurrent
+// requests can not use one token twice
+func (r *PasswordResetRepository) Use(tokenHash string) (*model.PasswordReset, error) {
+
+	now := time.Now().UTC().Truncate(time.Second)
+	res, err := r.store.db.Exec(
+		`UPDATE password_resets
+		 SET used_at = ?
+		 WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?`,
+		now,
+		tokenHash,
+		now,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkAffected(res); err != nil {
+		return nil, err
+	}
+
+	p := &model.PasswordReset{TokenHash: tokenHash, UsedAt: &now}
+	if err := r.store.db.QueryRow(
+		`SELECT id,
+				user_id,
+				created_at,
+				expires_at
+		 FROM password_resets
+		 WHERE token_hash = ?`,
+		tokenHash,
+	).Scan(
+		&p.ID,
+		&p.UserID,
+		&p.CreatedAt,
+		&p.ExpiresAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,217 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// PostRepository ...
+type PostRepository struct {
+	store *Store
+}
+
+// Create ...
+func (r *PostRepository) Create(p *model.Post) error {
+
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	p.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	p.UpdatedAt = p.CreatedAt
+
+	res, err := r.store.db.Exec(
+		`INSERT INTO posts (author_id, text, created_at, updated_at)
+		 VALUES (?, ?, ?, ?)`,
+		p.AuthorID,
+		p.Text,
+		p.CreatedAt,
+		p.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	p.ID = int(id)
+
+	return nil
+}
+
+// Find ...
+func (r *PostRepository) Find(id int) (*model.Post, error) {
+
+	p := &model.Post{}
+	if err := r.store.db.QueryRow(
+		`SELECT id,
+				author_id,
+				text,
+				created_at,
+				updated_at
+		 FROM posts
+		 WHERE id = ?`,
+		id,
+	).Scan(
+		&p.ID,
+		&p.AuthorID,
+		&p.Text,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+
+		return nil, err
+	}
+
+	if err := r.setAuthors([]*model.Post{p}); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Update changes text of post, only author of post can change it
+func (r *PostRepository) Update(p *model.Post) error {
+
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	p.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+
+	res, err := r.store.db.Exec(
+		`UPDATE posts
+		 SET text = ?,
+			 updated_at = ?
+		 WHERE id = ? AND author_id = ?`,
+		p.Text,
+		p.UpdatedAt,
+		p.ID,
+		p.AuthorID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+// Delete removes post, only author of post can remove it
+func (r *PostRepository) Delete(id, authorID int) error {
+
+	res, err := r.store.db.Exec(
+		`DELETE FROM posts WHERE id = ? AND author_id = ?`,
+		id,
+		authorID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+// GetByAuthor returns page of posts of author older than post with beforeID,
+// zero beforeID means the newest posts
+func (r *PostRepository) GetByAuthor(authorID, beforeID, limit int) (*model.PostsPage, error) {
+	return r.GetFeed([]int{authorID}, beforeID, limit)
+}
+
+// GetFeed returns page of posts of several authors in reverse chronological order
+func (r *PostRepository) GetFeed(authorIDs []int, beforeID, limit int) (*model.PostsPage, error) {
+
+	if len(authorIDs) == 0 {
+		return model.NewPostsPage(make([]*model.Post, 0), limit), nil
+	}
+
+	args := make([]interface{}, 0, len(authorIDs)+2)
+	for _, id := range authorIDs {
+		args = append(args, id)
+	}
+
+	before := ""
+	if beforeID > 0 {
+		before = "AND id < ?"
+		args = append(args, beforeID)
+	}
+	args = append(args, limit+1)
+
+	rows, err := r.store.db.Query(
+		`SELECT id,
+				author_id,
+				text,
+				created_at,
+				updated_at
+		 FROM posts
+		 WHERE author_id IN (`+placeholders(len(authorIDs))+`) `+before+`
+		 ORDER BY id DESC
+		 LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := make([]*model.Post, 0, limit+1)
+	for rows.Next() {
+		p := &model.Post{}
+		if err := rows.Scan(
+			&p.ID,
+			&p.AuthorID,
+			&p.Text,
+			&p.CreatedAt,
+			&p.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.setAuthors(posts); err != nil {
+		return nil, err
+	}
+
+	return model.NewPostsPage(posts, limit), nil
+}
+
+// setAuthors fills names of authors of posts,
+// authors are kept in shards apart from posts
+func (r *PostRepository) setAuthors(posts []*model.Post) error {
+
+	ids := make([]int, 0, len(posts))
+	for _, p := range posts {
+		ids = append(ids, p.AuthorID)
+	}
+
+	authors, err := r.store.users(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range posts {
+		if author, ok := authors[p.AuthorID]; ok {
+			p.AuthorName, p.AuthorSurname = author.Name, author.Surname
+		}
+	}
+
+	return nil
+}
+
+// placeholders returns n comma separated query placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,194 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+)
+
+// reshardBatchSize is number of user ids read from shard at once while resharding
+const reshardBatchSize = 500
+
+// Reshard moves users and their friendships to shards they belong to
+// according to the current placement, usually after shards are added
+// as joining or marked as leaving. It works online: user is locked in
+// the old shard while being moved and the server looks users up in the
+// old shard first. Moving is idempotent, so interrupted resharding may
+// be restarted. onMove is called after every moved user
+func (s *Store) Reshard(onMove func(userID int, from, to string)) (int, error) {
+
+	moved := 0
+	for _, name := range s.shards.names {
+		lastID := 0
+		for {
+			ids, err := selectUserIDs(s.shards.dbs[name], lastID)
+			if err != nil {
+				return moved, err
+			}
+			if len(ids) == 0 {
+				break
+			}
+			lastID = ids[len(ids)-1]
+
+			for _, id := range ids {
+				owner := s.shards.current.get(id)
+				if owner == name {
+					continue
+				}
+				ok, err := moveUser(s.shards.dbs[name], s.shards.dbs[owner], id)
+				if err != nil {
+					return moved, err
+				}
+				if ok {
+					moved++
+					if onMove != nil {
+						onMove(id, name, owner)
+					}
+				}
+			}
+		}
+	}
+
+	return moved, nil
+}
+
+// selectUserIDs returns the next batch of ids of users of shard greater than lastID
+func selectUserIDs(db *sql.DB, lastID int) ([]int, error) {
+
+	rows, err := db.Query(
+		`SELECT id FROM users WHERE id > ? ORDER BY id LIMIT ?`,
+		lastID,
+		reshardBatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0, reshardBatchSize)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// friendshipRow is row of friends table of moved user
+type friendshipRow struct {
+	friendID    int
+	requesterID int
+	status      string
+	createdAt   time.Time
+}
+
+// moveUser copies user and rows of friends of the user from one shard
+// to another and removes them from the former. User row stays locked
+// in the former shard until the move is finished, rows already copied
+// to the target shard by interrupted move are overwritten
+func moveUser(from, to *sql.DB, id int) (bool, error) {
+
+	return inTx(from, func(src *sql.Tx) (bool, error) {
+
+		var email, encryptedPassword string
+		var name, surname, sex, interests, city sql.NullString
+		var age int
+		var verifiedAt, verificationSentAt sql.NullTime
+		err := src.QueryRow(
+			`SELECT email, name, surname, age, sex, interests, city, encrypted_password,
+					email_verified_at, verification_sent_at
+			 FROM users
+			 WHERE id = ?
+			 FOR UPDATE`,
+			id,
+		).Scan(&email, &name, &surname, &age, &sex, &interests, &city, &encryptedPassword, &verifiedAt, &verificationSentAt)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		friendships, err := selectFriendships(src, id)
+		if err != nil {
+			return false, err
+		}
+
+		if _, err := inTx(to, func(dst *sql.Tx) (bool, error) {
+			if _, err := dst.Exec(
+				`INSERT INTO users (id, email, name, surname, age, sex, interests, city, encrypted_password,
+						email_verified_at, verification_sent_at)
+				 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				 ON DUPLICATE KEY UPDATE
+					name = VALUES(name),
+					surname = VALUES(surname),
+					age = VALUES(age),
+					sex = VALUES(sex),
+					interests = VALUES(interests),
+					city = VALUES(city),
+					encrypted_password = VALUES(encrypted_password),
+					email_verified_at = VALUES(email_verified_at),
+					verification_sent_at = VALUES(verification_sent_at)`,
+				id, email, name, surname, age, sex, interests, city, encryptedPassword,
+				verifiedAt, verificationSentAt,
+			); err != nil {
+				return false, err
+			}
+
+			for _, f := range friendships {
+				if _, err := dst.Exec(
+					`INSERT INTO friends (user_id, friend_id, requester_id, status, created_at)
+					 VALUES (?, ?, ?, ?, ?)
+					 ON DUPLICATE KEY UPDATE
+						requester_id = VALUES(requester_id),
+						status = VALUES(status),
+						created_at = VALUES(created_at)`,
+					id, f.friendID, f.requesterID, f.status, f.createdAt,
+				); err != nil {
+					return false, err
+				}
+			}
+
+			return true, nil
+		}); err != nil {
+			return false, err
+		}
+
+		if _, err := src.Exec(`DELETE FROM friends WHERE user_id = ?`, id); err != nil {
+			return false, err
+		}
+		if _, err := src.Exec(`DELETE FROM users WHERE id = ?`, id); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	})
+}
+
+func selectFriendships(tx *sql.Tx, id int) ([]*friendshipRow, error) {
+
+	rows, err := tx.Query(
+		`SELECT friend_id, requester_id, status, created_at
+		 FROM friends
+		 WHERE user_id = ?
+		 FOR UPDATE`,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	friendships := make([]*friendshipRow, 0)
+	for rows.Next() {
+		f := &friendshipRow{}
+		if err := rows.Scan(&f.friendID, &f.requesterID, &f.status, &f.createdAt); err != nil {
+			return nil, err
+		}
+		friendships = append(friendships, f)
+	}
+
+	return friendships, rows.Err()
+}
@@ -0,0 +1,62 @@
+package sqlstore
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// ring places keys on nodes with consistent hashing, every node is
+// represented by several virtual nodes so that keys are spread evenly
+// and adding or removing a node moves only keys of that node
+type ring struct {
+	points []uint32
+	nodes  map[uint32]string
+}
+
+// newRing ...
+func newRing(virtualNodes int, nodes ...string) *ring {
+
+	r := &ring{
+		points: make([]uint32, 0, len(nodes)*virtualNodes),
+		nodes:  make(map[uint32]string, len(nodes)*virtualNodes),
+	}
+
+	for _, node := range nodes {
+		for i := 0; i < virtualNodes; i++ {
+			point := hashKey(node + "#" + strconv.Itoa(i))
+			if _, ok := r.nodes[point]; ok {
+				continue
+			}
+			r.nodes[point] = node
+			r.points = append(r.points, point)
+		}
+	}
+
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+
+	return r
+}
+
+// get returns node owning key, it is the node of the first
+// virtual node clockwise from hash of the key
+func (r *ring) get(key int) string {
+
+	if len(r.points) == 0 {
+		return ""
+	}
+
+	hash := hashKey(strconv.Itoa(key))
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= hash })
+	if i == len(r.points) {
+		i = 0
+	}
+
+	return r.nodes[r.points[i]]
+}
+
+func hashKey(key string) uint32 {
+	sum := md5.Sum([]byte(key))
+	return binary.BigEndian.Uint32(sum[:4])
+}
@@ -0,0 +1,62 @@
+package sqlstore
+
+import (
+	"testing"
+)
+
+const ringTestKeys = 30000
+
+func TestRing_SpreadsKeysEvenly(t *testing.T) {
+
+	nodes := []string{"a", "b", "c"}
+	r := newRing(defaultVirtualNodes, nodes...)
+
+	counts := make(map[string]int)
+	for key := 1; key <= ringTestKeys; key++ {
+		counts[r.get(key)]++
+	}
+
+	expected := ringTestKeys / len(nodes)
+	for _, node := range nodes {
+		if counts[node] < expected*2/3 || counts[node] > expected*4/3 {
+			t.Errorf("node %s got %d keys, expected about %d", node, counts[node], expected)
+		}
+	}
+}
+
+func TestRing_AddingNodeMovesKeysOnlyToIt(t *testing.T) {
+
+	before := newRing(defaultVirtualNodes, "a", "b", "c")
+	after := newRing(defaultVirtualNodes, "a", "b", "c", "d")
+
+	moved := 0
+	for key := 1; key <= ringTestKeys; key++ {
+		from, to := before.get(key), after.get(key)
+		if from == to {
+			continue
+		}
+		if to != "d" {
+			t.Fatalf("key %d moved from %s to %s instead of the new node", key, from, to)
+		}
+		moved++
+	}
+
+	// the new node takes about a quarter of keys
+	if moved < ringTestKeys/6 || moved > ringTestKeys/3 {
+		t.Fatalf("%d of %d keys moved, expected about %d", moved, ringTestKeys, ringTestKeys/4)
+	}
+}
+
+func TestRing_Get(t *testing.T) {
+
+	if node := newRing(defaultVirtualNodes).get(1); node != "" {
+		t.Fatalf("empty ring returned node %q", node)
+	}
+
+	r := newRing(defaultVirtualNodes, "a", "b")
+	for key := 1; key <= 100; key++ {
+		if r.get(key) != newRing(defaultVirtualNodes, "b", "a").get(key) {
+			t.Fatalf("placement of key %d depends on order of nodes", key)
+		}
+	}
+}
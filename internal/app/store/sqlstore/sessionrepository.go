@@ -0,0 +1,188 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// SessionRepository ...
+type SessionRepository struct {
+	store *Store
+}
+
+// Create ...
+func (r *SessionRepository) Create(s *model.Session) error {
+
+	s.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	_, err := r.store.db.Exec(
+		`INSERT INTO sessions (id, user_id, data, user_agent, ip, created_at, last_seen_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.ID,
+		sessionUserID(s),
+		s.Data,
+		s.UserAgent,
+		s.IP,
+		s.CreatedAt,
+		s.LastSeenAt,
+		s.ExpiresAt,
+	)
+
+	return err
+}
+
+// Find ...
+func (r *SessionRepository) Find(id string) (*model.Session, error) {
+
+	s := &model.Session{}
+	var userID sql.NullInt64
+	if err := r.store.db.QueryRow(
+		`SELECT id,
+				user_id,
+				data,
+				user_agent,
+				ip,
+				created_at,
+				last_seen_at,
+				expires_at
+		 FROM sessions
+		 WHERE id = ?`,
+		id,
+	).Scan(
+		&s.ID,
+		&userID,
+		&s.Data,
+		&s.UserAgent,
+		&s.IP,
+		&s.CreatedAt,
+		&s.LastSeenAt,
+		&s.ExpiresAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	s.UserID = int(userID.Int64)
+
+	return s, nil
+}
+
+// Update ...
+func (r *SessionRepository) Update(s *model.Session) error {
+
+	res, err := r.store.db.Exec(
+		`UPDATE sessions
+		 SET user_id = ?,
+			 data = ?,
+			 user_agent = ?,
+			 ip = ?,
+			 last_seen_at = ?,
+			 expires_at = ?
+		 WHERE id = ?`,
+		sessionUserID(s),
+		s.Data,
+		s.UserAgent,
+		s.IP,
+		s.LastSeenAt,
+		s.ExpiresAt,
+		s.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+// Delete ...
+func (r *SessionRepository) Delete(id string) error {
+
+	_, err := r.store.db.Exec(
+		`DELETE FROM sessions
+		 WHERE id = ?`,
+		id,
+	)
+
+	return err
+}
+
+// GetByUser returns sessions of user which are not expired yet,
+// recently used ones go first
+func (r *SessionRepository) GetByUser(userID int) ([]*model.Session, error) {
+
+	rows, err := r.store.db.Query(
+		`SELECT id,
+				user_agent,
+				ip,
+				created_at,
+				last_seen_at,
+				expires_at
+		 FROM sessions
+		 WHERE user_id = ? AND expires_at > ?
+		 ORDER BY last_seen_at DESC`,
+		userID,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]*model.Session, 0)
+	for rows.Next() {
+		s := &model.Session{UserID: userID}
+		if err := rows.Scan(
+			&s.ID,
+			&s.UserAgent,
+			&s.IP,
+			&s.CreatedAt,
+			&s.LastSeenAt,
+			&s.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	return sessions, rows.Err()
+}
+
+// DeleteByUser ...
+func (r *SessionRepository) DeleteByUser(userID int) error {
+
+	_, err := r.store.db.Exec(
+		`DELETE FROM sessions
+		 WHERE user_id = ?`,
+		userID,
+	)
+
+	return err
+}
+
+// DeleteExpired removes sessions expired before now
+// and returns number of removed ones
+func (r *SessionRepository) DeleteExpired(now time.Time) (int, error) {
+
+	res, err := r.store.db.Exec(
+		`DELETE FROM sessions
+		 WHERE expires_at <= ?`,
+		now.UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+
+	return int(n), err
+}
+
+// sessionUserID returns user id of session,
+// sessions of anonymous users have no user
+func sessionUserID(s *model.Session) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(s.UserID), Valid: s.UserID > 0}
+}
@@ -0,0 +1,190 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// States of shard, they define placement of users before
+// and after resharding
+const (
+	// ShardActive shard holds users both before and after resharding
+	ShardActive = "active"
+	// ShardJoining shard receives users during resharding
+	ShardJoining = "joining"
+	// ShardLeaving shard gives away all its users during resharding
+	ShardLeaving = "leaving"
+)
+
+// defaultVirtualNodes is number of virtual nodes of every shard on hash ring
+const defaultVirtualNodes = 100
+
+// Shard is a database holding part of users and their friendships
+type Shard struct {
+	Name  string
+	DB    *sql.DB
+	State string
+}
+
+// querier is a database or transaction statements are executed in
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// shards routes user ids to databases. While resharding is in progress
+// previous ring tells where users were placed before it, user is looked up
+// in previous shard first and then in the current one
+type shards struct {
+	dbs      map[string]*sql.DB
+	names    []string
+	current  *ring
+	previous *ring
+}
+
+func newShards(list []*Shard, virtualNodes int) (*shards, error) {
+
+	if virtualNodes <= 0 {
+		virtualNodes = defaultVirtualNodes
+	}
+
+	s := &shards{dbs: make(map[string]*sql.DB, len(list))}
+
+	before, after := make([]string, 0), make([]string, 0)
+	resharding := false
+	for _, shard := range list {
+		if _, ok := s.dbs[shard.Name]; ok {
+			return nil, fmt.Errorf("duplicate shard %q", shard.Name)
+		}
+		s.dbs[shard.Name] = shard.DB
+		s.names = append(s.names, shard.Name)
+
+		switch shard.State {
+		case ShardActive, "":
+			before, after = append(before, shard.Name), append(after, shard.Name)
+		case ShardJoining:
+			after, resharding = append(after, shard.Name), true
+		case ShardLeaving:
+			before, resharding = append(before, shard.Name), true
+		default:
+			return nil, fmt.Errorf("unknown state %q of shard %q", shard.State, shard.Name)
+		}
+	}
+
+	if len(before) == 0 || len(after) == 0 {
+		return nil, fmt.Errorf("no shards to place users on")
+	}
+
+	sort.Strings(s.names)
+	s.current = newRing(virtualNodes, after...)
+	if resharding {
+		s.previous = newRing(virtualNodes, before...)
+	}
+
+	return s, nil
+}
+
+// owner returns database user belongs to after resharding
+func (s *shards) owner(id int) *sql.DB {
+	return s.dbs[s.current.get(id)]
+}
+
+// previousOwner returns database user belonged to before resharding,
+// it is nil if no resharding is in progress or user stays in place
+func (s *shards) previousOwner(id int) *sql.DB {
+
+	if s.previous == nil {
+		return nil
+	}
+
+	name := s.previous.get(id)
+	if name == s.current.get(id) {
+		return nil
+	}
+
+	return s.dbs[name]
+}
+
+// locations returns databases user may be found in,
+// in order they must be looked up
+func (s *shards) locations(id int) []*sql.DB {
+
+	if prev := s.previousOwner(id); prev != nil {
+		return []*sql.DB{prev, s.owner(id)}
+	}
+
+	return []*sql.DB{s.owner(id)}
+}
+
+// withUser calls fn with database holding user. While user is being moved
+// to another shard fn is executed in transaction holding shared lock
+// on user row, so that the user can not be moved away in the middle
+func (s *shards) withUser(id int, fn func(q querier) error) error {
+
+	prev := s.previousOwner(id)
+	if prev == nil {
+		return fn(s.owner(id))
+	}
+
+	found, err := inTx(prev, func(tx *sql.Tx) (bool, error) {
+		var userID int
+		err := tx.QueryRow(`SELECT id FROM users WHERE id = ? LOCK IN SHARE MODE`, id).Scan(&userID)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return true, fn(tx)
+	})
+	if err != nil || found {
+		return err
+	}
+
+	return fn(s.owner(id))
+}
+
+// each calls fn with every shard and its number concurrently
+// and returns the first error occurred
+func (s *shards) each(fn func(i int, db *sql.DB) error) error {
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(s.names))
+	for i, name := range s.names {
+		wg.Add(1)
+		go func(i int, db *sql.DB) {
+			defer wg.Done()
+			errs[i] = fn(i, db)
+		}(i, s.dbs[name])
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// inTx runs fn in transaction, transaction is committed
+// if fn succeeds and is rolled back otherwise
+func inTx(db *sql.DB, fn func(tx *sql.Tx) (bool, error)) (bool, error) {
+
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	ok, err := fn(tx)
+	if err != nil {
+		return false, err
+	}
+
+	return ok, tx.Commit()
+}
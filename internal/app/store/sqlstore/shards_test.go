@@ -0,0 +1,200 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+)
+
+// newShardedMockStore returns store over shard databases
+// stood in by mocks, mocks are returned by names of shards
+func newShardedMockStore(t *testing.T, states map[string]string) (*Store, map[string]sqlmock.Sqlmock) {
+
+	t.Helper()
+
+	open := func() (*sql.DB, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db, mock
+	}
+
+	mainDB, _ := open()
+	mocks := make(map[string]sqlmock.Sqlmock, len(states))
+	list := make([]*Shard, 0, len(states))
+	for name, state := range states {
+		db, mock := open()
+		mocks[name] = mock
+		list = append(list, &Shard{Name: name, DB: db, State: state})
+	}
+
+	s, err := NewSharded(mainDB, list, defaultVirtualNodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, mocks
+}
+
+func checkExpectations(t *testing.T, mocks map[string]sqlmock.Sqlmock) {
+
+	t.Helper()
+
+	for name, mock := range mocks {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("shard %s: %v", name, err)
+		}
+	}
+}
+
+func userRows(users ...*model.User) *sqlmock.Rows {
+
+	rows := sqlmock.NewRows([]string{"id", "name", "surname", "age", "sex", "city", "interests"})
+	for _, u := range users {
+		rows.AddRow(u.ID, u.Name, "", 30, "", "", "")
+	}
+
+	return rows
+}
+
+func TestUserRepository_GetUsersPage_MergesShards(t *testing.T) {
+
+	s, mocks := newShardedMockStore(t, map[string]string{"a": ShardActive, "b": ShardActive, "c": ShardActive})
+	limit := 3
+
+	shardUsers := map[string][]*model.User{
+		"a": {{ID: 4, Name: "Bob"}, {ID: 1, Name: "anna"}},
+		"b": {{ID: 2, Name: "Anna"}, {ID: 6, Name: "Bob"}, {ID: 5, Name: "Carl"}},
+		"c": {},
+	}
+	for name, users := range shardUsers {
+		mocks[name].ExpectQuery(`FROM users\s+ORDER BY name_key ASC, id ASC\s+LIMIT \?`).
+			WithArgs(limit + 1).
+			WillReturnRows(userRows(users...))
+	}
+
+	page, err := s.User().GetUsersPage(nil, nil, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []int{2, 4, 6}
+	if len(page.Users) != len(expected) {
+		t.Fatalf("expected users %v, got %d users", expected, len(page.Users))
+	}
+	for i, u := range page.Users {
+		if u.ID != expected[i] {
+			t.Fatalf("expected user %d at %d, got %d", expected[i], i, u.ID)
+		}
+	}
+	if page.NextCursor == "" {
+		t.Fatal("next page is missing")
+	}
+
+	checkExpectations(t, mocks)
+}
+
+func TestStore_Reshard(t *testing.T) {
+
+	s, mocks := newShardedMockStore(t, map[string]string{"a": ShardActive, "b": ShardActive, "c": ShardJoining})
+
+	// users are stored where the ring without joining shard placed them
+	stored := map[string][]int{"a": {}, "b": {}, "c": {}}
+	for id := 1; id <= 40; id++ {
+		name := s.shards.previous.get(id)
+		stored[name] = append(stored[name], id)
+	}
+
+	expected := make(map[int]string)
+	createdAt := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	for _, name := range []string{"a", "b", "c"} {
+		from := mocks[name]
+		ids := sqlmock.NewRows([]string{"id"})
+		for _, id := range stored[name] {
+			ids.AddRow(id)
+		}
+		from.ExpectQuery(`SELECT id FROM users WHERE id > \? ORDER BY id LIMIT \?`).
+			WithArgs(0, reshardBatchSize).
+			WillReturnRows(ids)
+
+		for _, id := range stored[name] {
+			owner := s.shards.current.get(id)
+			if owner == name {
+				continue
+			}
+			expected[id] = owner
+			to := mocks[owner]
+
+			from.ExpectBegin()
+			from.ExpectQuery(`SELECT email, name, surname`).WithArgs(id).WillReturnRows(
+				sqlmock.NewRows([]string{"email", "name", "surname", "age", "sex", "interests", "city", "encrypted_password", "email_verified_at", "verification_sent_at"}).
+					AddRow("user@example.org", "Anna", "Smith", 30, "female", "", "Moscow", "hash", nil, nil),
+			)
+			from.ExpectQuery(`SELECT friend_id, requester_id, status, created_at`).WithArgs(id).WillReturnRows(
+				sqlmock.NewRows([]string{"friend_id", "requester_id", "status", "created_at"}).
+					AddRow(id+100, id, model.FriendshipPending, createdAt),
+			)
+			to.ExpectBegin()
+			to.ExpectExec(`INSERT INTO users`).WithArgs(append([]driver.Value{id}, anyArgs(10)...)...).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			to.ExpectExec(`INSERT INTO friends`).WithArgs(id, id+100, id, model.FriendshipPending, createdAt).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			to.ExpectCommit()
+			from.ExpectExec(`DELETE FROM friends WHERE user_id = \?`).WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 1))
+			from.ExpectExec(`DELETE FROM users WHERE id = \?`).WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 1))
+			from.ExpectCommit()
+		}
+
+		if len(stored[name]) != 0 {
+			last := stored[name][len(stored[name])-1]
+			from.ExpectQuery(`SELECT id FROM users WHERE id > \?`).
+				WithArgs(last, reshardBatchSize).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		}
+	}
+
+	if len(expected) == 0 {
+		t.Fatal("joining shard gets no users")
+	}
+
+	moved := make(map[int]string)
+	n, err := s.Reshard(func(userID int, from, to string) {
+		moved[userID] = to
+		if from != s.shards.previous.get(userID) {
+			t.Errorf("user %d moved from %s, it is stored in %s", userID, from, s.shards.previous.get(userID))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != len(expected) || len(moved) != len(expected) {
+		t.Fatalf("expected %d moves, got %d", len(expected), n)
+	}
+	for id, to := range expected {
+		if to != "c" {
+			t.Errorf("user %d moved to %s, only joining shard receives users", id, to)
+		}
+		if moved[id] != to {
+			t.Errorf("user %d moved to %q, expected %s", id, moved[id], to)
+		}
+	}
+
+	checkExpectations(t, mocks)
+}
+
+func anyArgs(n int) []driver.Value {
+
+	args := make([]driver.Value, n)
+	for i := range args {
+		args[i] = sqlmock.AnyArg()
+	}
+
+	return args
+}
@@ -3,23 +3,56 @@ package sqlstore
 import (
 	"database/sql"
 
+	"github.com/DalerBakhriev/social_network/internal/app/model"
 	"github.com/DalerBakhriev/social_network/internal/app/store"
 	_ "github.com/go-sql-driver/mysql" // driver import
 )
 
 // Store ..
 type Store struct {
-	db             *sql.DB
-	userRepository *UserRepository
+	db                         *sql.DB
+	shards                     *shards
+	userRepository             *UserRepository
+	postRepository             *PostRepository
+	activityRepository         *ActivityRepository
+	dialogRepository           *DialogRepository
+	notificationRepository     *NotificationRepository
+	sessionRepository          *SessionRepository
+	passwordResetRepository    *PasswordResetRepository
+	twoFactorRepository        *TwoFactorRepository
+	apiTokenRepository         *APITokenRepository
+	externalIdentityRepository *ExternalIdentityRepository
+	loginAttemptRepository     *LoginAttemptRepository
+	auditRepository            *AuditRepository
 }
 
 // New ...
 func New(db *sql.DB) *Store {
+
+	shards, _ := newShards([]*Shard{{Name: "main", DB: db}}, defaultVirtualNodes)
+
 	return &Store{
-		db: db,
+		db:     db,
+		shards: shards,
 	}
 }
 
+// NewSharded returns store keeping users and friendships partitioned
+// across shards by user id, other data and registry of user ids
+// and emails are kept in main database db
+func NewSharded(db *sql.DB, list []*Shard, virtualNodes int) (*Store, error) {
+
+	shards, err := newShards(list, virtualNodes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		db:     db,
+		shards: shards,
+	}, nil
+}
+
 // User returns user repository to work with sql store
 func (s *Store) User() store.UserRepository {
 
@@ -33,3 +66,163 @@ func (s *Store) User() store.UserRepository {
 
 	return s.userRepository
 }
+
+// Post returns post repository to work with sql store
+func (s *Store) Post() store.PostRepository {
+
+	if s.postRepository != nil {
+		return s.postRepository
+	}
+
+	s.postRepository = &PostRepository{
+		store: s,
+	}
+
+	return s.postRepository
+}
+
+// Activity returns activity repository to work with sql store
+func (s *Store) Activity() store.ActivityRepository {
+
+	if s.activityRepository != nil {
+		return s.activityRepository
+	}
+
+	s.activityRepository = &ActivityRepository{
+		store: s,
+	}
+
+	return s.activityRepository
+}
+
+// Dialog returns dialog repository to work with sql store
+func (s *Store) Dialog() store.DialogRepository {
+
+	if s.dialogRepository != nil {
+		return s.dialogRepository
+	}
+
+	s.dialogRepository = &DialogRepository{
+		store: s,
+	}
+
+	return s.dialogRepository
+}
+
+// Notification returns notification repository to work with sql store
+func (s *Store) Notification() store.NotificationRepository {
+
+	if s.notificationRepository != nil {
+		return s.notificationRepository
+	}
+
+	s.notificationRepository = &NotificationRepository{
+		store: s,
+	}
+
+	return s.notificationRepository
+}
+
+// Session returns session repository to work with sql store,
+// sessions are kept in the main database
+func (s *Store) Session() store.SessionRepository {
+
+	if s.sessionRepository != nil {
+		return s.sessionRepository
+	}
+
+	s.sessionRepository = &SessionRepository{
+		store: s,
+	}
+
+	return s.sessionRepository
+}
+
+// PasswordReset returns password reset repository to work with sql store
+func (s *Store) PasswordReset() store.PasswordResetRepository {
+
+	if s.passwordResetRepository != nil {
+		return s.passwordResetRepository
+	}
+
+	s.passwordResetRepository = &PasswordResetRepository{
+		store: s,
+	}
+
+	return s.passwordResetRepository
+}
+
+// TwoFactor returns two-factor repository to work with sql store
+func (s *Store) TwoFactor() store.TwoFactorRepository {
+
+	if s.twoFactorRepository != nil {
+		return s.twoFactorRepository
+	}
+
+	s.twoFactorRepository = &TwoFactorRepository{
+		store: s,
+	}
+
+	return s.twoFactorRepository
+}
+
+// APIToken returns api token repository to work with sql store
+func (s *Store) APIToken() store.APITokenRepository {
+
+	if s.apiTokenRepository != nil {
+		return s.apiTokenRepository
+	}
+
+	s.apiTokenRepository = &APITokenRepository{
+		store: s,
+	}
+
+	return s.apiTokenRepository
+}
+
+// ExternalIdentity returns external identity repository to work with sql store
+func (s *Store) ExternalIdentity() store.ExternalIdentityRepository {
+
+	if s.externalIdentityRepository != nil {
+		return s.externalIdentityRepository
+	}
+
+	s.externalIdentityRepository = &ExternalIdentityRepository{
+		store: s,
+	}
+
+	return s.externalIdentityRepository
+}
+
+// LoginAttempt returns login attempt repository to work with sql store
+func (s *Store) LoginAttempt() store.LoginAttemptRepository {
+
+	if s.loginAttemptRepository != nil {
+		return s.loginAttemptRepository
+	}
+
+	s.loginAttemptRepository = &LoginAttemptRepository{
+		store: s,
+	}
+
+	return s.loginAttemptRepository
+}
+
+// Audit returns audit repository to work with sql store
+func (s *Store) Audit() store.AuditRepository {
+
+	if s.auditRepository != nil {
+		return s.auditRepository
+	}
+
+	s.auditRepository = &AuditRepository{
+		store: s,
+	}
+
+	return s.auditRepository
+}
+
+// users returns users with given ids from their shards
+func (s *Store) users(ids []int) (map[int]*model.User, error) {
+	return s.User().(*UserRepository).findMany(ids)
+}
@@ -0,0 +1,207 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// TwoFactorRepository ...
+type TwoFactorRepository struct {
+	store *Store
+}
+
+// Find ...
+func (r *TwoFactorRepository) Find(userID int) (*model.TwoFactor, error) {
+
+	t := &model.TwoFactor{}
+	if err := r.store.db.QueryRow(
+		`SELECT user_id,
+				secret,
+				confirmed_at,
+				last_used_step,
+				created_at
+		 FROM two_factor
+		 WHERE user_id = ?`,
+		userID,
+	).Scan(
+		&t.UserID,
+		&t.EncryptedSecret,
+		&t.ConfirmedAt,
+		&t.LastUsedStep,
+		&t.CreatedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Create starts enrollment replacing unconfirmed one left before,
+// confirmed enrollment has to be deleted first
+func (r *TwoFactorRepository) Create(t *model.TwoFactor) error {
+
+	tx, err := r.store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// affected rows can not tell unchanged enrollment, connection
+	// reports rows found, so confirmed one is looked up under lock
+	var confirmedAt *time.Time
+	err = tx.QueryRow(
+		`SELECT confirmed_at FROM two_factor WHERE user_id = ? FOR UPDATE`,
+		t.UserID,
+	).Scan(&confirmedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if confirmedAt != nil {
+		return store.ErrTwoFactorAlreadyEnabled
+	}
+
+	t.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	t.ConfirmedAt = nil
+	t.LastUsedStep = 0
+
+	if _, err := tx.Exec(
+		`INSERT INTO two_factor (user_id, secret, created_at)
+		 VALUES (?, ?, ?)
+		 ON DUPLICATE KEY UPDATE
+			secret = VALUES(secret),
+			created_at = VALUES(created_at),
+			confirmed_at = NULL,
+			last_used_step = 0`,
+		t.UserID,
+		t.EncryptedSecret,
+		t.CreatedAt,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Confirm enables enrollment of user and replaces
+// his recovery codes with hashes of new ones
+func (r *TwoFactorRepository) Confirm(userID int, codeHashes []string) error {
+
+	tx, err := r.store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		`UPDATE two_factor
+		 SET confirmed_at = ?
+		 WHERE user_id = ? AND confirmed_at IS NULL`,
+		time.Now().UTC().Truncate(time.Second),
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := checkAffected(res); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(
+			`INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)`,
+			userID,
+			hash,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseStep remembers time step of code user logged in with,
+// codes of the same or earlier steps are rejected afterwards
+func (r *TwoFactorRepository) UseStep(userID int, step int64) error {
+
+	res, err := r.store.db.Exec(
+		`UPDATE two_factor
+		 SET last_used_step = ?
+		 WHERE user_id = ? AND last_used_step < ?`,
+		step,
+		userID,
+		step,
+	)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+// UseRecoveryCode marks code of user as used, each code works once
+func (r *TwoFactorRepository) UseRecoveryCode(userID int, codeHash string) error {
+
+	res, err := r.store.db.Exec(
+		`UPDATE recovery_codes
+		 SET used_at = ?
+		 WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`,
+		time.Now().UTC().Truncate(time.Second),
+		userID,
+		codeHash,
+	)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+// CountRecoveryCodes returns number of unused recovery codes of user
+func (r *TwoFactorRepository) CountRecoveryCodes(userID int) (int, error) {
+
+	var count int
+	if err := r.store.db.QueryRow(
+		`SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL`,
+		userID,
+	).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Delete turns two-factor authentication of user off
+func (r *TwoFactorRepository) Delete(userID int) error {
+
+	tx, err := r.store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM two_factor WHERE user_id = ?`, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkAffected(res); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
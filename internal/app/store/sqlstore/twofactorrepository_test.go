@@ -0,0 +1,43 @@
+package sqlstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+func TestTwoFactorRepository_CreateAfterConfirm(t *testing.T) {
+
+	s, mock := newMockStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT confirmed_at FROM two_factor WHERE user_id = \? FOR UPDATE`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"confirmed_at"}))
+	mock.ExpectExec(`INSERT INTO two_factor`).
+		WithArgs(1, "first", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := s.TwoFactor().Create(&model.TwoFactor{UserID: 1, EncryptedSecret: "first"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// enrollment is confirmed, row is found but it must not change
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT confirmed_at FROM two_factor WHERE user_id = \? FOR UPDATE`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"confirmed_at"}).AddRow(time.Now()))
+	mock.ExpectRollback()
+
+	if err := s.TwoFactor().Create(&model.TwoFactor{UserID: 1, EncryptedSecret: "second"}); err != store.ErrTwoFactorAlreadyEnabled {
+		t.Fatalf("expected error %v, got %v", store.ErrTwoFactorAlreadyEnabled, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,26 +2,59 @@ package sqlstore
 
 import (
 	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/DalerBakhriev/social_network/internal/app/model"
 	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"github.com/go-sql-driver/mysql"
 )
 
+// errDuplicateEntry is mysql error number of unique key violation
+const errDuplicateEntry = 1062
+
 // UserRepository ...
 type UserRepository struct {
 	store *Store
 }
 
-// Create ...
+// Create registers id and email of user in main database
+// and saves user in shard the id belongs to
 func (r *UserRepository) Create(u *model.User) error {
 
 	if err := u.BeforeCreate(); err != nil {
 		return err
 	}
 
-	_, err := r.store.db.Exec(
-		`INSERT INTO users (email, name, surname, age, sex, interests, city, encrypted_password)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+	if u.Role == "" {
+		u.Role = model.RoleUser
+	}
+	u.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	res, err := r.store.db.Exec(
+		`INSERT INTO user_ids (email, role, created_at) VALUES (?, ?, ?)`,
+		u.Email,
+		u.Role,
+		u.CreatedAt,
+	)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			return store.ErrEmailAlreadyExists
+		}
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.store.shards.owner(int(id)).Exec(
+		`INSERT INTO users (id, email, name, surname, age, sex, interests, city, encrypted_password, email_verified_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id,
 		u.Email,
 		u.Name,
 		u.Surname,
@@ -30,39 +63,29 @@ func (r *UserRepository) Create(u *model.User) error {
 		u.Interests,
 		u.City,
 		u.EncryptedPassword,
-	)
+		u.EmailVerifiedAt,
+	); err != nil {
+		if isDuplicateEntry(err) {
+			err = store.ErrEmailAlreadyExists
+		}
+		if _, delErr := r.store.db.Exec(`DELETE FROM user_ids WHERE id = ?`, id); delErr != nil {
+			return fmt.Errorf("%w, id %d is left registered: %v", err, id, delErr)
+		}
+		return err
+	}
+	u.ID = int(id)
 
-	return err
+	return nil
 }
 
 // FindByEmail ...
 func (r *UserRepository) FindByEmail(email string) (*model.User, error) {
 
-	u := &model.User{}
+	var id int
 	if err := r.store.db.QueryRow(
-		`SELECT id,
-				email,
-				name,
-				surname,
-				age,
-				sex,
-				interests,
-				city,
-				encrypted_password
-		 FROM users
-		 WHERE email = ?`,
+		`SELECT id FROM user_ids WHERE email = ?`,
 		email,
-	).Scan(
-		&u.ID,
-		&u.Email,
-		&u.Name,
-		&u.Surname,
-		&u.Age,
-		&u.Sex,
-		&u.Interests,
-		&u.City,
-		&u.EncryptedPassword,
-	); err != nil {
+	).Scan(&id); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, store.ErrRecordNotFound
 		}
@@ -70,45 +93,81 @@ func (r *UserRepository) FindByEmail(email string) (*model.User, error) {
 		return nil, err
 	}
 
-	return u, nil
+	return r.Find(id)
 }
 
 // Find ...
 func (r *UserRepository) Find(id int) (*model.User, error) {
 
-	u := &model.User{}
+	for _, db := range r.store.shards.locations(id) {
+		u := &model.User{}
+		var verifiedAt sql.NullTime
+		err := db.QueryRow(
+			`SELECT id,
+					email,
+					name,
+					surname,
+					age,
+					sex,
+					interests,
+					city,
+					encrypted_password,
+					email_verified_at
+			 FROM users
+			 WHERE id = ?`,
+			id,
+		).Scan(
+			&u.ID,
+			&u.Email,
+			&u.Name,
+			&u.Surname,
+			&u.Age,
+			&u.Sex,
+			&u.Interests,
+			&u.City,
+			&u.EncryptedPassword,
+			&verifiedAt,
+		)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if verifiedAt.Valid {
+			u.EmailVerifiedAt = &verifiedAt.Time
+		}
+		if err := r.findAccount(u); err != nil {
+			return nil, err
+		}
+
+		return u, nil
+	}
+
+	return nil, store.ErrRecordNotFound
+}
+
+// findAccount fills role, suspension and sign up time
+// of user kept in registry of the main database
+func (r *UserRepository) findAccount(u *model.User) error {
+
+	var suspendedAt sql.NullTime
 	if err := r.store.db.QueryRow(
-		`SELECT id,
-				email,
-				name,
-				surname,
-				age,
-				sex,
-				interests,
-				city,
-				encrypted_password
-		 FROM users
+		`SELECT role, suspended_at, created_at
+		 FROM user_ids
 		 WHERE id = ?`,
-		id,
-	).Scan(
-		&u.ID,
-		&u.Email,
-		&u.Name,
-		&u.Surname,
-		&u.Age,
-		&u.Sex,
-		&u.Interests,
-		&u.City,
-		&u.EncryptedPassword,
-	); err != nil {
+		u.ID,
+	).Scan(&u.Role, &suspendedAt, &u.CreatedAt); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, store.ErrRecordNotFound
+			return store.ErrRecordNotFound
 		}
-
-		return nil, err
+		return err
+	}
+	if suspendedAt.Valid {
+		u.SuspendedAt = &suspendedAt.Time
 	}
 
-	return u, nil
+	return nil
 }
 
 // Update ...
@@ -118,90 +177,354 @@ func (r *UserRepository) Update(u *model.User) error {
 		return err
 	}
 
-	_, err := r.store.db.Exec(
-		`UPDATE users
-		 SET name = ?,
-			 surname = ?,
-			 age = ?,
-			 sex = ?,
-			 interests = ?,
-			 city = ?
-		 WHERE id = ?`,
-		u.Name,
-		u.Surname,
-		u.Age,
-		u.Sex,
-		u.Interests,
-		u.City,
-		u.ID,
+	return r.store.shards.withUser(u.ID, func(q querier) error {
+		_, err := q.Exec(
+			`UPDATE users
+			 SET name = ?,
+				 surname = ?,
+				 age = ?,
+				 sex = ?,
+				 interests = ?,
+				 city = ?
+			 WHERE id = ?`,
+			u.Name,
+			u.Surname,
+			u.Age,
+			u.Sex,
+			u.Interests,
+			u.City,
+			u.ID,
+		)
+		return err
+	})
+}
+
+// UpdatePassword sets encrypted password of user to new password
+func (r *UserRepository) UpdatePassword(u *model.User) error {
+
+	if len(u.Password) == 0 {
+		return model.ErrEmptyPassword
+	}
+
+	if err := u.BeforeCreate(); err != nil {
+		return err
+	}
+
+	return r.store.shards.withUser(u.ID, func(q querier) error {
+		res, err := q.Exec(
+			`UPDATE users
+			 SET encrypted_password = ?
+			 WHERE id = ?`,
+			u.EncryptedPassword,
+			u.ID,
+		)
+		if err != nil {
+			return err
+		}
+		return checkAffected(res)
+	})
+}
+
+// VerifyEmail marks email of user as verified,
+// it fails when user has changed email since then
+func (r *UserRepository) VerifyEmail(id int, email string) error {
+
+	return r.store.shards.withUser(id, func(q querier) error {
+		res, err := q.Exec(
+			`UPDATE users
+			 SET email_verified_at = COALESCE(email_verified_at, ?)
+			 WHERE id = ? AND email = ?`,
+			time.Now().UTC().Truncate(time.Second),
+			id,
+			email,
+		)
+		if err != nil {
+			return err
+		}
+		return checkAffected(res)
+	})
+}
+
+// MarkVerificationSent records that verification email is sent to user,
+// it returns false when user is verified or got one within cooldown
+func (r *UserRepository) MarkVerificationSent(id int, cooldown time.Duration) (bool, error) {
+
+	now := time.Now().UTC().Truncate(time.Second)
+	marked := false
+	err := r.store.shards.withUser(id, func(q querier) error {
+		res, err := q.Exec(
+			`UPDATE users
+			 SET verification_sent_at = ?
+			 WHERE id = ?
+				AND email_verified_at IS NULL
+				AND (verification_sent_at IS NULL OR verification_sent_at <= ?)`,
+			now,
+			id,
+			now.Add(-cooldown),
+		)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		marked = n > 0
+		return err
+	})
+
+	return marked, err
+}
+
+// MarkPasswordResetSent records that password reset email is sent
+// to user, it returns false when user got one within cooldown
+func (r *UserRepository) MarkPasswordResetSent(id int, cooldown time.Duration) (bool, error) {
+
+	now := time.Now().UTC().Truncate(time.Second)
+	res, err := r.store.db.Exec(
+		`UPDATE user_ids
+		 SET password_reset_sent_at = ?
+		 WHERE id = ?
+			AND (password_reset_sent_at IS NULL OR password_reset_sent_at <= ?)`,
+		now,
+		id,
+		now.Add(-cooldown),
 	)
+	if err != nil {
+		return false, err
+	}
 
-	return err
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
 }
 
-// GetTopUsers ...
-func (r *UserRepository) GetTopUsers(n int) ([]*model.User, error) {
+// GetUsersPage returns page of users matching filter ordered by name and id
+func (r *UserRepository) GetUsersPage(filter *model.UserFilter, cursor *model.Cursor, limit int) (*model.UsersPage, error) {
 
-	rows, err := r.store.db.Query(
-		`SELECT id,
-		        name,
-				surname,
-				sex,
-				age,
-				city,
-				interests
-		 FROM users
-		 ORDER BY name
-		 limit ?`,
-		n,
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+
+	if filter != nil {
+		if filter.City != "" {
+			conditions = append(conditions, "city = ?")
+			args = append(args, filter.City)
+		}
+		if filter.Sex != "" {
+			conditions = append(conditions, "sex = ?")
+			args = append(args, filter.Sex)
+		}
+		if filter.MinAge > 0 {
+			conditions = append(conditions, "age >= ?")
+			args = append(args, filter.MinAge)
+		}
+		if filter.MaxAge > 0 {
+			conditions = append(conditions, "age <= ?")
+			args = append(args, filter.MaxAge)
+		}
+		if filter.Interests != "" {
+			conditions = append(conditions, "interests LIKE ?")
+			args = append(args, "%"+escapeLike(filter.Interests)+"%")
+		}
+	}
+
+	return r.queryUsersPage(conditions, args, cursor, limit)
+}
+
+// Search returns page of users whose name and surname start
+// with given prefixes ordered by name and id
+func (r *UserRepository) Search(firstPrefix, lastPrefix string, limit int, cursor *model.Cursor) (*model.UsersPage, error) {
+
+	// surname is nullable and NULL matches no pattern,
+	// so empty prefix adds no condition instead of LIKE '%'
+	conditions := make([]string, 0, 2)
+	args := make([]interface{}, 0, 2)
+	if firstPrefix != "" {
+		conditions = append(conditions, "name LIKE ?")
+		args = append(args, escapeLike(firstPrefix)+"%")
+	}
+	if lastPrefix != "" {
+		conditions = append(conditions, "surname LIKE ?")
+		args = append(args, escapeLike(lastPrefix)+"%")
+	}
+
+	return r.queryUsersPage(conditions, args, cursor, limit)
+}
+
+// queryUsersPage selects page of users satisfying conditions,
+// pages are selected by keyset of the last seen user instead of offset
+func (r *UserRepository) queryUsersPage(conditions []string, args []interface{}, cursor *model.Cursor, limit int) (*model.UsersPage, error) {
+
+	order := "ASC"
+	if cursor != nil {
+		if cursor.Backward {
+			conditions = append(conditions, "(name_key < ? OR (name_key = ? AND id < ?))")
+			order = "DESC"
+		} else {
+			conditions = append(conditions, "(name_key > ? OR (name_key = ? AND id > ?))")
+		}
+		args = append(args, cursor.Name, cursor.Name, cursor.ID)
+	}
+
+	where := ""
+	if len(conditions) != 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit+1)
+
+	lists, err := r.scatter(
+		fmt.Sprintf(
+			`SELECT `+userColumns+`
+			 FROM users
+			 %s
+			 ORDER BY name_key %s, id %s
+			 LIMIT ?`,
+			where, order, order,
+		),
+		args...,
 	)
+	if err != nil {
+		return nil, err
+	}
 
+	users := mergeUsers(lists, cursor != nil && cursor.Backward, limit+1)
+	if cursor != nil && cursor.Backward {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+
+	return model.NewUsersPage(users, cursor, limit), nil
+}
+
+// GetFriendsList ...
+func (r *UserRepository) GetFriendsList(id int) ([]*model.User, error) {
+	return r.queryFriends(id, `status = ?`, model.FriendshipAccepted)
+}
+
+// GetIncomingFriendsRequests returns users who sent friend request to user
+func (r *UserRepository) GetIncomingFriendsRequests(id int) ([]*model.User, error) {
+	return r.queryFriends(id, `status = ? AND requester_id = friend_id`, model.FriendshipPending)
+}
+
+// GetOutgoingFriendsRequests returns users whom user sent friend request
+func (r *UserRepository) GetOutgoingFriendsRequests(id int) ([]*model.User, error) {
+	return r.queryFriends(id, `status = ? AND requester_id = user_id`, model.FriendshipPending)
+}
+
+// queryFriends selects users linked with rows of friends table
+// of user satisfying condition
+func (r *UserRepository) queryFriends(id int, condition string, args ...interface{}) ([]*model.User, error) {
+
+	ids := make([]int, 0)
+	if err := r.store.shards.withUser(id, func(q querier) error {
+		rows, err := q.Query(
+			`SELECT friend_id
+			 FROM friends
+			 WHERE user_id = ? AND `+condition+`
+			 ORDER BY friend_id`,
+			append([]interface{}{id}, args...)...,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var friendID int
+			if err := rows.Scan(&friendID); err != nil {
+				return err
+			}
+			ids = append(ids, friendID)
+		}
+
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	found, err := r.findMany(ids)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	users := make([]*model.User, 0)
-	for rows.Next() {
+	users := make([]*model.User, 0, len(ids))
+	for _, id := range ids {
+		if u, ok := found[id]; ok {
+			users = append(users, u)
+		}
+	}
 
-		user := &model.User{}
-		if err := rows.Scan(
-			&user.ID,
-			&user.Name,
-			&user.Surname,
-			&user.Sex,
-			&user.Age,
-			&user.City,
-			&user.Interests,
-		); err != nil {
-			return nil, err
+	return users, nil
+}
+
+// findMany returns users with given ids from all shards
+// they are placed in, missing users are skipped
+func (r *UserRepository) findMany(ids []int) (map[int]*model.User, error) {
+
+	users := make(map[int]*model.User, len(ids))
+	pending := ids
+	for attempt := 0; len(pending) > 0; attempt++ {
+
+		groups := make(map[*sql.DB][]interface{})
+		for _, id := range pending {
+			if locations := r.store.shards.locations(id); attempt < len(locations) {
+				groups[locations[attempt]] = append(groups[locations[attempt]], id)
+			}
+		}
+		if len(groups) == 0 {
+			break
 		}
 
-		users = append(users, user)
+		for db, group := range groups {
+			found, err := queryUsers(
+				db,
+				`SELECT `+userColumns+`
+				 FROM users
+				 WHERE id IN (`+placeholders(len(group))+`)`,
+				group...,
+			)
+			if err != nil {
+				return nil, err
+			}
+			for _, u := range found {
+				users[u.ID] = u
+			}
+		}
+
+		missing := make([]int, 0)
+		for _, id := range pending {
+			if _, ok := users[id]; !ok {
+				missing = append(missing, id)
+			}
+		}
+		pending = missing
 	}
 
 	return users, nil
 }
 
-// GetFriendsList ...
-func (r *UserRepository) GetFriendsList(id int) ([]*model.User, error) {
+// scatter runs query in all shards concurrently
+// and returns users selected from every shard
+func (r *UserRepository) scatter(query string, args ...interface{}) ([][]*model.User, error) {
 
-	rows, err := r.store.db.Query(
-		`SELECT id,
-		        name,
-				surname,
-				age,
-				sex,
-				city,
-				interests
-		 FROM users
-		 WHERE id IN (SELECT friend_id
-					  FROM friends
-					  WHERE user_id = ?
-					    AND is_accepted = true)`,
-		id,
-	)
+	lists := make([][]*model.User, len(r.store.shards.names))
+	err := r.store.shards.each(func(i int, db *sql.DB) error {
+		users, err := queryUsers(db, query, args...)
+		lists[i] = users
+		return err
+	})
+
+	return lists, err
+}
+
+// userColumns are columns of users table selected by queryUsers
+const userColumns = `id, name, surname, age, sex, city, interests`
+
+// queryUsers selects users with userColumns by query
+func queryUsers(q querier, query string, args ...interface{}) ([]*model.User, error) {
 
+	rows, err := q.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -224,98 +547,424 @@ func (r *UserRepository) GetFriendsList(id int) ([]*model.User, error) {
 		users = append(users, user)
 	}
 
-	return users, nil
+	return users, rows.Err()
+}
+
+// mergeUsers merges lists of users ordered by name_key and id into one list
+// of at most n users, users found in several shards are taken once
+func mergeUsers(lists [][]*model.User, desc bool, n int) []*model.User {
+
+	users := make([]*model.User, 0)
+	seen := make(map[int]bool)
+	for _, list := range lists {
+		for _, u := range list {
+			if !seen[u.ID] {
+				seen[u.ID] = true
+				users = append(users, u)
+			}
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		if desc {
+			return compareUsers(users[j], users[i]) < 0
+		}
+		return compareUsers(users[i], users[j]) < 0
+	})
+
+	if len(users) > n {
+		users = users[:n]
+	}
+
+	return users
+}
+
+// compareUsers compares users by name_key and id, name_key
+// is the name compared byte by byte as strings.Compare does
+func compareUsers(a, b *model.User) int {
+
+	if a.Name != b.Name {
+		return strings.Compare(a.Name, b.Name)
+	}
+
+	return a.ID - b.ID
 }
 
-// GetFriendsRequests ...
-func (r *UserRepository) GetFriendsRequests(id int) ([]*model.User, error) {
+// GetAccounts returns page of accounts with email starting with emailPrefix
+// ordered from the latest signed up, beforeID selects older page
+func (r *UserRepository) GetAccounts(emailPrefix string, beforeID, limit int) (*model.AccountsPage, error) {
+
+	conditions := make([]string, 0, 2)
+	args := make([]interface{}, 0, 3)
+	if emailPrefix != "" {
+		conditions = append(conditions, "email LIKE ?")
+		args = append(args, escapeLike(emailPrefix)+"%")
+	}
+	if beforeID > 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, beforeID)
+	}
+
+	where := ""
+	if len(conditions) != 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit+1)
 
 	rows, err := r.store.db.Query(
-		`SELECT id,
-		        name,
-				surname,
-				age,
-				sex,
-				city,
-				interests
-		 FROM users
-		 WHERE id IN (SELECT friend_id
-					  FROM friends
-					  WHERE user_id = ?
-					    AND is_accepted = false)`,
-		id,
+		`SELECT id, email, role, suspended_at, created_at
+		 FROM user_ids
+		 `+where+`
+		 ORDER BY id DESC
+		 LIMIT ?`,
+		args...,
 	)
-
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	users := make([]*model.User, 0)
+	ids := make([]int, 0)
 	for rows.Next() {
-		user := &model.User{}
-		if err := rows.Scan(
-			&user.ID,
-			&user.Name,
-			&user.Surname,
-			&user.Age,
-			&user.Sex,
-			&user.City,
-			&user.Interests,
-		); err != nil {
+		u := &model.User{}
+		var suspendedAt sql.NullTime
+		if err := rows.Scan(&u.ID, &u.Email, &u.Role, &suspendedAt, &u.CreatedAt); err != nil {
 			return nil, err
 		}
-		users = append(users, user)
+		if suspendedAt.Valid {
+			u.SuspendedAt = &suspendedAt.Time
+		}
+		users = append(users, u)
+		ids = append(ids, u.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return users, nil
+	profiles, err := r.findMany(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		if profile, ok := profiles[u.ID]; ok {
+			u.Name = profile.Name
+			u.Surname = profile.Surname
+		}
+	}
+
+	return model.NewAccountsPage(users, limit), nil
 }
 
-// RequestWasAlreadySent ...
+// SetRole ...
+func (r *UserRepository) SetRole(id int, role string) error {
+
+	if err := model.ValidateRole(role); err != nil {
+		return err
+	}
+
+	res, err := r.store.db.Exec(
+		`UPDATE user_ids SET role = ? WHERE id = ?`,
+		role,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+// SetSuspended suspends user since suspendedAt, nil lifts suspension
+func (r *UserRepository) SetSuspended(id int, suspendedAt *time.Time) error {
+
+	res, err := r.store.db.Exec(
+		`UPDATE user_ids SET suspended_at = ? WHERE id = ?`,
+		suspendedAt,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+// deleteUserStatements are statements deleting rows of user from tables
+// of the main database, user id is passed to every placeholder
+var deleteUserStatements = []string{
+	`DELETE FROM posts WHERE author_id = ?`,
+	`DELETE FROM activities WHERE actor_id = ? OR subject_id = ?`,
+	`DELETE FROM messages WHERE from_id = ? OR to_id = ?`,
+	`DELETE FROM notifications WHERE user_id = ? OR actor_id = ?`,
+	`DELETE FROM sessions WHERE user_id = ?`,
+	`DELETE FROM password_resets WHERE user_id = ?`,
+	`DELETE FROM recovery_codes WHERE user_id = ?`,
+	`DELETE FROM two_factor WHERE user_id = ?`,
+	`DELETE FROM api_tokens WHERE user_id = ?`,
+	`DELETE FROM external_identities WHERE user_id = ?`,
+	`DELETE FROM login_attempts WHERE attempt_key = CONCAT('user:', ?)`,
+}
+
+// Delete removes user with friendships from all shards and then user with
+// everything user created from the main database in transaction. Shards go
+// first, so that failed delete leaves user who can be deleted again
+// instead of rows nobody owns
+func (r *UserRepository) Delete(id int) error {
+
+	if err := r.store.shards.each(func(i int, db *sql.DB) error {
+		if _, err := db.Exec(`DELETE FROM friends WHERE user_id = ? OR friend_id = ?`, id, id); err != nil {
+			return err
+		}
+		_, err := db.Exec(`DELETE FROM users WHERE id = ?`, id)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	_, err := inTx(r.store.db, func(tx *sql.Tx) (bool, error) {
+		for _, statement := range deleteUserStatements {
+			args := make([]interface{}, strings.Count(statement, "?"))
+			for i := range args {
+				args[i] = id
+			}
+			if _, err := tx.Exec(statement, args...); err != nil {
+				return false, err
+			}
+		}
+
+		res, err := tx.Exec(`DELETE FROM user_ids WHERE id = ?`, id)
+		if err != nil {
+			return false, err
+		}
+		return true, checkAffected(res)
+	})
+
+	return err
+}
+
+// RequestWasAlreadySent tells whether users are already
+// linked by friend request or friendship
 func (r *UserRepository) RequestWasAlreadySent(fromID, toID int) bool {
 
-	var userID, friendID int
-	err := r.store.db.QueryRow(
-		`SELECT user_id, friend_id
-		 FROM friends
-		 WHERE user_id = ? AND friend_id = ?`,
-		fromID,
-		toID,
-	).Scan(&userID, &friendID)
+	err := r.store.shards.withUser(fromID, func(q querier) error {
+		var userID, friendID int
+		return q.QueryRow(
+			`SELECT user_id, friend_id
+			 FROM friends
+			 WHERE user_id = ? AND friend_id = ?`,
+			fromID,
+			toID,
+		).Scan(&userID, &friendID)
+	})
+
+	return err == nil
+}
 
+// AreFriends tells whether users are accepted friends
+func (r *UserRepository) AreFriends(userID, friendID int) (bool, error) {
+
+	var n int
+	err := r.store.shards.withUser(userID, func(q querier) error {
+		return q.QueryRow(
+			`SELECT COUNT(*)
+			 FROM friends
+			 WHERE user_id = ? AND friend_id = ? AND status = ?`,
+			userID,
+			friendID,
+			model.FriendshipAccepted,
+		).Scan(&n)
+	})
 	if err != nil {
-		return false
+		return false, err
 	}
 
-	return true
+	return n > 0, nil
 }
 
-// SendFriendRequest ...
+// SendFriendRequest saves row of request in shard of sender and its copy
+// in shard of addressee. Row of sender is the source of truth: when it
+// exists already, missing copy left by failed request is restored from it,
+// so failed request completes when it is sent again
 func (r *UserRepository) SendFriendRequest(fromID, toID int) error {
 
-	if requestwasAlreadySent := r.RequestWasAlreadySent(fromID, toID); requestwasAlreadySent {
+	created, err := r.insertFriendship(fromID, toID, fromID, model.FriendshipPending)
+	if err != nil {
+		return err
+	}
+
+	requesterID, status := fromID, model.FriendshipPending
+	if !created {
+		if requesterID, status, err = r.findFriendship(fromID, toID); err != nil {
+			return err
+		}
+		// row is a copy of request sent to the sender
+		if requesterID != fromID {
+			return store.ErrFriendRequestWasAlreadySent
+		}
+	}
+
+	copied, err := r.insertFriendship(toID, fromID, requesterID, status)
+	if err != nil {
+		if !created {
+			return err
+		}
+		if delErr := r.deleteFriendship(fromID, toID); delErr != nil {
+			return fmt.Errorf("%w, request row of user %d is left: %v", err, fromID, delErr)
+		}
+		return err
+	}
+
+	if !created && !copied {
 		return store.ErrFriendRequestWasAlreadySent
 	}
 
-	_, err := r.store.db.Exec(
-		`INSERT INTO friends (user_id, friend_id, is_accepted)
-		 VALUES (?, ?, ?), (?, ?, ?)`,
-		fromID, toID, false,
-		toID, fromID, false,
-	)
+	return nil
+}
 
-	return err
+// insertFriendship saves row of friends table in shard of user,
+// it returns false when the row exists already
+func (r *UserRepository) insertFriendship(userID, friendID, requesterID int, status string) (bool, error) {
+
+	err := r.store.shards.withUser(userID, func(q querier) error {
+		_, err := q.Exec(
+			`INSERT INTO friends (user_id, friend_id, requester_id, status)
+			 VALUES (?, ?, ?, ?)`,
+			userID, friendID, requesterID, status,
+		)
+		return err
+	})
+	if isDuplicateEntry(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// findFriendship returns requester and status of row of friends table
+func (r *UserRepository) findFriendship(userID, friendID int) (int, string, error) {
+
+	var requesterID int
+	var status string
+	err := r.store.shards.withUser(userID, func(q querier) error {
+		return q.QueryRow(
+			`SELECT requester_id, status FROM friends WHERE user_id = ? AND friend_id = ?`,
+			userID, friendID,
+		).Scan(&requesterID, &status)
+	})
+	if err == sql.ErrNoRows {
+		return 0, "", store.ErrRecordNotFound
+	}
+
+	return requesterID, status, err
 }
 
-// AcceptFriendRequest ...
-func (r *UserRepository) AcceptFriendRequest(fromID, toID int) error {
+// deleteFriendship removes row of friends table from shard of user
+func (r *UserRepository) deleteFriendship(userID, friendID int) error {
 
-	_, err := r.store.db.Exec(
-		`UPDATE friends
-		 SET is_accepted = true
-		 WHERE user_id IN (?, ?) AND friend_id IN (?, ?)`,
-		fromID, toID, toID, fromID,
-	)
+	return r.store.shards.withUser(userID, func(q querier) error {
+		_, err := q.Exec(`DELETE FROM friends WHERE user_id = ? AND friend_id = ?`, userID, friendID)
+		return err
+	})
+}
 
-	return err
+// AcceptFriendRequest accepts request sent by requester to user,
+// only the addressee of request can accept it
+func (r *UserRepository) AcceptFriendRequest(userID, requesterID int) error {
+
+	return r.execFriendship(userID, requesterID, func(userID, friendID int) (string, []interface{}) {
+		return `UPDATE friends
+				SET status = ?
+				WHERE user_id = ? AND friend_id = ?
+				  AND requester_id = ? AND status = ?`,
+			[]interface{}{
+				model.FriendshipAccepted,
+				userID, friendID,
+				requesterID, model.FriendshipPending,
+			}
+	})
+}
+
+// DeclineFriendRequest removes request sent by requester to user
+func (r *UserRepository) DeclineFriendRequest(userID, requesterID int) error {
+	return r.deletePendingRequest(requesterID, userID)
+}
+
+// CancelFriendRequest removes request sent by user
+func (r *UserRepository) CancelFriendRequest(fromID, toID int) error {
+	return r.deletePendingRequest(fromID, toID)
+}
+
+func (r *UserRepository) deletePendingRequest(fromID, toID int) error {
+
+	return r.execFriendship(fromID, toID, func(userID, friendID int) (string, []interface{}) {
+		return `DELETE FROM friends
+				WHERE user_id = ? AND friend_id = ?
+				  AND requester_id = ? AND status = ?`,
+			[]interface{}{userID, friendID, fromID, model.FriendshipPending}
+	})
+}
+
+// RemoveFriend removes accepted friendship between users
+func (r *UserRepository) RemoveFriend(userID, friendID int) error {
+
+	return r.execFriendship(userID, friendID, func(userID, friendID int) (string, []interface{}) {
+		return `DELETE FROM friends
+				WHERE user_id = ? AND friend_id = ? AND status = ?`,
+			[]interface{}{userID, friendID, model.FriendshipAccepted}
+	})
+}
+
+// execFriendship executes statement built by statement func for both rows
+// of friendship, each in shard of user owning the row. It returns
+// ErrRecordNotFound if statement changed no rows
+func (r *UserRepository) execFriendship(userID, friendID int, statement func(userID, friendID int) (string, []interface{})) error {
+
+	var affected int64
+	for _, ids := range [][2]int{{userID, friendID}, {friendID, userID}} {
+		query, args := statement(ids[0], ids[1])
+		if err := r.store.shards.withUser(ids[0], func(q querier) error {
+			res, err := q.Exec(query, args...)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			affected += n
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	if affected == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// checkAffected returns ErrRecordNotFound when statement changed no rows
+func checkAffected(res sql.Result) error {
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// isDuplicateEntry tells whether err is violation of unique key
+func isDuplicateEntry(err error) bool {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	return ok && mysqlErr.Number == errDuplicateEntry
+}
+
+// escapeLike escapes wildcard characters of LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
@@ -0,0 +1,295 @@
+package sqlstore
+
+import (
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+	"github.com/go-sql-driver/mysql"
+)
+
+var errDuplicate = &mysql.MySQLError{Number: errDuplicateEntry, Message: "Duplicate entry"}
+
+func newMockStore(t *testing.T) (*Store, sqlmock.Sqlmock) {
+
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return New(db), mock
+}
+
+func expectFriendshipInsert(mock sqlmock.Sqlmock, userID, friendID, requesterID int) *sqlmock.ExpectedExec {
+	return mock.ExpectExec(`INSERT INTO friends`).
+		WithArgs(userID, friendID, requesterID, model.FriendshipPending)
+}
+
+func TestUserRepository_SendFriendRequest(t *testing.T) {
+
+	testCases := []struct {
+		name   string
+		expect func(mock sqlmock.Sqlmock)
+		err    error
+	}{
+		{
+			name: "new request",
+			expect: func(mock sqlmock.Sqlmock) {
+				expectFriendshipInsert(mock, 1, 2, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectFriendshipInsert(mock, 2, 1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "copy left by failed request is restored",
+			expect: func(mock sqlmock.Sqlmock) {
+				expectFriendshipInsert(mock, 1, 2, 1).WillReturnError(errDuplicate)
+				mock.ExpectQuery(`SELECT requester_id, status FROM friends`).
+					WithArgs(1, 2).
+					WillReturnRows(sqlmock.NewRows([]string{"requester_id", "status"}).AddRow(1, model.FriendshipPending))
+				expectFriendshipInsert(mock, 2, 1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "request was already sent",
+			expect: func(mock sqlmock.Sqlmock) {
+				expectFriendshipInsert(mock, 1, 2, 1).WillReturnError(errDuplicate)
+				mock.ExpectQuery(`SELECT requester_id, status FROM friends`).
+					WithArgs(1, 2).
+					WillReturnRows(sqlmock.NewRows([]string{"requester_id", "status"}).AddRow(1, model.FriendshipPending))
+				expectFriendshipInsert(mock, 2, 1, 1).WillReturnError(errDuplicate)
+			},
+			err: store.ErrFriendRequestWasAlreadySent,
+		},
+		{
+			name: "addressee has sent request to sender",
+			expect: func(mock sqlmock.Sqlmock) {
+				expectFriendshipInsert(mock, 1, 2, 1).WillReturnError(errDuplicate)
+				mock.ExpectQuery(`SELECT requester_id, status FROM friends`).
+					WithArgs(1, 2).
+					WillReturnRows(sqlmock.NewRows([]string{"requester_id", "status"}).AddRow(2, model.FriendshipPending))
+			},
+			err: store.ErrFriendRequestWasAlreadySent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, mock := newMockStore(t)
+			tc.expect(mock)
+
+			if err := s.User().SendFriendRequest(1, 2); err != tc.err {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestUserRepository_SendFriendRequest_ReportsFailedRollback(t *testing.T) {
+
+	s, mock := newMockStore(t)
+	errCopy := errors.New("copy failed")
+	errRollback := errors.New("rollback failed")
+
+	expectFriendshipInsert(mock, 1, 2, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectFriendshipInsert(mock, 2, 1, 1).WillReturnError(errCopy)
+	mock.ExpectExec(`DELETE FROM friends`).WithArgs(1, 2).WillReturnError(errRollback)
+
+	err := s.User().SendFriendRequest(1, 2)
+	if !errors.Is(err, errCopy) {
+		t.Fatalf("expected error wrapping %v, got %v", errCopy, err)
+	}
+	if err.Error() == errCopy.Error() {
+		t.Fatalf("error of rollback is lost: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUserRepository_Create_ReportsFailedRollback(t *testing.T) {
+
+	s, mock := newMockStore(t)
+	errRollback := errors.New("rollback failed")
+
+	mock.ExpectExec(`INSERT INTO user_ids`).WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec(`INSERT INTO users`).WillReturnError(errDuplicate)
+	mock.ExpectExec(`DELETE FROM user_ids`).WithArgs(7).WillReturnError(errRollback)
+
+	err := s.User().Create(&model.User{Email: "user@example.org", Password: "password", Age: 20})
+	if !errors.Is(err, store.ErrEmailAlreadyExists) {
+		t.Fatalf("expected error wrapping %v, got %v", store.ErrEmailAlreadyExists, err)
+	}
+	if err == store.ErrEmailAlreadyExists {
+		t.Fatalf("error of rollback is lost: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeUsers(t *testing.T) {
+
+	lastID := 0
+	users := func(names ...string) []*model.User {
+		list := make([]*model.User, 0, len(names))
+		for _, name := range names {
+			lastID++
+			list = append(list, &model.User{ID: lastID, Name: name})
+		}
+		return list
+	}
+	names := func(list []*model.User) []string {
+		result := make([]string, 0, len(list))
+		for _, u := range list {
+			result = append(result, u.Name)
+		}
+		return result
+	}
+
+	testCases := []struct {
+		name     string
+		lists    [][]*model.User
+		desc     bool
+		n        int
+		expected []string
+	}{
+		{
+			name:     "single shard is sorted by bytes of name",
+			lists:    [][]*model.User{users("anna", "Bob", "Anna")},
+			n:        10,
+			expected: []string{"Anna", "Bob", "anna"},
+		},
+		{
+			name:     "shards are merged and cut",
+			lists:    [][]*model.User{users("Anna", "Carl"), users("Bob", "Dan")},
+			n:        3,
+			expected: []string{"Anna", "Bob", "Carl"},
+		},
+		{
+			name:     "descending",
+			lists:    [][]*model.User{users("Dan", "Bob"), users("Carl", "Anna")},
+			desc:     true,
+			n:        3,
+			expected: []string{"Dan", "Carl", "Bob"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			merged := names(mergeUsers(tc.lists, tc.desc, tc.n))
+			if len(merged) != len(tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, merged)
+			}
+			for i := range merged {
+				if merged[i] != tc.expected[i] {
+					t.Fatalf("expected %v, got %v", tc.expected, merged)
+				}
+			}
+		})
+	}
+}
+
+func TestUserRepository_Delete(t *testing.T) {
+
+	errShard := errors.New("shard failed")
+
+	t.Run("shard rows go first", func(t *testing.T) {
+		s, mock := newMockStore(t)
+
+		mock.ExpectExec(`DELETE FROM friends WHERE user_id = \? OR friend_id = \?`).WithArgs(7, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DELETE FROM users WHERE id = \?`).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectBegin()
+		for _, statement := range deleteUserStatements {
+			mock.ExpectExec(regexp.QuoteMeta(statement)).WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+		mock.ExpectExec(`DELETE FROM user_ids WHERE id = \?`).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		if err := s.User().Delete(7); err != nil {
+			t.Fatal(err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("failed shard keeps user", func(t *testing.T) {
+		s, mock := newMockStore(t)
+
+		mock.ExpectExec(`DELETE FROM friends WHERE user_id = \? OR friend_id = \?`).WithArgs(7, 7).WillReturnError(errShard)
+
+		if err := s.User().Delete(7); err != errShard {
+			t.Fatalf("expected error %v, got %v", errShard, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestUserRepository_Search(t *testing.T) {
+
+	testCases := []struct {
+		name          string
+		firstPrefix   string
+		lastPrefix    string
+		expectedQuery string
+		expectedArgs  []driver.Value
+	}{
+		{
+			name:          "name and surname",
+			firstPrefix:   "An",
+			lastPrefix:    "Sm",
+			expectedQuery: `FROM users\s+WHERE name LIKE \? AND surname LIKE \?\s+ORDER BY`,
+			expectedArgs:  []driver.Value{"An%", "Sm%", 11},
+		},
+		{
+			name:          "name only matches users without surname",
+			firstPrefix:   "An",
+			expectedQuery: `FROM users\s+WHERE name LIKE \?\s+ORDER BY`,
+			expectedArgs:  []driver.Value{"An%", 11},
+		},
+		{
+			name:          "surname only",
+			lastPrefix:    "Sm",
+			expectedQuery: `FROM users\s+WHERE surname LIKE \?\s+ORDER BY`,
+			expectedArgs:  []driver.Value{"Sm%", 11},
+		},
+		{
+			name:          "no prefixes",
+			expectedQuery: `FROM users\s+ORDER BY`,
+			expectedArgs:  []driver.Value{11},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, mock := newMockStore(t)
+
+			mock.ExpectQuery(tc.expectedQuery).
+				WithArgs(tc.expectedArgs...).
+				WillReturnRows(userRows(&model.User{ID: 1, Name: "Ann"}))
+
+			page, err := s.User().Search(tc.firstPrefix, tc.lastPrefix, 10, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page.Users) != 1 {
+				t.Fatalf("expected 1 user, got %d", len(page.Users))
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
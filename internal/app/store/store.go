@@ -3,4 +3,15 @@ package store
 // Store ...
 type Store interface {
 	User() UserRepository
+	Post() PostRepository
+	Activity() ActivityRepository
+	Dialog() DialogRepository
+	Notification() NotificationRepository
+	Session() SessionRepository
+	PasswordReset() PasswordResetRepository
+	TwoFactor() TwoFactorRepository
+	APIToken() APITokenRepository
+	ExternalIdentity() ExternalIdentityRepository
+	LoginAttempt() LoginAttemptRepository
+	Audit() AuditRepository
 }
@@ -0,0 +1,68 @@
+package teststore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+)
+
+// ActivityRepository ...
+type ActivityRepository struct {
+	store      *Store
+	activities map[int]*model.Activity
+	lastID     int
+}
+
+// Create ...
+func (r *ActivityRepository) Create(a *model.Activity) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.lastID++
+	a.ID = r.lastID
+	a.CreatedAt = time.Now().UTC()
+
+	activity := *a
+	r.activities[a.ID] = &activity
+
+	return nil
+}
+
+// GetByActors ...
+func (r *ActivityRepository) GetByActors(actorIDs []int, limit int) ([]*model.Activity, error) {
+
+	users := r.store.User().(*UserRepository)
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	actors := make(map[int]bool, len(actorIDs))
+	for _, id := range actorIDs {
+		actors[id] = true
+	}
+
+	activities := make([]*model.Activity, 0)
+	for _, a := range r.activities {
+		if !actors[a.ActorID] {
+			continue
+		}
+		activity := *a
+		if u, ok := users.users[a.ActorID]; ok {
+			activity.ActorName, activity.ActorSurname = u.Name, u.Surname
+		}
+		if u, ok := users.users[a.SubjectID]; ok {
+			activity.SubjectName, activity.SubjectSurname = u.Name, u.Surname
+		}
+		activities = append(activities, &activity)
+	}
+
+	sort.Slice(activities, func(i, j int) bool { return activities[i].ID > activities[j].ID })
+
+	if len(activities) > limit {
+		activities = activities[:limit]
+	}
+
+	return activities, nil
+}
@@ -0,0 +1,101 @@
+package teststore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// APITokenRepository ...
+type APITokenRepository struct {
+	store  *Store
+	tokens map[int]*model.APIToken
+	lastID int
+}
+
+// Create ...
+func (r *APITokenRepository) Create(t *model.APIToken) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.lastID++
+	t.ID = r.lastID
+	t.CreatedAt = time.Now().UTC()
+
+	r.tokens[t.ID] = copyAPIToken(t)
+
+	return nil
+}
+
+// FindByHash ...
+func (r *APITokenRepository) FindByHash(tokenHash string) (*model.APIToken, error) {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, t := range r.tokens {
+		if t.TokenHash == tokenHash {
+			return copyAPIToken(t), nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
+}
+
+// GetByUser ...
+func (r *APITokenRepository) GetByUser(userID int) ([]*model.APIToken, error) {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tokens := make([]*model.APIToken, 0)
+	for _, t := range r.tokens {
+		if t.UserID == userID {
+			tokens = append(tokens, copyAPIToken(t))
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID > tokens[j].ID })
+
+	return tokens, nil
+}
+
+// Touch ...
+func (r *APITokenRepository) Touch(id int, usedAt time.Time) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if t, ok := r.tokens[id]; ok {
+		usedAt = usedAt.UTC()
+		t.LastUsedAt = &usedAt
+	}
+
+	return nil
+}
+
+// Delete ...
+func (r *APITokenRepository) Delete(userID, id int) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.tokens[id]
+	if !ok || t.UserID != userID {
+		return store.ErrRecordNotFound
+	}
+	delete(r.tokens, id)
+
+	return nil
+}
+
+func copyAPIToken(t *model.APIToken) *model.APIToken {
+
+	token := *t
+	token.Scopes = append([]string(nil), t.Scopes...)
+
+	return &token
+}
@@ -0,0 +1,30 @@
+package teststore
+
+import (
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+)
+
+// AuditRepository ...
+type AuditRepository struct {
+	store  *Store
+	events map[int]*model.AuditEvent
+	lastID int
+}
+
+// Create ...
+func (r *AuditRepository) Create(e *model.AuditEvent) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.lastID++
+	e.ID = r.lastID
+	e.CreatedAt = time.Now().UTC()
+
+	event := *e
+	r.events[e.ID] = &event
+
+	return nil
+}
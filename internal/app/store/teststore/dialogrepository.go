@@ -0,0 +1,123 @@
+package teststore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+)
+
+// DialogRepository ...
+type DialogRepository struct {
+	store    *Store
+	messages map[int]*model.Message
+	lastID   int
+}
+
+// Send ...
+func (r *DialogRepository) Send(m *model.Message) error {
+
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.lastID++
+	m.ID = r.lastID
+	m.CreatedAt = time.Now().UTC()
+
+	message := *m
+	r.messages[m.ID] = &message
+
+	return nil
+}
+
+// GetDialogs ...
+func (r *DialogRepository) GetDialogs(userID int) ([]*model.Dialog, error) {
+
+	users := r.store.User().(*UserRepository)
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	dialogs := make(map[int]*model.Dialog)
+	for _, m := range r.messages {
+
+		peerID := m.ToID
+		if m.ToID == userID {
+			peerID = m.FromID
+		} else if m.FromID != userID {
+			continue
+		}
+
+		d, ok := dialogs[peerID]
+		if !ok {
+			d = &model.Dialog{PeerID: peerID}
+			if u, ok := users.users[peerID]; ok {
+				d.PeerName, d.PeerSurname = u.Name, u.Surname
+			}
+			dialogs[peerID] = d
+		}
+
+		if d.LastMessage == nil || d.LastMessage.ID < m.ID {
+			message := *m
+			d.LastMessage = &message
+		}
+
+		if m.ToID == userID && m.ReadAt == nil {
+			d.UnreadCount++
+		}
+	}
+
+	result := make([]*model.Dialog, 0, len(dialogs))
+	for _, d := range dialogs {
+		result = append(result, d)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].LastMessage.ID > result[j].LastMessage.ID })
+
+	return result, nil
+}
+
+// GetMessages ...
+func (r *DialogRepository) GetMessages(userID, peerID, beforeID, limit int) (*model.MessagesPage, error) {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	messages := make([]*model.Message, 0)
+	for _, m := range r.messages {
+		inDialog := (m.FromID == userID && m.ToID == peerID) || (m.FromID == peerID && m.ToID == userID)
+		if inDialog && (beforeID <= 0 || m.ID < beforeID) {
+			message := *m
+			messages = append(messages, &message)
+		}
+	}
+
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID > messages[j].ID })
+
+	if len(messages) > limit+1 {
+		messages = messages[:limit+1]
+	}
+
+	return model.NewMessagesPage(messages, limit), nil
+}
+
+// MarkRead ...
+func (r *DialogRepository) MarkRead(userID, peerID int) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, m := range r.messages {
+		if m.ToID == userID && m.FromID == peerID && m.ReadAt == nil {
+			readAt := now
+			m.ReadAt = &readAt
+		}
+	}
+
+	return nil
+}
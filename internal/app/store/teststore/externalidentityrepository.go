@@ -0,0 +1,53 @@
+package teststore
+
+import (
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// ExternalIdentityRepository ...
+type ExternalIdentityRepository struct {
+	store      *Store
+	identities map[int]*model.ExternalIdentity
+	lastID     int
+}
+
+// Create ...
+func (r *ExternalIdentityRepository) Create(i *model.ExternalIdentity) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.identities {
+		if existing.Provider == i.Provider && existing.Subject == i.Subject {
+			return store.ErrIdentityAlreadyLinked
+		}
+	}
+
+	r.lastID++
+	i.ID = r.lastID
+	i.CreatedAt = time.Now().UTC()
+
+	identity := *i
+	r.identities[i.ID] = &identity
+
+	return nil
+}
+
+// Find ...
+func (r *ExternalIdentityRepository) Find(provider, subject string) (*model.ExternalIdentity, error) {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, i := range r.identities {
+		if i.Provider == provider && i.Subject == subject {
+			identity := *i
+			return &identity, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
+}
@@ -0,0 +1,105 @@
+package teststore
+
+import (
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// LoginAttemptRepository ...
+type LoginAttemptRepository struct {
+	store    *Store
+	attempts map[string]*model.LoginAttempts
+}
+
+// Find ...
+func (r *LoginAttemptRepository) Find(key string) (*model.LoginAttempts, error) {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	a, ok := r.attempts[key]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	attempts := *a
+
+	return &attempts, nil
+}
+
+// Reserve ...
+func (r *LoginAttemptRepository) Reserve(key string, limit model.LoginLimit) (*model.LoginAttempts, error) {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now().UTC()
+
+	a, ok := r.attempts[key]
+	if !ok || limit.Expired(a, now) {
+		a = &model.LoginAttempts{Key: key, LastFailureAt: now}
+		r.attempts[key] = a
+	}
+
+	if limit.BlockedUntil(a).After(now) {
+		attempts := *a
+		return &attempts, store.ErrLoginAttemptsExceeded
+	}
+
+	a.Failures++
+	a.PreviousFailureAt = a.LastFailureAt
+	a.LastFailureAt = now
+
+	attempts := *a
+
+	return &attempts, nil
+}
+
+// Release ...
+func (r *LoginAttemptRepository) Release(reserved *model.LoginAttempts) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	a, ok := r.attempts[reserved.Key]
+	if !ok || a.Failures == 0 {
+		return nil
+	}
+
+	if a.LastFailureAt.Equal(reserved.LastFailureAt) {
+		a.LastFailureAt = reserved.PreviousFailureAt
+	}
+	a.Failures--
+
+	return nil
+}
+
+// Delete ...
+func (r *LoginAttemptRepository) Delete(key string) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.attempts, key)
+
+	return nil
+}
+
+// DeleteBefore ...
+func (r *LoginAttemptRepository) DeleteBefore(t time.Time) (int, error) {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	n := 0
+	for key, a := range r.attempts {
+		if a.LastFailureAt.Before(t) {
+			delete(r.attempts, key)
+			n++
+		}
+	}
+
+	return n, nil
+}
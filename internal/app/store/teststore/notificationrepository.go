@@ -0,0 +1,122 @@
+package teststore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// NotificationRepository ...
+type NotificationRepository struct {
+	store         *Store
+	notifications map[int]*model.Notification
+	lastID        int
+}
+
+// Create ...
+func (r *NotificationRepository) Create(n *model.Notification) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.lastID++
+	n.ID = r.lastID
+	n.CreatedAt = time.Now().UTC()
+
+	notification := *n
+	r.notifications[n.ID] = &notification
+
+	return nil
+}
+
+// GetByUser ...
+func (r *NotificationRepository) GetByUser(userID, beforeID, limit int) (*model.NotificationsPage, error) {
+
+	users := r.store.User().(*UserRepository)
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	notifications := make([]*model.Notification, 0)
+	unread := 0
+	for _, n := range r.notifications {
+		if n.UserID != userID {
+			continue
+		}
+		if n.ReadAt == nil {
+			unread++
+		}
+		if beforeID <= 0 || n.ID < beforeID {
+			notification := *n
+			if actor, ok := users.users[n.ActorID]; ok {
+				notification.ActorName, notification.ActorSurname = actor.Name, actor.Surname
+			}
+			notifications = append(notifications, &notification)
+		}
+	}
+
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].ID > notifications[j].ID })
+
+	if len(notifications) > limit+1 {
+		notifications = notifications[:limit+1]
+	}
+
+	page := model.NewNotificationsPage(notifications, limit)
+	page.Unread = unread
+
+	return page, nil
+}
+
+// CountUnread ...
+func (r *NotificationRepository) CountUnread(userID int) (int, error) {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	unread := 0
+	for _, n := range r.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			unread++
+		}
+	}
+
+	return unread, nil
+}
+
+// MarkRead ...
+func (r *NotificationRepository) MarkRead(id, userID int) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	n, ok := r.notifications[id]
+	if !ok || n.UserID != userID {
+		return store.ErrRecordNotFound
+	}
+
+	if n.ReadAt == nil {
+		readAt := time.Now().UTC()
+		n.ReadAt = &readAt
+	}
+
+	return nil
+}
+
+// MarkAllRead ...
+func (r *NotificationRepository) MarkAllRead(userID int) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, n := range r.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			readAt := now
+			n.ReadAt = &readAt
+		}
+	}
+
+	return nil
+}
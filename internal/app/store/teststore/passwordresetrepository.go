@@ -0,0 +1,65 @@
+package teststore
+
+import (
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// PasswordResetRepository ...
+type PasswordResetRepository struct {
+	store  *Store
+	resets map[string]*model.PasswordReset
+	lastID int
+}
+
+// Create ...
+func (r *PasswordResetRepository) Create(p *model.PasswordReset) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.lastID++
+	p.ID = r.lastID
+	p.CreatedAt = time.Now().UTC()
+
+	reset := *p
+	r.resets[p.TokenHash] = &reset
+
+	return nil
+}
+
+// FindByToken ...
+func (r *PasswordResetRepository) FindByToken(tokenHash string) (*model.PasswordReset, error) {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	p, ok := r.resets[tokenHash]
+	if !ok || p.UsedAt != nil || !p.ExpiresAt.After(time.Now().UTC()) {
+		return nil, store.ErrRecordNotFound
+	}
+
+	reset := *p
+
+	return &reset, nil
+}
+
+// Use ...
+func (r *PasswordResetRepository) Use(tokenHash string) (*model.PasswordReset, error) {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now().UTC()
+	p, ok := r.resets[tokenHash]
+	if !ok || p.UsedAt != nil || !p.ExpiresAt.After(now) {
+		return nil, store.ErrRecordNotFound
+	}
+	p.UsedAt = &now
+
+	reset := *p
+
+	return &reset, nil
+}
@@ -0,0 +1,149 @@
+package teststore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// PostRepository ...
+type PostRepository struct {
+	store  *Store
+	posts  map[int]*model.Post
+	lastID int
+}
+
+// Create ...
+func (r *PostRepository) Create(p *model.Post) error {
+
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	users := r.users()
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := users.users[p.AuthorID]; !ok {
+		return store.ErrRecordNotFound
+	}
+
+	r.lastID++
+	p.ID = r.lastID
+	p.CreatedAt = time.Now().UTC()
+	p.UpdatedAt = p.CreatedAt
+
+	post := *p
+	r.posts[p.ID] = &post
+
+	return nil
+}
+
+// Find ...
+func (r *PostRepository) Find(id int) (*model.Post, error) {
+
+	users := r.users()
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	p, ok := r.posts[id]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return withAuthor(p, users), nil
+}
+
+// Update ...
+func (r *PostRepository) Update(p *model.Post) error {
+
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	post, ok := r.posts[p.ID]
+	if !ok || post.AuthorID != p.AuthorID {
+		return store.ErrRecordNotFound
+	}
+
+	p.UpdatedAt = time.Now().UTC()
+	post.Text = p.Text
+	post.UpdatedAt = p.UpdatedAt
+
+	return nil
+}
+
+// Delete ...
+func (r *PostRepository) Delete(id, authorID int) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	post, ok := r.posts[id]
+	if !ok || post.AuthorID != authorID {
+		return store.ErrRecordNotFound
+	}
+
+	delete(r.posts, id)
+
+	return nil
+}
+
+// GetByAuthor ...
+func (r *PostRepository) GetByAuthor(authorID, beforeID, limit int) (*model.PostsPage, error) {
+	return r.GetFeed([]int{authorID}, beforeID, limit)
+}
+
+// GetFeed ...
+func (r *PostRepository) GetFeed(authorIDs []int, beforeID, limit int) (*model.PostsPage, error) {
+
+	users := r.users()
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	authors := make(map[int]bool, len(authorIDs))
+	for _, id := range authorIDs {
+		authors[id] = true
+	}
+
+	posts := make([]*model.Post, 0)
+	for _, p := range r.posts {
+		if authors[p.AuthorID] && (beforeID <= 0 || p.ID < beforeID) {
+			posts = append(posts, withAuthor(p, users))
+		}
+	}
+
+	sort.Slice(posts, func(i, j int) bool { return posts[i].ID > posts[j].ID })
+
+	if len(posts) > limit+1 {
+		posts = posts[:limit+1]
+	}
+
+	return model.NewPostsPage(posts, limit), nil
+}
+
+// users returns user repository of the same store,
+// it must be called before locking the store
+func (r *PostRepository) users() *UserRepository {
+	return r.store.User().(*UserRepository)
+}
+
+// withAuthor returns copy of post filled with name of its author
+func withAuthor(p *model.Post, users *UserRepository) *model.Post {
+
+	post := *p
+	if u, ok := users.users[p.AuthorID]; ok {
+		post.AuthorName = u.Name
+		post.AuthorSurname = u.Surname
+	}
+
+	return &post
+}
@@ -0,0 +1,127 @@
+package teststore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// SessionRepository ...
+type SessionRepository struct {
+	store    *Store
+	sessions map[string]*model.Session
+}
+
+// Create ...
+func (r *SessionRepository) Create(s *model.Session) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	s.CreatedAt = time.Now().UTC()
+
+	session := *s
+	r.sessions[s.ID] = &session
+
+	return nil
+}
+
+// Find ...
+func (r *SessionRepository) Find(id string) (*model.Session, error) {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	s, ok := r.sessions[id]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	session := *s
+
+	return &session, nil
+}
+
+// Update ...
+func (r *SessionRepository) Update(s *model.Session) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.sessions[s.ID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	session := *s
+	session.CreatedAt = stored.CreatedAt
+	r.sessions[s.ID] = &session
+
+	return nil
+}
+
+// Delete ...
+func (r *SessionRepository) Delete(id string) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.sessions, id)
+
+	return nil
+}
+
+// GetByUser ...
+func (r *SessionRepository) GetByUser(userID int) ([]*model.Session, error) {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	now := time.Now().UTC()
+	sessions := make([]*model.Session, 0)
+	for _, s := range r.sessions {
+		if s.UserID == userID && s.ExpiresAt.After(now) {
+			session := *s
+			session.Data = nil
+			sessions = append(sessions, &session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt) })
+
+	return sessions, nil
+}
+
+// DeleteByUser ...
+func (r *SessionRepository) DeleteByUser(userID int) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, s := range r.sessions {
+		if s.UserID == userID {
+			delete(r.sessions, id)
+		}
+	}
+
+	return nil
+}
+
+// DeleteExpired ...
+func (r *SessionRepository) DeleteExpired(now time.Time) (int, error) {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	n := 0
+	for id, s := range r.sessions {
+		if !s.ExpiresAt.After(now) {
+			delete(r.sessions, id)
+			n++
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,322 @@
+package teststore
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// Store keeps all the data in memory,
+// it is used in tests and for local development without database
+type Store struct {
+	mu                         sync.RWMutex
+	userRepository             *UserRepository
+	postRepository             *PostRepository
+	activityRepository         *ActivityRepository
+	dialogRepository           *DialogRepository
+	notificationRepository     *NotificationRepository
+	sessionRepository          *SessionRepository
+	passwordResetRepository    *PasswordResetRepository
+	twoFactorRepository        *TwoFactorRepository
+	apiTokenRepository         *APITokenRepository
+	externalIdentityRepository *ExternalIdentityRepository
+	loginAttemptRepository     *LoginAttemptRepository
+	auditRepository            *AuditRepository
+}
+
+// New ...
+func New() *Store {
+	return &Store{}
+}
+
+// User returns user repository to work with in-memory store
+func (s *Store) User() store.UserRepository {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.userRepository != nil {
+		return s.userRepository
+	}
+
+	s.userRepository = &UserRepository{
+		store:               s,
+		users:               make(map[int]*model.User),
+		friends:             make(map[friendship]*friendRecord),
+		verificationSentAt:  make(map[int]time.Time),
+		passwordResetSentAt: make(map[int]time.Time),
+	}
+
+	return s.userRepository
+}
+
+// Post returns post repository to work with in-memory store
+func (s *Store) Post() store.PostRepository {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.postRepository != nil {
+		return s.postRepository
+	}
+
+	s.postRepository = &PostRepository{
+		store: s,
+		posts: make(map[int]*model.Post),
+	}
+
+	return s.postRepository
+}
+
+// Activity returns activity repository to work with in-memory store
+func (s *Store) Activity() store.ActivityRepository {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.activityRepository != nil {
+		return s.activityRepository
+	}
+
+	s.activityRepository = &ActivityRepository{
+		store:      s,
+		activities: make(map[int]*model.Activity),
+	}
+
+	return s.activityRepository
+}
+
+// Dialog returns dialog repository to work with in-memory store
+func (s *Store) Dialog() store.DialogRepository {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dialogRepository != nil {
+		return s.dialogRepository
+	}
+
+	s.dialogRepository = &DialogRepository{
+		store:    s,
+		messages: make(map[int]*model.Message),
+	}
+
+	return s.dialogRepository
+}
+
+// Notification returns notification repository to work with in-memory store
+func (s *Store) Notification() store.NotificationRepository {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.notificationRepository != nil {
+		return s.notificationRepository
+	}
+
+	s.notificationRepository = &NotificationRepository{
+		store:         s,
+		notifications: make(map[int]*model.Notification),
+	}
+
+	return s.notificationRepository
+}
+
+// Session returns session repository to work with in-memory store
+func (s *Store) Session() store.SessionRepository {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sessionRepository != nil {
+		return s.sessionRepository
+	}
+
+	s.sessionRepository = &SessionRepository{
+		store:    s,
+		sessions: make(map[string]*model.Session),
+	}
+
+	return s.sessionRepository
+}
+
+// PasswordReset returns password reset repository to work with in-memory store
+func (s *Store) PasswordReset() store.PasswordResetRepository {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.passwordResetRepository != nil {
+		return s.passwordResetRepository
+	}
+
+	s.passwordResetRepository = &PasswordResetRepository{
+		store:  s,
+		resets: make(map[string]*model.PasswordReset),
+	}
+
+	return s.passwordResetRepository
+}
+
+// TwoFactor returns two-factor repository to work with in-memory store
+func (s *Store) TwoFactor() store.TwoFactorRepository {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.twoFactorRepository != nil {
+		return s.twoFactorRepository
+	}
+
+	s.twoFactorRepository = &TwoFactorRepository{
+		store:         s,
+		enrollments:   make(map[int]*model.TwoFactor),
+		recoveryCodes: make(map[int]map[string]bool),
+	}
+
+	return s.twoFactorRepository
+}
+
+// APIToken returns api token repository to work with in-memory store
+func (s *Store) APIToken() store.APITokenRepository {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.apiTokenRepository != nil {
+		return s.apiTokenRepository
+	}
+
+	s.apiTokenRepository = &APITokenRepository{
+		store:  s,
+		tokens: make(map[int]*model.APIToken),
+	}
+
+	return s.apiTokenRepository
+}
+
+// ExternalIdentity returns external identity repository to work with in-memory store
+func (s *Store) ExternalIdentity() store.ExternalIdentityRepository {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.externalIdentityRepository != nil {
+		return s.externalIdentityRepository
+	}
+
+	s.externalIdentityRepository = &ExternalIdentityRepository{
+		store:      s,
+		identities: make(map[int]*model.ExternalIdentity),
+	}
+
+	return s.externalIdentityRepository
+}
+
+// LoginAttempt returns login attempt repository to work with in-memory store
+func (s *Store) LoginAttempt() store.LoginAttemptRepository {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.loginAttemptRepository != nil {
+		return s.loginAttemptRepository
+	}
+
+	s.loginAttemptRepository = &LoginAttemptRepository{
+		store:    s,
+		attempts: make(map[string]*model.LoginAttempts),
+	}
+
+	return s.loginAttemptRepository
+}
+
+// Audit returns audit repository to work with in-memory store
+func (s *Store) Audit() store.AuditRepository {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.auditRepository != nil {
+		return s.auditRepository
+	}
+
+	s.auditRepository = &AuditRepository{
+		store:  s,
+		events: make(map[int]*model.AuditEvent),
+	}
+
+	return s.auditRepository
+}
+
+// deleteUserData removes rows of user from repositories other than users,
+// it is called with mu locked
+func (s *Store) deleteUserData(id int) {
+
+	if r := s.postRepository; r != nil {
+		for postID, p := range r.posts {
+			if p.AuthorID == id {
+				delete(r.posts, postID)
+			}
+		}
+	}
+	if r := s.activityRepository; r != nil {
+		for activityID, a := range r.activities {
+			if a.ActorID == id || a.SubjectID == id {
+				delete(r.activities, activityID)
+			}
+		}
+	}
+	if r := s.dialogRepository; r != nil {
+		for messageID, m := range r.messages {
+			if m.FromID == id || m.ToID == id {
+				delete(r.messages, messageID)
+			}
+		}
+	}
+	if r := s.notificationRepository; r != nil {
+		for notificationID, n := range r.notifications {
+			if n.UserID == id || n.ActorID == id {
+				delete(r.notifications, notificationID)
+			}
+		}
+	}
+	if r := s.sessionRepository; r != nil {
+		for sessionID, session := range r.sessions {
+			if session.UserID == id {
+				delete(r.sessions, sessionID)
+			}
+		}
+	}
+	if r := s.passwordResetRepository; r != nil {
+		for hash, reset := range r.resets {
+			if reset.UserID == id {
+				delete(r.resets, hash)
+			}
+		}
+	}
+	if r := s.twoFactorRepository; r != nil {
+		delete(r.enrollments, id)
+		delete(r.recoveryCodes, id)
+	}
+	if r := s.apiTokenRepository; r != nil {
+		for tokenID, token := range r.tokens {
+			if token.UserID == id {
+				delete(r.tokens, tokenID)
+			}
+		}
+	}
+	if r := s.externalIdentityRepository; r != nil {
+		for identityID, identity := range r.identities {
+			if identity.UserID == id {
+				delete(r.identities, identityID)
+			}
+		}
+	}
+	if r := s.loginAttemptRepository; r != nil {
+		delete(r.attempts, fmt.Sprintf("user:%d", id))
+	}
+}
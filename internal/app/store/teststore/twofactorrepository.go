@@ -0,0 +1,135 @@
+package teststore
+
+import (
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// TwoFactorRepository ...
+type TwoFactorRepository struct {
+	store         *Store
+	enrollments   map[int]*model.TwoFactor
+	recoveryCodes map[int]map[string]bool
+}
+
+// Find ...
+func (r *TwoFactorRepository) Find(userID int) (*model.TwoFactor, error) {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	t, ok := r.enrollments[userID]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	enrollment := *t
+
+	return &enrollment, nil
+}
+
+// Create ...
+func (r *TwoFactorRepository) Create(t *model.TwoFactor) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if existing, ok := r.enrollments[t.UserID]; ok && existing.Enabled() {
+		return store.ErrTwoFactorAlreadyEnabled
+	}
+
+	t.CreatedAt = time.Now().UTC()
+	t.ConfirmedAt = nil
+	t.LastUsedStep = 0
+
+	enrollment := *t
+	r.enrollments[t.UserID] = &enrollment
+
+	return nil
+}
+
+// Confirm ...
+func (r *TwoFactorRepository) Confirm(userID int, codeHashes []string) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.enrollments[userID]
+	if !ok || t.Enabled() {
+		return store.ErrRecordNotFound
+	}
+
+	now := time.Now().UTC()
+	t.ConfirmedAt = &now
+
+	codes := make(map[string]bool, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes[hash] = false
+	}
+	r.recoveryCodes[userID] = codes
+
+	return nil
+}
+
+// UseStep ...
+func (r *TwoFactorRepository) UseStep(userID int, step int64) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	t, ok := r.enrollments[userID]
+	if !ok || t.LastUsedStep >= step {
+		return store.ErrRecordNotFound
+	}
+	t.LastUsedStep = step
+
+	return nil
+}
+
+// UseRecoveryCode ...
+func (r *TwoFactorRepository) UseRecoveryCode(userID int, codeHash string) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	used, ok := r.recoveryCodes[userID][codeHash]
+	if !ok || used {
+		return store.ErrRecordNotFound
+	}
+	r.recoveryCodes[userID][codeHash] = true
+
+	return nil
+}
+
+// CountRecoveryCodes ...
+func (r *TwoFactorRepository) CountRecoveryCodes(userID int) (int, error) {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	count := 0
+	for _, used := range r.recoveryCodes[userID] {
+		if !used {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Delete ...
+func (r *TwoFactorRepository) Delete(userID int) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.enrollments[userID]; !ok {
+		return store.ErrRecordNotFound
+	}
+	delete(r.enrollments, userID)
+	delete(r.recoveryCodes, userID)
+
+	return nil
+}
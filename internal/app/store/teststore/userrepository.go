@@ -0,0 +1,550 @@
+package teststore
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DalerBakhriev/social_network/internal/app/model"
+	"github.com/DalerBakhriev/social_network/internal/app/store"
+)
+
+// friendship is a key of friends table: (user_id, friend_id)
+type friendship struct {
+	userID   int
+	friendID int
+}
+
+// friendRecord is a row of friends table
+type friendRecord struct {
+	requesterID int
+	status      string
+}
+
+// UserRepository ...
+type UserRepository struct {
+	store   *Store
+	users   map[int]*model.User
+	friends map[friendship]*friendRecord
+	// verificationSentAt is time of the last verification email of user
+	verificationSentAt map[int]time.Time
+	// passwordResetSentAt is time of the last password reset email of user
+	passwordResetSentAt map[int]time.Time
+	lastID              int
+}
+
+// Create ...
+func (r *UserRepository) Create(u *model.User) error {
+
+	if err := u.BeforeCreate(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.Email == u.Email {
+			return store.ErrEmailAlreadyExists
+		}
+	}
+
+	r.lastID++
+	u.ID = r.lastID
+	if u.Role == "" {
+		u.Role = model.RoleUser
+	}
+	u.CreatedAt = time.Now().UTC()
+
+	stored := *u
+	stored.Password = ""
+	r.users[u.ID] = &stored
+
+	return nil
+}
+
+// FindByEmail ...
+func (r *UserRepository) FindByEmail(email string) (*model.User, error) {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, u := range r.users {
+		if u.Email == email {
+			user := *u
+			return &user, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
+}
+
+// Find ...
+func (r *UserRepository) Find(id int) (*model.User, error) {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	user := *u
+	return &user, nil
+}
+
+// Update ...
+func (r *UserRepository) Update(u *model.User) error {
+
+	if err := u.BeforeCreate(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.users[u.ID]
+	if !ok {
+		return nil
+	}
+
+	user.Name = u.Name
+	user.Surname = u.Surname
+	user.Age = u.Age
+	user.Sex = u.Sex
+	user.Interests = u.Interests
+	user.City = u.City
+
+	return nil
+}
+
+// UpdatePassword ...
+func (r *UserRepository) UpdatePassword(u *model.User) error {
+
+	if len(u.Password) == 0 {
+		return model.ErrEmptyPassword
+	}
+
+	if err := u.BeforeCreate(); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.users[u.ID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	user.EncryptedPassword = u.EncryptedPassword
+
+	return nil
+}
+
+// VerifyEmail ...
+func (r *UserRepository) VerifyEmail(id int, email string) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.Email != email {
+		return store.ErrRecordNotFound
+	}
+
+	if user.EmailVerifiedAt == nil {
+		verifiedAt := time.Now().UTC()
+		user.EmailVerifiedAt = &verifiedAt
+	}
+
+	return nil
+}
+
+// MarkVerificationSent ...
+func (r *UserRepository) MarkVerificationSent(id int, cooldown time.Duration) (bool, error) {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.EmailVerifiedAt != nil {
+		return false, nil
+	}
+
+	now := time.Now().UTC()
+	if sentAt, ok := r.verificationSentAt[id]; ok && now.Sub(sentAt) < cooldown {
+		return false, nil
+	}
+	r.verificationSentAt[id] = now
+
+	return true, nil
+}
+
+// MarkPasswordResetSent ...
+func (r *UserRepository) MarkPasswordResetSent(id int, cooldown time.Duration) (bool, error) {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return false, nil
+	}
+
+	now := time.Now().UTC()
+	if sentAt, ok := r.passwordResetSentAt[id]; ok && now.Sub(sentAt) < cooldown {
+		return false, nil
+	}
+	r.passwordResetSentAt[id] = now
+
+	return true, nil
+}
+
+// GetUsersPage ...
+func (r *UserRepository) GetUsersPage(filter *model.UserFilter, cursor *model.Cursor, limit int) (*model.UsersPage, error) {
+
+	return r.usersPage(func(u *model.User) bool { return matchesFilter(u, filter) }, cursor, limit), nil
+}
+
+// Search ...
+func (r *UserRepository) Search(firstPrefix, lastPrefix string, limit int, cursor *model.Cursor) (*model.UsersPage, error) {
+
+	return r.usersPage(func(u *model.User) bool {
+		return hasPrefixFold(u.Name, firstPrefix) && hasPrefixFold(u.Surname, lastPrefix)
+	}, cursor, limit), nil
+}
+
+// usersPage selects page of users satisfying match ordered by name and id
+func (r *UserRepository) usersPage(match func(*model.User) bool, cursor *model.Cursor, limit int) *model.UsersPage {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	users := make([]*model.User, 0)
+	for _, u := range r.users {
+		if match(u) && matchesCursor(u, cursor) {
+			users = append(users, publicCopy(u))
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool { return userLess(users[i], users[j]) })
+
+	if len(users) > limit+1 {
+		if cursor != nil && cursor.Backward {
+			users = users[len(users)-limit-1:]
+		} else {
+			users = users[:limit+1]
+		}
+	}
+
+	return model.NewUsersPage(users, cursor, limit)
+}
+
+// GetFriendsList ...
+func (r *UserRepository) GetFriendsList(id int) ([]*model.User, error) {
+	return r.getFriends(id, func(key friendship, f *friendRecord) bool {
+		return f.status == model.FriendshipAccepted
+	}), nil
+}
+
+// GetIncomingFriendsRequests ...
+func (r *UserRepository) GetIncomingFriendsRequests(id int) ([]*model.User, error) {
+	return r.getFriends(id, func(key friendship, f *friendRecord) bool {
+		return f.status == model.FriendshipPending && f.requesterID == key.friendID
+	}), nil
+}
+
+// GetOutgoingFriendsRequests ...
+func (r *UserRepository) GetOutgoingFriendsRequests(id int) ([]*model.User, error) {
+	return r.getFriends(id, func(key friendship, f *friendRecord) bool {
+		return f.status == model.FriendshipPending && f.requesterID == key.userID
+	}), nil
+}
+
+// GetAccounts ...
+func (r *UserRepository) GetAccounts(emailPrefix string, beforeID, limit int) (*model.AccountsPage, error) {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	users := make([]*model.User, 0)
+	for _, u := range r.users {
+		if strings.HasPrefix(u.Email, emailPrefix) && (beforeID == 0 || u.ID < beforeID) {
+			user := *u
+			users = append(users, &user)
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID > users[j].ID
+	})
+	if len(users) > limit+1 {
+		users = users[:limit+1]
+	}
+
+	return model.NewAccountsPage(users, limit), nil
+}
+
+// SetRole ...
+func (r *UserRepository) SetRole(id int, role string) error {
+
+	if err := model.ValidateRole(role); err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+	u.Role = role
+
+	return nil
+}
+
+// SetSuspended ...
+func (r *UserRepository) SetSuspended(id int, suspendedAt *time.Time) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+	u.SuspendedAt = suspendedAt
+
+	return nil
+}
+
+// Delete removes user with friendships and
+// everything user created from all repositories
+func (r *UserRepository) Delete(id int) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return store.ErrRecordNotFound
+	}
+
+	delete(r.users, id)
+	delete(r.verificationSentAt, id)
+	delete(r.passwordResetSentAt, id)
+	for key := range r.friends {
+		if key.userID == id || key.friendID == id {
+			delete(r.friends, key)
+		}
+	}
+
+	r.store.deleteUserData(id)
+
+	return nil
+}
+
+// RequestWasAlreadySent ...
+func (r *UserRepository) RequestWasAlreadySent(fromID, toID int) bool {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	_, ok := r.friends[friendship{userID: fromID, friendID: toID}]
+
+	return ok
+}
+
+// AreFriends ...
+func (r *UserRepository) AreFriends(userID, friendID int) (bool, error) {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	f, ok := r.friends[friendship{userID: userID, friendID: friendID}]
+
+	return ok && f.status == model.FriendshipAccepted, nil
+}
+
+// SendFriendRequest ...
+func (r *UserRepository) SendFriendRequest(fromID, toID int) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// check is done under the write lock, otherwise two concurrent
+	// requests could both pass it
+	if _, ok := r.friends[friendship{userID: fromID, friendID: toID}]; ok {
+		return store.ErrFriendRequestWasAlreadySent
+	}
+
+	r.friends[friendship{userID: fromID, friendID: toID}] = &friendRecord{requesterID: fromID, status: model.FriendshipPending}
+	r.friends[friendship{userID: toID, friendID: fromID}] = &friendRecord{requesterID: fromID, status: model.FriendshipPending}
+
+	return nil
+}
+
+// AcceptFriendRequest ...
+func (r *UserRepository) AcceptFriendRequest(userID, requesterID int) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	keys := pendingRequestKeys(r.friends, requesterID, userID)
+	if len(keys) == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	for _, key := range keys {
+		r.friends[key].status = model.FriendshipAccepted
+	}
+
+	return nil
+}
+
+// DeclineFriendRequest ...
+func (r *UserRepository) DeclineFriendRequest(userID, requesterID int) error {
+	return r.deletePendingRequest(requesterID, userID)
+}
+
+// CancelFriendRequest ...
+func (r *UserRepository) CancelFriendRequest(fromID, toID int) error {
+	return r.deletePendingRequest(fromID, toID)
+}
+
+// RemoveFriend ...
+func (r *UserRepository) RemoveFriend(userID, friendID int) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	found := false
+	for _, key := range []friendship{
+		{userID: userID, friendID: friendID},
+		{userID: friendID, friendID: userID},
+	} {
+		if f, ok := r.friends[key]; ok && f.status == model.FriendshipAccepted {
+			delete(r.friends, key)
+			found = true
+		}
+	}
+
+	if !found {
+		return store.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepository) deletePendingRequest(fromID, toID int) error {
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	keys := pendingRequestKeys(r.friends, fromID, toID)
+	if len(keys) == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	for _, key := range keys {
+		delete(r.friends, key)
+	}
+
+	return nil
+}
+
+// pendingRequestKeys returns keys of rows of pending request sent from one user to another
+func pendingRequestKeys(friends map[friendship]*friendRecord, fromID, toID int) []friendship {
+
+	keys := make([]friendship, 0, 2)
+	for _, key := range []friendship{
+		{userID: fromID, friendID: toID},
+		{userID: toID, friendID: fromID},
+	} {
+		if f, ok := friends[key]; ok && f.status == model.FriendshipPending && f.requesterID == fromID {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// getFriends returns users linked with user id
+// by rows of friends satisfying match ordered by id
+func (r *UserRepository) getFriends(id int, match func(friendship, *friendRecord) bool) []*model.User {
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	users := make([]*model.User, 0)
+	for key, f := range r.friends {
+		if key.userID != id || !match(key, f) {
+			continue
+		}
+		if u, ok := r.users[key.friendID]; ok {
+			users = append(users, publicCopy(u))
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	return users
+}
+
+// publicCopy returns copy of user without email and password
+// the same way sql store selects users for lists
+func publicCopy(u *model.User) *model.User {
+	return &model.User{
+		ID:        u.ID,
+		Name:      u.Name,
+		Surname:   u.Surname,
+		Age:       u.Age,
+		Sex:       u.Sex,
+		Interests: u.Interests,
+		City:      u.City,
+	}
+}
+
+// userLess orders users by name and id
+func userLess(a, b *model.User) bool {
+
+	if a.Name == b.Name {
+		return a.ID < b.ID
+	}
+
+	return a.Name < b.Name
+}
+
+func matchesFilter(u *model.User, filter *model.UserFilter) bool {
+
+	if filter == nil {
+		return true
+	}
+
+	return (filter.City == "" || u.City == filter.City) &&
+		(filter.Sex == "" || u.Sex == filter.Sex) &&
+		(filter.MinAge <= 0 || u.Age >= filter.MinAge) &&
+		(filter.MaxAge <= 0 || u.Age <= filter.MaxAge) &&
+		(filter.Interests == "" || strings.Contains(strings.ToLower(u.Interests), strings.ToLower(filter.Interests)))
+}
+
+// hasPrefixFold is case insensitive strings.HasPrefix
+// as LIKE works with default database collation
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+func matchesCursor(u *model.User, cursor *model.Cursor) bool {
+
+	if cursor == nil {
+		return true
+	}
+
+	position := &model.User{Name: cursor.Name, ID: cursor.ID}
+	if cursor.Backward {
+		return userLess(u, position)
+	}
+
+	return userLess(position, u)
+}
@@ -0,0 +1,204 @@
+package twofactor
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"image/png"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	// period is lifetime of code in seconds as RFC 6238 recommends
+	period = 30
+	// skew is number of periods before and after current
+	// one codes are accepted from, it covers clock drift
+	skew       = 1
+	qrCodeSize = 200
+)
+
+var (
+	// ErrNoKey is returned when key to encrypt secrets is not configured
+	ErrNoKey = errors.New("Key to encrypt two-factor secrets is not configured")
+
+	errMalformedSecret = errors.New("malformed encrypted secret")
+)
+
+// Cipher encrypts totp secrets before they are stored
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher returns AES-GCM cipher with key derived from key string
+func NewCipher(key string) (*Cipher, error) {
+
+	if key == "" {
+		return nil, ErrNoKey
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt ...
+func (c *Cipher) Encrypt(secret string) (string, error) {
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(secret), nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt ...
+func (c *Cipher) Decrypt(encrypted string) (string, error) {
+
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < c.aead.NonceSize() {
+		return "", errMalformedSecret
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	secret, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret), nil
+}
+
+// NewSecret returns random base32 secret of totp key
+func NewSecret(issuer, account string) (string, error) {
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: account,
+		Period:      period,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return key.Secret(), nil
+}
+
+// ProvisioningURI returns otpauth uri authenticator apps add key from
+func ProvisioningURI(issuer, account, secret string) string {
+
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", otp.AlgorithmSHA1.String())
+	query.Set("digits", otp.DigitsSix.String())
+	query.Set("period", "30")
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
+}
+
+// QRCode returns provisioning uri as png image encoded into data uri
+func QRCode(uri string) (string, error) {
+
+	key, err := otp.NewKeyFromURL(uri)
+	if err != nil {
+		return "", err
+	}
+
+	img, err := key.Image(qrCodeSize, qrCodeSize)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// Validate checks code at time t and returns time step the code
+// belongs to, caller stores the step to reject reuse of the code
+func Validate(secret, code string, t time.Time) (int64, bool) {
+
+	code = strings.TrimSpace(code)
+	current := t.Unix() / period
+	for step := current - skew; step <= current+skew; step++ {
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*period, 0), totp.ValidateOpts{
+			Period:    period,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// NewRecoveryCodes returns n random one-time codes
+// which let user log in without authenticator
+func NewRecoveryCodes(n int) ([]string, error) {
+
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		b := make([]byte, 6)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+
+	return codes, nil
+}
+
+// HashRecoveryCode returns hash recovery code is stored with,
+// case, spaces and dashes typed by user do not matter
+func HashRecoveryCode(code string) string {
+
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+
+	return hex.EncodeToString(sum[:])
+}